@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,12 +11,19 @@ import (
 	"time"
 
 	"ApiSmart/config"
+	"ApiSmart/src/core/application"
 	"ApiSmart/src/core/application/service"
 	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+	"ApiSmart/src/core/tipo_de_datos"
 	eventAdapter "ApiSmart/src/infrastructure/adapters/events"
 	httpAdapter "ApiSmart/src/infrastructure/adapters/http"
 	"ApiSmart/src/infrastructure/adapters/http/handlers"
+	"ApiSmart/src/infrastructure/adapters/notifications"
+	"ApiSmart/src/infrastructure/adapters/realtime"
 	"ApiSmart/src/infrastructure/adapters/repositories/mysql"
+	"ApiSmart/src/infrastructure/adapters/webhooks"
 	"ApiSmart/src/infrastructure/auth"
 	"ApiSmart/src/infrastructure/database"
 )
@@ -24,6 +32,14 @@ func main() {
 	// Cargar configuración
 	cfg := config.LoadConfig()
 
+	// Comando "replay": reproducir eventos registrados y terminar
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Error reproduciendo eventos: %v", err)
+		}
+		return
+	}
+
 	// Inicializar conexión a base de datos
 	dbConn, err := database.NewMySQLConnection(cfg.Database)
 	if err != nil {
@@ -39,66 +55,209 @@ func main() {
 	// Inicializar repositorios
 	userRepo := mysql.NewUserRepository(db)
 	sensorRepo := mysql.NewSensorRepository(db)
+	deviceRepo := mysql.NewDeviceRepository(db)
+	thresholdRepo := mysql.NewThresholdRepository(db)
+	rollupRepo := mysql.NewRollupRepository(db)
+	outboxRepo := mysql.NewOutboxRepository(db)
+	eventStore := mysql.NewEventLogRepository(db)
+	transactor := mysql.NewTransactor(db)
+	webhookRepo := mysql.NewWebhookRepository(db)
+	notificationPrefRepo := mysql.NewNotificationPreferenceRepository(db)
+	alertRuleRepo := mysql.NewAlertRuleRepository(db)
+	alertStateRepo := mysql.NewAlertStateRepository(db)
+	alertReadRepo := mysql.NewAlertReadRepository(db)
 
 	// Inicializar servicios
-	alertService := service.NewAlertService()
+	alertService := service.NewAlertService(thresholdRepo, models.AlertClearBand{
+		Temperatura: cfg.Alerts.ClearBandTemperatura,
+		Luz:         cfg.Alerts.ClearBandLuz,
+		Humedad:     cfg.Alerts.ClearBandHumedad,
+		Humo:        cfg.Alerts.ClearBandHumo,
+	})
+	ruleEngine := service.NewRuleEngine(alertRuleRepo, sensorRepo, time.Duration(cfg.Alerts.RuleMaxSampleGapSecs)*time.Second)
+	alertTracker := service.NewAlertTracker(alertStateRepo, sensorRepo, time.Duration(cfg.Alerts.MinDurationSeconds)*time.Second)
 
-	// Intentar inicializar el sistema de eventos
-	var eventDispatcher *eventAdapter.EventDispatcherAdapter
-	var rabbitMQAdapter *eventAdapter.RabbitMQAdapter
+	// Iniciar el cálculo de resúmenes y la retención de lecturas en segundo plano
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
 
-	rabbitMQAdapter, err = eventAdapter.NewRabbitMQAdapter(cfg.RabbitMQ)
-	if err != nil {
-		log.Printf("Advertencia: Error inicializando RabbitMQ: %v", err)
-		log.Println("Continuando sin sistema de eventos...")
+	if cfg.Retention.RollupEnabled {
+		rollupWorker := service.NewRollupWorker(
+			rollupRepo,
+			models.RetentionPolicy{
+				Raw:    days(cfg.Retention.RawDays),
+				Minute: days(cfg.Retention.MinuteDays),
+				Hour:   days(cfg.Retention.HourDays),
+				Day:    days(cfg.Retention.DayDays),
+			},
+			time.Duration(cfg.Retention.IntervalSecs)*time.Second,
+			cfg.Retention.BatchSize,
+		)
+		go rollupWorker.Run(workerCtx)
+	}
+
+	// Inicializar el sistema de eventos. Con RabbitMQ nunca se recurre al broker en memoria:
+	// si no está disponible, el adaptador sigue intentando conectar y la bandeja de salida
+	// conserva los eventos hasta entonces.
+	var broker application.EventBroker
+	var deadLetters application.DeadLetterStore
+	if cfg.EventBroker == "memory" {
+		memoryBroker := eventAdapter.NewMemoryBroker(cfg.EventRetry)
+		broker, deadLetters = memoryBroker, memoryBroker
+		log.Println("Sistema de eventos en memoria inicializado")
 	} else {
-		defer rabbitMQAdapter.Close()
-		eventDispatcher = eventAdapter.NewEventDispatcherAdapter(rabbitMQAdapter)
+		rabbitMQAdapter, err := eventAdapter.NewRabbitMQAdapter(cfg.RabbitMQ, cfg.EventRetry)
+		if err != nil {
+			log.Fatalf("Error inicializando RabbitMQ: %v", err)
+		}
+		broker, deadLetters = rabbitMQAdapter, rabbitMQAdapter
 		log.Println("Sistema de eventos inicializado correctamente")
 	}
+	defer broker.Close()
+
+	// Registrar todos los eventos publicados para poder reproducirlos
+	broker = eventAdapter.NewRecordingBroker(broker, eventStore)
+	eventDispatcher := eventAdapter.NewEventDispatcherAdapter(broker)
+
+	// Publicar en el broker los eventos guardados en la bandeja de salida
+	outboxRelay := service.NewOutboxRelay(
+		outboxRepo,
+		broker,
+		events.OutboxRetryPolicy{
+			MaxAttempts:  cfg.Outbox.MaxAttempts,
+			InitialDelay: time.Duration(cfg.Outbox.RetryDelayMs) * time.Millisecond,
+			MaxDelay:     time.Duration(cfg.Outbox.RetryMaxDelayMs) * time.Millisecond,
+		},
+		time.Duration(cfg.Outbox.PollIntervalMs)*time.Millisecond,
+		cfg.Outbox.BatchSize,
+		time.Duration(cfg.Outbox.SentRetentionHours)*time.Hour,
+	)
+	go outboxRelay.Run(workerCtx)
+
+	// Hub en proceso para notificaciones en tiempo real (funciona sin RabbitMQ)
+	realtimeHub := realtime.NewHub(1000)
+
+	// Envío de alertas por correo; desactivado, las notificaciones solo se registran en el log
+	var notifier application.Notifier = notifications.NewLogNotifier()
+	if cfg.Notifications.EmailEnabled {
+		notifier = notifications.NewSMTPNotifier(cfg.SMTP)
+		log.Printf("Notificaciones por correo habilitadas (%s:%s)", cfg.SMTP.Host, cfg.SMTP.Port)
+	}
 
 	// Inicializar casos de uso
 	authUseCase := use_case.NewAuthUseCase(userRepo, eventDispatcher, jwtService)
-	sensorUseCase := use_case.NewSensorUseCase(sensorRepo, alertService, eventDispatcher)
+	sensorUseCase := use_case.NewSensorUseCase(sensorRepo, alertReadRepo, deviceRepo, outboxRepo, transactor, alertService, ruleEngine, alertTracker, eventDispatcher, realtimeHub)
+	deviceUseCase := use_case.NewDeviceUseCase(deviceRepo)
+	thresholdUseCase := use_case.NewThresholdUseCase(thresholdRepo, deviceRepo, alertService)
+	alertRuleUseCase := use_case.NewAlertRuleUseCase(alertRuleRepo, deviceRepo, ruleEngine)
+	eventAdminUseCase := use_case.NewEventAdminUseCase(deadLetters, eventStore, broker)
+	notificationUseCase := use_case.NewNotificationUseCase(
+		notificationPrefRepo,
+		sensorRepo,
+		alertReadRepo,
+		notifier,
+		models.NotificationRateLimit{
+			MinInterval: time.Duration(cfg.Notifications.MinIntervalMinutes) * time.Minute,
+			MaxPerHour:  cfg.Notifications.MaxPerHour,
+		},
+		cfg.Alerts.EscalationEmail,
+	)
+
+	// Escalar las alertas críticas que nadie reconoce a tiempo
+	if cfg.Alerts.EscalationEmail != "" && cfg.Alerts.EscalationMinutes > 0 {
+		escalationWorker := service.NewEscalationWorker(
+			sensorRepo,
+			notificationUseCase,
+			time.Duration(cfg.Alerts.EscalationMinutes)*time.Minute,
+			time.Duration(cfg.Alerts.EscalationIntervalSecs)*time.Second,
+		)
+		go escalationWorker.Run(workerCtx)
+	}
+	webhookUseCase := use_case.NewWebhookUseCase(
+		webhookRepo,
+		webhooks.NewHTTPSender(time.Duration(cfg.Webhooks.TimeoutMs)*time.Millisecond, cfg.Webhooks.AllowPrivateTargets),
+		models.WebhookDeliveryPolicy{
+			MaxAttempts:            cfg.Webhooks.MaxAttempts,
+			InitialDelay:           time.Duration(cfg.Webhooks.InitialDelayMs) * time.Millisecond,
+			MaxDelay:               time.Duration(cfg.Webhooks.MaxDelayMs) * time.Millisecond,
+			MaxConsecutiveFailures: cfg.Webhooks.MaxConsecutiveFailures,
+		},
+	)
+
+	// Reintentar en segundo plano las entregas a webhooks que fallaron
+	webhookRetryWorker := service.NewWebhookRetryWorker(
+		webhookUseCase,
+		time.Duration(cfg.Webhooks.RetryIntervalMs)*time.Millisecond,
+	)
+	go webhookRetryWorker.Run(workerCtx)
 
 	// Inicializar handlers HTTP
 	authHandler := handlers.NewAuthHandler(authUseCase)
 	sensorHandler := handlers.NewSensorHandler(sensorUseCase)
+	deviceHandler := handlers.NewDeviceHandler(deviceUseCase)
+	thresholdHandler := handlers.NewThresholdHandler(thresholdUseCase)
+	streamHandler := handlers.NewStreamHandler(realtimeHub)
+	eventAdminHandler := handlers.NewEventAdminHandler(eventAdminUseCase)
+	webhookHandler := handlers.NewWebhookHandler(webhookUseCase)
+	notificationHandler := handlers.NewNotificationHandler(notificationUseCase)
+	alertRuleHandler := handlers.NewAlertRuleHandler(alertRuleUseCase)
+	allowedOrigins := []string{"http://localhost:3000", "http://127.0.0.1:8000"}
+	wsHandler := handlers.NewWebSocketHandler(realtimeHub, sensorUseCase, allowedOrigins)
+
+	if len(cfg.Admin.UserIDs) == 0 {
+		log.Println("ADMIN_USER_IDS no está configurado: las rutas de administración quedan deshabilitadas")
+	}
 
 	// Configurar router HTTP
 	router := httpAdapter.NewRouter(
 		authHandler,
 		sensorHandler,
+		deviceHandler,
+		thresholdHandler,
+		streamHandler,
+		wsHandler,
+		eventAdminHandler,
+		webhookHandler,
+		notificationHandler,
+		alertRuleHandler,
 		httpAdapter.RouterConfig{
-			AllowedOrigins: []string{"http://localhost:3000", "http://127.0.0.1:8000"},
+			AllowedOrigins: allowedOrigins,
+			AdminUserIDs:   cfg.Admin.UserIDs,
 		},
 	)
 
-	// Si el sistema de eventos está disponible, configurar consumidores
-	if rabbitMQAdapter != nil {
-		// Crear manejadores de eventos
-		sensorDataHandler := eventAdapter.NewSensorDataHandler(sensorUseCase)
-		alertHandler := eventAdapter.NewAlertHandler(sensorUseCase)
-		userEventHandler := eventAdapter.NewUserEventHandler(authUseCase)
+	// Crear manejadores de eventos
+	sensorDataHandler := eventAdapter.NewSensorDataHandler(sensorUseCase)
+	alertHandler := eventAdapter.NewAlertHandler(sensorUseCase, notificationUseCase)
+	userEventHandler := eventAdapter.NewUserEventHandler(authUseCase)
+	webhookEventHandler := eventAdapter.NewWebhookHandler(webhookUseCase)
 
-		// Suscribir manejadores a topics
-		if err := rabbitMQAdapter.Subscribe("sensor.data", sensorDataHandler); err != nil {
-			log.Printf("Error suscribiendo al topic sensor.data: %v", err)
-		}
+	// Suscribir manejadores a topics
+	if err := broker.Subscribe("sensor.data", sensorDataHandler, subscriptionOptions(cfg.Consumers.SensorData)); err != nil {
+		log.Printf("Error suscribiendo al topic sensor.data: %v", err)
+	}
 
-		if err := rabbitMQAdapter.Subscribe("sensor.alerts", alertHandler); err != nil {
-			log.Printf("Error suscribiendo al topic sensor.alerts: %v", err)
-		}
+	if err := broker.Subscribe("sensor.alerts", alertHandler, subscriptionOptions(cfg.Consumers.SensorAlerts)); err != nil {
+		log.Printf("Error suscribiendo al topic sensor.alerts: %v", err)
+	}
 
-		if err := rabbitMQAdapter.Subscribe("user.events", userEventHandler); err != nil {
-			log.Printf("Error suscribiendo al topic user.events: %v", err)
-		}
+	if err := broker.Subscribe("user.events", userEventHandler, subscriptionOptions(cfg.Consumers.UserEvents)); err != nil {
+		log.Printf("Error suscribiendo al topic user.events: %v", err)
+	}
+
+	// Los webhooks usan su propia cola (sensor.#) para recibir los eventos de sensores
+	// sin competir con los manejadores anteriores
+	if err := broker.Subscribe("sensor.#", webhookEventHandler, subscriptionOptions(cfg.Consumers.Webhooks)); err != nil {
+		log.Printf("Error suscribiendo al topic sensor.#: %v", err)
 	}
 
 	// Configurar servidor HTTP
 	srv := &http.Server{
 		Addr:    ":" + cfg.ServerPort,
 		Handler: router.Setup(),
+		// Las peticiones heredan workerCtx para que las conexiones de streaming
+		// terminen al apagar el servidor
+		BaseContext: func(net.Listener) context.Context { return workerCtx },
 	}
 
 	// Iniciar servidor en una goroutine
@@ -115,6 +274,8 @@ func main() {
 	<-quit
 
 	log.Println("Apagando servidor...")
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -124,3 +285,17 @@ func main() {
 
 	log.Println("Servidor cerrado correctamente")
 }
+
+// days convierte un número de días de configuración en una duración
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// subscriptionOptions convierte la configuración de un consumidor en opciones de suscripción
+func subscriptionOptions(consumer tipo_de_datos.ConsumerConfig) application.SubscriptionOptions {
+	return application.SubscriptionOptions{
+		Prefetch:        consumer.Prefetch,
+		Workers:         consumer.Workers,
+		OrderedByDevice: consumer.OrderedByDevice,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"ApiSmart/config"
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	eventAdapter "ApiSmart/src/infrastructure/adapters/events"
+	"ApiSmart/src/infrastructure/adapters/repositories/mysql"
+	"ApiSmart/src/infrastructure/database"
+)
+
+// runReplay implementa el comando "replay", que vuelve a publicar en un topic los eventos
+// registrados en event_log. Ejemplo:
+//
+//	go run . replay -topic replay.sensor.alerts -from 2024-05-01 -types sensor.threshold.alert
+func runReplay(cfg *config.AppConfig, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	topic := flags.String("topic", "", "topic replay.* en el que publicar los eventos (obligatorio)")
+	from := flags.String("from", "", "inicio del rango, RFC 3339 o AAAA-MM-DD")
+	to := flags.String("to", "", "fin del rango (excluido), RFC 3339 o AAAA-MM-DD")
+	types := flags.String("types", "", "tipos de evento separados por comas (todos si se omite)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req := models.ReplayEventsRequest{Topic: *topic}
+	var err error
+	if req.From, err = parseReplayTime(*from); err != nil {
+		return err
+	}
+	if req.To, err = parseReplayTime(*to); err != nil {
+		return err
+	}
+	for _, eventType := range strings.Split(*types, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			req.EventTypes = append(req.EventTypes, eventType)
+		}
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	// En otro proceso el broker en memoria no llegaría a ningún consumidor
+	if cfg.EventBroker == "memory" {
+		return errors.New("la reproducción requiere EVENT_BROKER=rabbitmq")
+	}
+
+	dbConn, err := database.NewMySQLConnection(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("error conectando a la base de datos: %w", err)
+	}
+	defer dbConn.Close()
+
+	broker, err := eventAdapter.NewRabbitMQAdapter(cfg.RabbitMQ, cfg.EventRetry)
+	if err != nil {
+		return err
+	}
+	defer broker.Close()
+
+	// Una reproducción puntual no espera a que RabbitMQ vuelva
+	if !broker.Connected() {
+		return errors.New("no se pudo conectar a RabbitMQ")
+	}
+
+	eventAdminUseCase := use_case.NewEventAdminUseCase(broker, mysql.NewEventLogRepository(dbConn.GetDB()), broker)
+
+	// Permitir interrumpir una reproducción larga con Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	replayed, err := eventAdminUseCase.ReplayEvents(ctx, req)
+	log.Printf("Eventos reproducidos en %s: %d", req.Topic, replayed)
+	return err
+}
+
+// parseReplayTime interpreta una fecha opcional en formato RFC 3339 o AAAA-MM-DD
+func parseReplayTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("fecha inválida %q: use RFC 3339 o AAAA-MM-DD", value)
+	}
+	return &t, nil
+}
@@ -2,6 +2,7 @@ package application
 
 import (
 	"context"
+	"time"
 
 	"ApiSmart/src/core/domain/events"
 	"ApiSmart/src/core/domain/models"
@@ -14,14 +15,143 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id uint) (*models.User, error)
 }
 
-// SensorRepository define la interfaz para el acceso a datos de sensores
+// SensorRepository define la interfaz para el acceso a datos de sensores.
+// GetSensorDataWindow devuelve, de la más antigua a la más reciente, la última lectura del
+// dispositivo anterior o igual a from seguida de las posteriores, hasta la lectura untilID.
 type SensorRepository interface {
 	SaveSensorData(ctx context.Context, data *models.SensorData) error
-	GetAllSensorData(ctx context.Context) ([]models.SensorData, error)
-	GetLatestSensorData(ctx context.Context) (*models.SensorData, error)
+	GetAllSensorData(ctx context.Context, query models.SensorDataQuery) (*models.SensorDataPage, error)
+	StreamSensorData(ctx context.Context, query models.SensorDataQuery, fn func(models.SensorData) error) error
+	GetLatestSensorData(ctx context.Context, deviceID *uint) (*models.SensorData, error)
+	GetSensorDataWindow(ctx context.Context, deviceID uint, from time.Time, untilID uint) ([]models.SensorData, error)
+	AggregateSensorData(ctx context.Context, query models.SensorAggregateQuery) ([]models.MetricSeries, error)
 	SaveAlert(ctx context.Context, alert *models.Alert) error
-	GetAlerts(ctx context.Context, isRead *bool) ([]models.Alert, error)
-	MarkAlertAsRead(ctx context.Context, alertID uint) error
+	GetAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error)
+	RecordAlertOccurrence(ctx context.Context, alertID uint, value float64, seenAt time.Time) error
+	AcknowledgeAlert(ctx context.Context, alertID, userID uint, acknowledgedAt time.Time) error
+	ResolveAlert(ctx context.Context, alertID uint, resolvedBy *uint, resolvedAt time.Time) error
+	FindAlertsToEscalate(ctx context.Context, openedBefore time.Time, limit int) ([]models.Alert, error)
+	SetAlertEscalated(ctx context.Context, alertID uint, escalatedAt *time.Time) (bool, error)
+	GetAlerts(ctx context.Context, query models.AlertQuery) (*models.AlertPage, error)
+	StreamAlerts(ctx context.Context, filter models.AlertFilter, fn func(models.Alert) error) error
+	CountAlerts(ctx context.Context, query models.AlertCountQuery) ([]models.AlertCount, error)
+	DeleteAlerts(ctx context.Context, selection models.AlertSelection) (int64, error)
+}
+
+// Transactor ejecuta fn dentro de una transacción. Los repositorios que reciben el
+// contexto de fn participan en ella; si fn devuelve un error se revierte todo.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxRepository define la interfaz para la bandeja de salida de eventos. Enqueue
+// participa en la transacción del contexto, de modo que el evento se guarda junto con
+// los datos que lo originan. ClaimPending reclama los eventos pendientes cuyo turno ha
+// llegado aplazándolos lease, para que otra instancia no los publique a la vez; Release
+// los devuelve a su turno original sin contar un intento. MarkFailed programa el siguiente
+// intento y MarkParked aparta el evento para no volver a intentarlo.
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, topic string, payload events.Payload) error
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]events.OutboxMessage, error)
+	Release(ctx context.Context, id uint64, nextAttemptAt time.Time) error
+	MarkSent(ctx context.Context, id uint64) error
+	MarkFailed(ctx context.Context, id uint64, reason string, nextAttemptAt time.Time) error
+	MarkParked(ctx context.Context, id uint64, reason string) error
+	PurgeSent(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// RollupRepository define la interfaz para mantener los resúmenes y la retención de lecturas.
+// Una resolución 0 se refiere a las lecturas sin resumir.
+type RollupRepository interface {
+	Rollup(ctx context.Context, resolution time.Duration, until time.Time) error
+	Purge(ctx context.Context, resolution time.Duration, before time.Time, batchSize int) (int64, error)
+}
+
+// DeviceRepository define la interfaz para el acceso a datos de dispositivos
+type DeviceRepository interface {
+	Create(ctx context.Context, device *models.Device) error
+	FindByID(ctx context.Context, id uint) (*models.Device, error)
+	FindAll(ctx context.Context) ([]models.Device, error)
+	Update(ctx context.Context, device *models.Device) error
+	SetAPIKey(ctx context.Context, id uint, apiKey string, issuedAt time.Time) error
+	RevokeAPIKey(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// ThresholdRepository define la interfaz para el acceso a los umbrales de alertas.
+// Un deviceID nil se refiere a los umbrales globales.
+type ThresholdRepository interface {
+	Get(ctx context.Context, deviceID *uint) (*models.ThresholdSettings, error)
+	Save(ctx context.Context, settings *models.ThresholdSettings) error
+	Delete(ctx context.Context, deviceID uint) error
+}
+
+// AlertStateRepository define la interfaz para el estado de las condiciones de alerta de cada
+// dispositivo. FindByDevice bloquea las filas hasta el final de la transacción del contexto,
+// de modo que las lecturas de un mismo dispositivo se procesan de una en una.
+type AlertStateRepository interface {
+	FindByDevice(ctx context.Context, deviceID uint) ([]models.AlertState, error)
+	FindAll(ctx context.Context, deviceID *uint) ([]models.AlertState, error)
+	Save(ctx context.Context, state *models.AlertState) error
+	ResolveByAlert(ctx context.Context, alertID uint, resolvedAt time.Time) error
+}
+
+// AlertReadRepository define la interfaz para el estado de lectura de las alertas, que es
+// propio de cada usuario. Los métodos de marcado devuelven cuántas alertas cambiaron de estado.
+type AlertReadRepository interface {
+	MarkRead(ctx context.Context, userID uint, selection models.AlertSelection, readAt time.Time) (int64, error)
+	MarkUnread(ctx context.Context, userID uint, selection models.AlertSelection) (int64, error)
+	CountUnread(ctx context.Context, userID uint) (map[string]int, error)
+}
+
+// AlertRuleRepository define la interfaz para el acceso a las reglas de alerta
+type AlertRuleRepository interface {
+	Create(ctx context.Context, rule *models.AlertRule) error
+	FindByID(ctx context.Context, id uint) (*models.AlertRule, error)
+	FindAll(ctx context.Context) ([]models.AlertRule, error)
+	FindEnabled(ctx context.Context) ([]models.AlertRule, error)
+	Update(ctx context.Context, rule *models.AlertRule) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// WebhookRepository define la interfaz para el acceso a los webhooks y su historial de entregas.
+// RecordFailure suma un fallo consecutivo y deshabilita el webhook al llegar a maxFailures;
+// devuelve si quedó deshabilitado. ClaimDueRetries reclama los reintentos vencidos
+// aplazándolos lease, para que otra instancia no los repita mientras se entregan.
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	FindByID(ctx context.Context, id uint) (*models.Webhook, error)
+	FindByUser(ctx context.Context, userID uint) ([]models.Webhook, error)
+	FindActiveByEventType(ctx context.Context, eventType string) ([]models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id uint) error
+	RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error)
+	RecordSuccess(ctx context.Context, id uint) error
+	RecordFailure(ctx context.Context, id uint, maxFailures int) (bool, error)
+	ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error
+	ClaimDueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookRetry, error)
+	RescheduleRetry(ctx context.Context, id uint64, attempt int, nextAttemptAt time.Time) error
+	DeleteRetry(ctx context.Context, id uint64) error
+}
+
+// WebhookSender entrega un evento firmado a la URL de un webhook. Devuelve el código de
+// respuesta (0 si no la hubo) y un error si el receptor no la aceptó.
+type WebhookSender interface {
+	Send(ctx context.Context, webhook *models.Webhook, event events.Event) (int, error)
+}
+
+// NotificationPreferenceRepository define la interfaz para las preferencias de notificación.
+// Get devuelve las preferencias por defecto si el usuario no las ha guardado.
+type NotificationPreferenceRepository interface {
+	Get(ctx context.Context, userID uint) (*models.NotificationPreferences, error)
+	Save(ctx context.Context, prefs *models.NotificationPreferences) error
+	FindAlertRecipients(ctx context.Context) ([]models.User, error)
+}
+
+// Notifier define la interfaz para enviar notificaciones fuera de la aplicación (correo, etc.)
+type Notifier interface {
+	Notify(ctx context.Context, notification models.Notification) error
 }
 
 // AuthService define la interfaz para el servicio de autenticación
@@ -34,20 +164,110 @@ type AuthService interface {
 // SensorService define la interfaz para el servicio de sensores
 type SensorService interface {
 	SaveSensorData(ctx context.Context, data *models.SensorData) error
-	GetAllSensorData(ctx context.Context) ([]models.SensorData, error)
-	GetLatestSensorData(ctx context.Context) (*models.SensorData, error)
-	GetAlerts(ctx context.Context, isRead *bool) ([]models.Alert, error)
-	MarkAlertAsRead(ctx context.Context, alertID uint) error
+	GetAllSensorData(ctx context.Context, query models.SensorDataQuery) (*models.SensorDataPage, error)
+	GetLatestSensorData(ctx context.Context, deviceID *uint) (*models.SensorData, error)
+	AggregateSensorData(ctx context.Context, query models.SensorAggregateQuery) (*models.SensorAggregate, error)
+	ExportSensorData(ctx context.Context, query models.SensorDataQuery, fn func(models.SensorData) error) error
+	GetAlerts(ctx context.Context, query models.AlertQuery) (*models.AlertPage, error)
+	CountAlerts(ctx context.Context, query models.AlertCountQuery) ([]models.AlertCount, error)
+	GetAlertStates(ctx context.Context, deviceID *uint) ([]models.AlertState, error)
+	ExportAlerts(ctx context.Context, filter models.AlertFilter, fn func(models.Alert) error) error
+	MarkAlertAsRead(ctx context.Context, userID, alertID uint) error
+	UpdateAlerts(ctx context.Context, userID uint, req models.AlertBulkUpdateRequest) (int64, error)
+	DeleteAlerts(ctx context.Context, req models.AlertBulkRequest) (int64, error)
+	AcknowledgeAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error)
+	ResolveAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error)
+}
+
+// WebhookService define la interfaz para el servicio de webhooks. Cada usuario solo ve y
+// modifica sus propios webhooks.
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, userID uint, req models.CreateWebhookRequest) (*models.WebhookCredentials, error)
+	GetWebhooks(ctx context.Context, userID uint) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userID, id uint) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, userID, id uint, req models.UpdateWebhookRequest) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, id uint) error
+	GetDeliveries(ctx context.Context, userID, id uint, limit int) ([]models.WebhookDelivery, error)
+	DeliverEvent(ctx context.Context, event events.Event) error
+	RetryDeliveries(ctx context.Context, limit int) (int, error)
+}
+
+// NotificationService define la interfaz para el servicio de notificaciones
+type NotificationService interface {
+	GetPreferences(ctx context.Context, userID uint) (*models.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID uint, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error)
+	NotifyAlert(ctx context.Context, alert *events.SensorThresholdAlert) error
+	NotifyEscalation(ctx context.Context, alert *models.Alert) error
+	GetInbox(ctx context.Context, userID uint, unreadOnly bool, limit int) (*models.NotificationInbox, error)
+	MarkRead(ctx context.Context, userID uint, alertIDs []uint) (int64, error)
+	MarkAllRead(ctx context.Context, userID uint) (int64, error)
+}
+
+// DeviceService define la interfaz para el servicio de dispositivos
+type DeviceService interface {
+	CreateDevice(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceCredentials, error)
+	GetDevice(ctx context.Context, id uint) (*models.Device, error)
+	GetAllDevices(ctx context.Context) ([]models.Device, error)
+	UpdateDevice(ctx context.Context, id uint, req models.UpdateDeviceRequest) (*models.Device, error)
+	DeleteDevice(ctx context.Context, id uint) error
+	RotateDeviceKey(ctx context.Context, id uint) (*models.DeviceCredentials, error)
+	RevokeDeviceKey(ctx context.Context, id uint) error
+	AuthenticateKey(ctx context.Context, id uint, apiKey string) error
+	VerifySignature(ctx context.Context, id uint, timestamp string, body []byte, signature string) error
 }
 
 // AlertService define la interfaz para el servicio de alertas
 type AlertService interface {
-	CheckAndCreateAlerts(data *models.SensorData) []models.Alert
+	CheckAndCreateAlerts(ctx context.Context, data *models.SensorData, firing map[string]bool) ([]models.Alert, error)
+	InvalidateThresholds(deviceID *uint)
+}
+
+// RuleEngine evalúa las reglas de alerta habilitadas con cada lectura y devuelve las alertas
+// de las que se cumplen, o un error si no pudo evaluarlas. InvalidateRules descarta las
+// reglas en caché tras modificarlas.
+type RuleEngine interface {
+	Evaluate(ctx context.Context, data *models.SensorData) ([]models.Alert, error)
+	InvalidateRules()
 }
 
-// EventDispatcher define la interfaz para el despachador de eventos
+// AlertRuleService define la interfaz para el servicio de reglas de alerta
+type AlertRuleService interface {
+	CreateRule(ctx context.Context, req models.AlertRuleRequest) (*models.AlertRule, error)
+	GetRules(ctx context.Context) ([]models.AlertRule, error)
+	GetRule(ctx context.Context, id uint) (*models.AlertRule, error)
+	UpdateRule(ctx context.Context, id uint, req models.AlertRuleRequest) (*models.AlertRule, error)
+	DeleteRule(ctx context.Context, id uint) error
+}
+
+// AlertTracker deduplica las alertas de cada dispositivo. Track carga el estado de sus
+// condiciones, llama a evaluate con las que tienen una alerta abierta (por Alert.Key) y, con
+// las alertas que devuelve, abre las nuevas tras el tiempo mínimo, suma ocurrencias a las
+// abiertas y resuelve las que ya no se cumplen. Si evaluate falla no cambia ningún estado y
+// devuelve su error. Devuelve solo las alertas abiertas en esta lectura.
+// Resolve resuelve una alerta a mano; si su condición sigue cumpliéndose se abrirá otra.
+type AlertTracker interface {
+	Track(ctx context.Context, data *models.SensorData, evaluate func(firing map[string]bool) ([]models.Alert, error)) ([]models.Alert, error)
+	Resolve(ctx context.Context, alertID, userID uint) error
+	GetStates(ctx context.Context, deviceID *uint) ([]models.AlertState, error)
+}
+
+// ThresholdService define la interfaz para el servicio de umbrales
+type ThresholdService interface {
+	GetThresholds(ctx context.Context, deviceID *uint) (*models.ThresholdSettings, error)
+	UpdateThresholds(ctx context.Context, deviceID *uint, req models.UpdateThresholdsRequest) (*models.ThresholdSettings, error)
+	DeleteThresholds(ctx context.Context, deviceID uint) error
+}
+
+// EventDispatcher define la interfaz para el despachador de eventos. Dispatch no
+// termina hasta que el broker confirma el evento (o vence ctx) y devuelve el motivo si no lo hizo.
 type EventDispatcher interface {
-	Dispatch(ctx context.Context, eventType string, topic string, data map[string]interface{}) error
+	Dispatch(ctx context.Context, topic string, payload events.Payload) error
+}
+
+// RealtimePublisher define la interfaz para notificar en proceso a los clientes conectados
+// (SSE, WebSocket), independiente del broker de eventos
+type RealtimePublisher interface {
+	Publish(topic, eventType string, deviceID uint, data interface{})
 }
 
 // EventHandler define la interfaz para el manejador de eventos
@@ -58,6 +278,30 @@ type EventHandler interface {
 // EventBroker define la interfaz para el broker de eventos
 type EventBroker interface {
 	Publish(ctx context.Context, topic string, event events.Event) error
-	Subscribe(topic string, handler EventHandler) error
+	Subscribe(topic string, handler EventHandler, options SubscriptionOptions) error
 	Close() error
 }
+
+// SubscriptionOptions configura el consumo de una suscripción. Prefetch es el máximo de
+// eventos entregados sin confirmar (0 = sin límite); Workers, cuántos se procesan en
+// paralelo (0 = 1). Con OrderedByDevice los eventos de un mismo dispositivo se procesan
+// siempre en el mismo worker, en el orden en que se reciben.
+type SubscriptionOptions struct {
+	Prefetch        int
+	Workers         int
+	OrderedByDevice bool
+}
+
+// DeadLetterStore lo implementan los brokers que apartan los eventos que agotaron sus
+// reintentos. RequeueDeadLetters con una lista vacía reencola todos.
+type DeadLetterStore interface {
+	ListDeadLetters(ctx context.Context, limit int) ([]events.DeadLetter, error)
+	RequeueDeadLetters(ctx context.Context, eventIDs []string) (int, error)
+}
+
+// EventStore define la interfaz para el registro persistente de los eventos publicados.
+// Append ignora los eventos ya registrados (mismo ID), de modo que las reentregas no se duplican.
+type EventStore interface {
+	Append(ctx context.Context, topic string, event events.Event) error
+	Stream(ctx context.Context, filter models.EventLogFilter, fn func(events.LoggedEvent) error) error
+}
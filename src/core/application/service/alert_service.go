@@ -1,95 +1,211 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"ApiSmart/src/core/application"
 	"ApiSmart/src/core/domain/models"
 )
 
+// thresholdCacheTTL limita cuánto tiempo se reutilizan los umbrales en caché,
+// de modo que los cambios hechos por otras instancias terminan aplicándose
+const thresholdCacheTTL = time.Minute
+
+// cachedThresholds es una entrada de la caché de umbrales
+type cachedThresholds struct {
+	thresholds models.AlertThresholds
+	expiresAt  time.Time
+}
+
 // AlertService implementa la interfaz AlertService
 type AlertService struct {
-	thresholds models.AlertThresholds
+	thresholdRepo application.ThresholdRepository
+	clearBand     models.AlertClearBand
+
+	mu    sync.RWMutex
+	cache map[uint]cachedThresholds // clave 0 = umbrales globales
 }
 
 // NewAlertService crea una nueva instancia de AlertService
-func NewAlertService() application.AlertService {
+func NewAlertService(thresholdRepo application.ThresholdRepository, clearBand models.AlertClearBand) application.AlertService {
 	return &AlertService{
-		thresholds: models.DefaultAlertThresholds,
+		thresholdRepo: thresholdRepo,
+		clearBand:     clearBand,
+		cache:         make(map[uint]cachedThresholds),
+	}
+}
+
+// InvalidateThresholds descarta los umbrales en caché de un dispositivo, o todos si deviceID es nil
+func (s *AlertService) InvalidateThresholds(deviceID *uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Cambiar los umbrales globales afecta a todos los dispositivos que los heredan
+	if deviceID == nil {
+		s.cache = make(map[uint]cachedThresholds)
+		return
+	}
+	delete(s.cache, *deviceID)
+}
+
+// thresholdsFor obtiene los umbrales efectivos de un dispositivo: los propios, los globales
+// o, si no hay ninguno persistido, models.DefaultAlertThresholds. Si el repositorio falla
+// se siguen usando los últimos umbrales cargados aunque hayan caducado, y solo se devuelve
+// el error si nunca se cargaron: los predeterminados podrían no coincidir con los configurados.
+func (s *AlertService) thresholdsFor(ctx context.Context, deviceID uint) (models.AlertThresholds, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[deviceID]
+	s.mu.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.thresholds, nil
 	}
+
+	thresholds, err := s.loadThresholds(ctx, deviceID)
+	if err != nil {
+		if !ok {
+			return models.AlertThresholds{}, fmt.Errorf("error cargando umbrales: %w", err)
+		}
+		log.Printf("Error cargando umbrales, se usan los anteriores: %v", err)
+		return entry.thresholds, nil
+	}
+
+	s.mu.Lock()
+	s.cache[deviceID] = cachedThresholds{thresholds: thresholds, expiresAt: time.Now().Add(thresholdCacheTTL)}
+	s.mu.Unlock()
+
+	return thresholds, nil
 }
 
-// CheckAndCreateAlerts verifica si los datos del sensor superan los umbrales y crea alertas
-func (s *AlertService) CheckAndCreateAlerts(data *models.SensorData) []models.Alert {
+// loadThresholds consulta el repositorio aplicando la herencia dispositivo → global → predeterminados
+func (s *AlertService) loadThresholds(ctx context.Context, deviceID uint) (models.AlertThresholds, error) {
+	if deviceID != 0 {
+		settings, err := s.thresholdRepo.Get(ctx, &deviceID)
+		if err == nil {
+			return settings.AlertThresholds, nil
+		}
+		if !errors.Is(err, models.ErrThresholdsNotFound) {
+			return models.AlertThresholds{}, err
+		}
+	}
+
+	settings, err := s.thresholdRepo.Get(ctx, nil)
+	if err != nil {
+		if errors.Is(err, models.ErrThresholdsNotFound) {
+			return models.DefaultAlertThresholds, nil
+		}
+		return models.AlertThresholds{}, err
+	}
+
+	return settings.AlertThresholds, nil
+}
+
+// CheckAndCreateAlerts verifica si los datos del sensor superan los umbrales y crea alertas.
+// Las de humo son críticas y las demás, avisos.
+// Para las condiciones de firing (Alert.Key), que ya tienen una alerta abierta, el umbral
+// de ese sentido se relaja con el margen de histéresis: la alerta sigue activa hasta
+// recuperar ese margen. El umbral del sentido contrario no cambia.
+// Si los umbrales no se pueden obtener devuelve un error en lugar de resolver las alertas abiertas.
+func (s *AlertService) CheckAndCreateAlerts(ctx context.Context, data *models.SensorData, firing map[string]bool) ([]models.Alert, error) {
 	alerts := []models.Alert{}
+	thresholds, err := s.thresholdsFor(ctx, data.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// band devuelve el margen de histéresis de una condición, 0 si no tiene alerta abierta
+	band := func(sensorType, direction string, margin float64) float64 {
+		if firing[models.ThresholdAlertKey(sensorType, direction)] {
+			return margin
+		}
+		return 0
+	}
 
 	// Verificar temperatura
-	if data.TemperaturaDHT > s.thresholds.TemperaturaMax {
+	if data.TemperaturaDHT > thresholds.TemperaturaMax-band("temperatura", models.ThresholdAbove, s.clearBand.Temperatura) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "temperatura",
+			Severity:   models.SeverityWarning,
 			Value:      data.TemperaturaDHT,
-			Message:    fmt.Sprintf("Temperatura alta: %.2f°C - Ha superado el umbral de %.2f°C", data.TemperaturaDHT, s.thresholds.TemperaturaMax),
+			Message:    fmt.Sprintf("Temperatura alta: %.2f°C - Ha superado el umbral de %.2f°C", data.TemperaturaDHT, thresholds.TemperaturaMax),
+			Direction:  models.ThresholdAbove,
 			IsRead:     false,
 		})
-	} else if data.TemperaturaDHT < s.thresholds.TemperaturaMin {
+	} else if data.TemperaturaDHT < thresholds.TemperaturaMin+band("temperatura", models.ThresholdBelow, s.clearBand.Temperatura) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "temperatura",
+			Severity:   models.SeverityWarning,
 			Value:      data.TemperaturaDHT,
-			Message:    fmt.Sprintf("Temperatura baja: %.2f°C - Por debajo del umbral de %.2f°C", data.TemperaturaDHT, s.thresholds.TemperaturaMin),
+			Message:    fmt.Sprintf("Temperatura baja: %.2f°C - Por debajo del umbral de %.2f°C", data.TemperaturaDHT, thresholds.TemperaturaMin),
+			Direction:  models.ThresholdBelow,
 			IsRead:     false,
 		})
 	}
 
 	// Verificar luz
-	if data.Luz > s.thresholds.LuzMax {
+	if data.Luz > thresholds.LuzMax-band("luz", models.ThresholdAbove, s.clearBand.Luz) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "luz",
+			Severity:   models.SeverityWarning,
 			Value:      data.Luz,
-			Message:    fmt.Sprintf("Nivel de luz alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Luz, s.thresholds.LuzMax),
+			Message:    fmt.Sprintf("Nivel de luz alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Luz, thresholds.LuzMax),
+			Direction:  models.ThresholdAbove,
 			IsRead:     false,
 		})
-	} else if data.Luz < s.thresholds.LuzMin {
+	} else if data.Luz < thresholds.LuzMin+band("luz", models.ThresholdBelow, s.clearBand.Luz) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "luz",
+			Severity:   models.SeverityWarning,
 			Value:      data.Luz,
-			Message:    fmt.Sprintf("Nivel de luz bajo: %.2f%% - Por debajo del umbral de %.2f%%", data.Luz, s.thresholds.LuzMin),
+			Message:    fmt.Sprintf("Nivel de luz bajo: %.2f%% - Por debajo del umbral de %.2f%%", data.Luz, thresholds.LuzMin),
+			Direction:  models.ThresholdBelow,
 			IsRead:     false,
 		})
 	}
 
 	// Verificar humedad
-	if data.Humedad > s.thresholds.HumedadMax {
+	if data.Humedad > thresholds.HumedadMax-band("humedad", models.ThresholdAbove, s.clearBand.Humedad) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "humedad",
+			Severity:   models.SeverityWarning,
 			Value:      data.Humedad,
-			Message:    fmt.Sprintf("Nivel de humedad alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Humedad, s.thresholds.HumedadMax),
+			Message:    fmt.Sprintf("Nivel de humedad alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Humedad, thresholds.HumedadMax),
+			Direction:  models.ThresholdAbove,
 			IsRead:     false,
 		})
-	} else if data.Humedad < s.thresholds.HumedadMin {
+	} else if data.Humedad < thresholds.HumedadMin+band("humedad", models.ThresholdBelow, s.clearBand.Humedad) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "humedad",
+			Severity:   models.SeverityWarning,
 			Value:      data.Humedad,
-			Message:    fmt.Sprintf("Nivel de humedad bajo: %.2f%% - Por debajo del umbral de %.2f%%", data.Humedad, s.thresholds.HumedadMin),
+			Message:    fmt.Sprintf("Nivel de humedad bajo: %.2f%% - Por debajo del umbral de %.2f%%", data.Humedad, thresholds.HumedadMin),
+			Direction:  models.ThresholdBelow,
 			IsRead:     false,
 		})
 	}
 
 	// Verificar humo
-	if data.Humo > s.thresholds.HumoMax {
+	if data.Humo > thresholds.HumoMax-band("humo", models.ThresholdAbove, s.clearBand.Humo) {
 		alerts = append(alerts, models.Alert{
 			SensorID:   data.ID,
 			SensorType: "humo",
+			Severity:   models.SeverityCritical,
 			Value:      data.Humo,
-			Message:    fmt.Sprintf("Nivel de humo alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Humo, s.thresholds.HumoMax),
+			Message:    fmt.Sprintf("Nivel de humo alto: %.2f%% - Ha superado el umbral de %.2f%%", data.Humo, thresholds.HumoMax),
+			Direction:  models.ThresholdAbove,
 			IsRead:     false,
 		})
 	}
 
-	return alerts
+	return alerts, nil
 }
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// AlertTracker implementa la interfaz AlertTracker
+type AlertTracker struct {
+	stateRepo   application.AlertStateRepository
+	sensorRepo  application.SensorRepository
+	minDuration time.Duration
+}
+
+// NewAlertTracker crea una nueva instancia de AlertTracker. Una condición debe cumplirse
+// durante minDuration antes de abrir su alerta (0 = en la primera lectura).
+func NewAlertTracker(
+	stateRepo application.AlertStateRepository,
+	sensorRepo application.SensorRepository,
+	minDuration time.Duration,
+) application.AlertTracker {
+	return &AlertTracker{
+		stateRepo:   stateRepo,
+		sensorRepo:  sensorRepo,
+		minDuration: minDuration,
+	}
+}
+
+// GetStates obtiene el estado de las condiciones de alerta, opcionalmente de un dispositivo
+func (t *AlertTracker) GetStates(ctx context.Context, deviceID *uint) ([]models.AlertState, error) {
+	return t.stateRepo.FindAll(ctx, deviceID)
+}
+
+// Track aplica a la lectura las transiciones de estado de las condiciones de su dispositivo.
+// Debe llamarse dentro de la transacción que guarda la lectura.
+func (t *AlertTracker) Track(ctx context.Context, data *models.SensorData, evaluate func(firing map[string]bool) ([]models.Alert, error)) ([]models.Alert, error) {
+	states, err := t.stateRepo.FindByDevice(ctx, data.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*models.AlertState, len(states))
+	firing := make(map[string]bool)
+	for i := range states {
+		byKey[states[i].Key] = &states[i]
+		if states[i].Status == models.AlertStateFiring && states[i].AlertID != nil {
+			firing[states[i].Key] = true
+		}
+	}
+
+	evaluated, err := evaluate(firing)
+	if err != nil {
+		return nil, err
+	}
+
+	now := data.CreatedAt
+	opened := []models.Alert{}
+	active := make(map[string]bool)
+
+	for _, alert := range evaluated {
+		key := alert.Key()
+		if active[key] {
+			continue
+		}
+		active[key] = true
+
+		state, ok := byKey[key]
+		// Una alerta abierta eliminada (por ejemplo, por la retención de lecturas) se vuelve a abrir
+		if !ok || state.Status == models.AlertStateResolved || (state.Status == models.AlertStateFiring && state.AlertID == nil) {
+			state = &models.AlertState{
+				DeviceID: data.DeviceID,
+				Key:      key,
+				Status:   models.AlertStatePending,
+				Since:    now,
+				AlertID:  stateAlertID(state),
+			}
+		}
+		state.LastValue = alert.Value
+
+		switch {
+		case state.Status == models.AlertStateFiring:
+			// La alerta ya está abierta: solo se anota la nueva ocurrencia
+			if err := t.sensorRepo.RecordAlertOccurrence(ctx, *state.AlertID, alert.Value, now); err != nil {
+				return nil, err
+			}
+
+		case now.Sub(state.Since) >= t.minDuration:
+			alert.DeviceID = data.DeviceID
+			alert.Status = models.AlertStatusOpen
+			alert.Occurrences = 1
+			alert.LastValue = alert.Value
+			if err := t.sensorRepo.SaveAlert(ctx, &alert); err != nil {
+				return nil, err
+			}
+
+			alertID := alert.ID
+			state.Status = models.AlertStateFiring
+			state.Since = now
+			state.AlertID = &alertID
+			opened = append(opened, alert)
+		}
+
+		if err := t.stateRepo.Save(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+
+	// Las condiciones que ya no se cumplen se resuelven, hayan llegado a abrir alerta o no
+	for i := range states {
+		state := &states[i]
+		if active[state.Key] || state.Status == models.AlertStateResolved {
+			continue
+		}
+
+		if state.Status == models.AlertStateFiring && state.AlertID != nil {
+			// Puede que ya se hubiera resuelto a mano
+			err := t.sensorRepo.ResolveAlert(ctx, *state.AlertID, nil, now)
+			if err != nil && !errors.Is(err, models.ErrInvalidAlertTransition) {
+				return nil, err
+			}
+		}
+
+		state.Status = models.AlertStateResolved
+		state.Since = now
+		if err := t.stateRepo.Save(ctx, state); err != nil {
+			return nil, err
+		}
+	}
+
+	return opened, nil
+}
+
+// Resolve resuelve una alerta a mano y da por resuelta su condición, de modo que si sigue
+// cumpliéndose se abra una alerta nueva (tras el tiempo mínimo). Debe llamarse dentro de una
+// transacción; el estado se actualiza primero para bloquear las filas en el mismo orden que Track.
+func (t *AlertTracker) Resolve(ctx context.Context, alertID, userID uint) error {
+	now := time.Now()
+
+	if err := t.stateRepo.ResolveByAlert(ctx, alertID, now); err != nil {
+		return err
+	}
+
+	return t.sensorRepo.ResolveAlert(ctx, alertID, &userID, now)
+}
+
+// stateAlertID conserva la última alerta de un estado anterior, si lo hay
+func stateAlertID(state *models.AlertState) *uint {
+	if state == nil {
+		return nil
+	}
+	return state.AlertID
+}
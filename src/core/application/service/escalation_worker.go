@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ApiSmart/src/core/application"
+)
+
+// escalationBatchSize limita cuántas alertas se escalan en cada pasada
+const escalationBatchSize = 50
+
+// EscalationWorker escala periódicamente las alertas críticas que siguen abiertas sin reconocer
+type EscalationWorker struct {
+	sensorRepo application.SensorRepository
+	notifier   application.NotificationService
+	after      time.Duration
+	interval   time.Duration
+}
+
+// NewEscalationWorker crea una nueva instancia de EscalationWorker. Una alerta crítica se
+// escala cuando lleva abierta sin reconocer al menos after.
+func NewEscalationWorker(
+	sensorRepo application.SensorRepository,
+	notifier application.NotificationService,
+	after time.Duration,
+	interval time.Duration,
+) *EscalationWorker {
+	return &EscalationWorker{
+		sensorRepo: sensorRepo,
+		notifier:   notifier,
+		after:      after,
+		interval:   interval,
+	}
+}
+
+// Run ejecuta el worker hasta que se cancela el contexto
+func (w *EscalationWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce escala las alertas pendientes. Cada alerta se reclama antes de avisar para que
+// varias instancias no la escalen dos veces, y se libera si el aviso falla.
+func (w *EscalationWorker) RunOnce(ctx context.Context) {
+	now := time.Now()
+
+	alerts, err := w.sensorRepo.FindAlertsToEscalate(ctx, now.Add(-w.after), escalationBatchSize)
+	if err != nil {
+		log.Printf("Error buscando alertas para escalar: %v", err)
+		return
+	}
+
+	for i := range alerts {
+		alert := &alerts[i]
+
+		claimed, err := w.sensorRepo.SetAlertEscalated(ctx, alert.ID, &now)
+		if err != nil {
+			log.Printf("Error reclamando la alerta %d para escalarla: %v", alert.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := w.notifier.NotifyEscalation(ctx, alert); err != nil {
+			log.Printf("Error escalando la alerta %d: %v", alert.ID, err)
+			if _, err := w.sensorRepo.SetAlertEscalated(ctx, alert.ID, nil); err != nil {
+				log.Printf("Error liberando la alerta %d: %v", alert.ID, err)
+			}
+		}
+	}
+}
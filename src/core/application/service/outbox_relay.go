@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+)
+
+// outboxClaimLease es cuánto se aplazan los eventos reclamados: si la instancia cae
+// mientras los publica, otra los retoma pasado ese tiempo
+const outboxClaimLease = time.Minute
+
+// OutboxRelay publica en el broker los eventos de la bandeja de salida y los marca como
+// enviados. Un evento puede publicarse más de una vez si el proceso se detiene entre la
+// publicación y la marca (entrega al menos una vez); los consumidores deben tolerarlo
+// usando el ID del evento. Los eventos que fallan se reintentan con espera creciente sin
+// bloquear a los siguientes, y se apartan al agotar los intentos de la política.
+type OutboxRelay struct {
+	outboxRepo    application.OutboxRepository
+	broker        application.EventBroker
+	policy        events.OutboxRetryPolicy
+	interval      time.Duration
+	batchSize     int
+	sentRetention time.Duration
+}
+
+// NewOutboxRelay crea una nueva instancia de OutboxRelay. Con sentRetention en 0 los
+// eventos enviados se conservan indefinidamente.
+func NewOutboxRelay(
+	outboxRepo application.OutboxRepository,
+	broker application.EventBroker,
+	policy events.OutboxRetryPolicy,
+	interval time.Duration,
+	batchSize int,
+	sentRetention time.Duration,
+) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo:    outboxRepo,
+		broker:        broker,
+		policy:        policy,
+		interval:      interval,
+		batchSize:     batchSize,
+		sentRetention: sentRetention,
+	}
+}
+
+// Run ejecuta el relay hasta que se cancela el contexto
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastPurge := time.Now()
+	for {
+		// Mientras haya lotes completos se sigue publicando sin esperar al siguiente tick
+		for {
+			published := r.RunOnce(ctx)
+			if published == 0 || published < r.batchSize || ctx.Err() != nil {
+				break
+			}
+		}
+
+		if r.sentRetention > 0 && time.Since(lastPurge) >= time.Hour {
+			r.purge(ctx)
+			lastPurge = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publica un lote de eventos pendientes y devuelve cuántos se publicaron. Un
+// evento que falla se reprograma (o se aparta) y el lote continúa; si el broker no está
+// disponible el lote se devuelve sin contar el intento, porque fallarían todos.
+func (r *OutboxRelay) RunOnce(ctx context.Context) int {
+	messages, err := r.outboxRepo.ClaimPending(ctx, time.Now(), outboxClaimLease, r.batchSize)
+	if err != nil {
+		log.Printf("Error leyendo la bandeja de salida: %v", err)
+		return 0
+	}
+
+	published := 0
+	for i, msg := range messages {
+		err := r.broker.Publish(ctx, msg.Topic, msg.Event)
+		if errors.Is(err, events.ErrBrokerUnavailable) {
+			log.Printf("Broker no disponible, se reintentará la bandeja de salida: %v", err)
+			r.release(ctx, messages[i:])
+			return published
+		}
+		if err != nil {
+			r.fail(ctx, msg, err)
+			continue
+		}
+
+		if err := r.outboxRepo.MarkSent(ctx, msg.ID); err != nil {
+			// Se volverá a publicar cuando venza la reserva
+			log.Printf("Error marcando como enviado el evento %s: %v", msg.Event.ID, err)
+			continue
+		}
+		published++
+	}
+
+	return published
+}
+
+// fail registra un intento fallido: programa el siguiente con espera creciente o, si se
+// han agotado, aparta el evento para que no bloquee la bandeja de salida
+func (r *OutboxRelay) fail(ctx context.Context, msg events.OutboxMessage, cause error) {
+	attempts := msg.Attempts + 1
+
+	if r.policy.Exhausted(attempts) {
+		log.Printf("Evento %s de la bandeja de salida apartado tras %d intentos: %v", msg.Event.ID, attempts, cause)
+		if err := r.outboxRepo.MarkParked(ctx, msg.ID, cause.Error()); err != nil {
+			log.Printf("Error apartando el evento %s: %v", msg.Event.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Error publicando evento %s de la bandeja de salida (intento %d): %v", msg.Event.ID, attempts, cause)
+	if err := r.outboxRepo.MarkFailed(ctx, msg.ID, cause.Error(), time.Now().Add(r.policy.Delay(attempts))); err != nil {
+		log.Printf("Error registrando el fallo del evento %s: %v", msg.Event.ID, err)
+	}
+}
+
+// release devuelve a su turno los eventos reclamados que no se llegaron a publicar
+func (r *OutboxRelay) release(ctx context.Context, messages []events.OutboxMessage) {
+	for _, msg := range messages {
+		if err := r.outboxRepo.Release(ctx, msg.ID, msg.NextAttemptAt); err != nil {
+			log.Printf("Error liberando el evento %s: %v", msg.Event.ID, err)
+		}
+	}
+}
+
+// purge elimina por lotes los eventos enviados que superan la retención
+func (r *OutboxRelay) purge(ctx context.Context) {
+	before := time.Now().Add(-r.sentRetention)
+
+	var total int64
+	for {
+		deleted, err := r.outboxRepo.PurgeSent(ctx, before, r.batchSize)
+		if err != nil {
+			log.Printf("Error purgando la bandeja de salida: %v", err)
+			return
+		}
+		total += deleted
+
+		if deleted < int64(r.batchSize) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Bandeja de salida: %d eventos enviados eliminados", total)
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+)
+
+// fakeOutboxRow es un evento de fakeOutboxRepo con su estado
+type fakeOutboxRow struct {
+	msg    events.OutboxMessage
+	sent   bool
+	parked bool
+}
+
+// fakeOutboxRepo es un OutboxRepository en memoria
+type fakeOutboxRepo struct {
+	mu   sync.Mutex
+	rows []*fakeOutboxRow
+}
+
+func newFakeOutboxRepo(eventIDs ...string) *fakeOutboxRepo {
+	repo := &fakeOutboxRepo{}
+	start := time.Now().Add(-time.Minute)
+	for i, id := range eventIDs {
+		createdAt := start.Add(time.Duration(i) * time.Millisecond)
+		repo.rows = append(repo.rows, &fakeOutboxRow{msg: events.OutboxMessage{
+			ID:            uint64(i + 1),
+			Topic:         events.TopicSensorData,
+			Event:         events.Event{ID: id, Type: events.EventTypeSensorDataCreated, Timestamp: createdAt},
+			NextAttemptAt: createdAt,
+			CreatedAt:     createdAt,
+		}})
+	}
+	return repo
+}
+
+func (r *fakeOutboxRepo) Enqueue(ctx context.Context, topic string, payload events.Payload) error {
+	return errors.New("no implementado")
+}
+
+func (r *fakeOutboxRepo) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]events.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []*fakeOutboxRow
+	for _, row := range r.rows {
+		if !row.sent && !row.parked && !row.msg.NextAttemptAt.After(now) {
+			due = append(due, row)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].msg.NextAttemptAt.Before(due[j].msg.NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]events.OutboxMessage, len(due))
+	for i, row := range due {
+		claimed[i] = row.msg
+		row.msg.NextAttemptAt = now.Add(lease)
+	}
+	return claimed, nil
+}
+
+func (r *fakeOutboxRepo) Release(ctx context.Context, id uint64, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.row(id).msg.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkSent(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row := r.row(id)
+	row.msg.Attempts++
+	row.sent = true
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkFailed(ctx context.Context, id uint64, reason string, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row := r.row(id)
+	row.msg.Attempts++
+	row.msg.LastError = reason
+	row.msg.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (r *fakeOutboxRepo) MarkParked(ctx context.Context, id uint64, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	row := r.row(id)
+	row.msg.Attempts++
+	row.msg.LastError = reason
+	row.parked = true
+	return nil
+}
+
+func (r *fakeOutboxRepo) PurgeSent(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOutboxRepo) row(id uint64) *fakeOutboxRow {
+	for _, row := range r.rows {
+		if row.msg.ID == id {
+			return row
+		}
+	}
+	panic(fmt.Sprintf("fila %d inexistente", id))
+}
+
+// makeDue adelanta el turno de todos los eventos pendientes, como si hubiera pasado la espera
+func (r *fakeOutboxRepo) makeDue() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, row := range r.rows {
+		row.msg.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+// fakeBroker rechaza los eventos de poison y, con unavailable, todos
+type fakeBroker struct {
+	mu          sync.Mutex
+	poison      map[string]bool
+	unavailable bool
+	published   []string
+}
+
+func (b *fakeBroker) Publish(ctx context.Context, topic string, event events.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unavailable {
+		return events.ErrBrokerUnavailable
+	}
+	if b.poison[event.ID] {
+		return events.ErrEventUnroutable
+	}
+	b.published = append(b.published, event.ID)
+	return nil
+}
+
+func (b *fakeBroker) Subscribe(topic string, handler application.EventHandler, options application.SubscriptionOptions) error {
+	return nil
+}
+
+func (b *fakeBroker) Close() error { return nil }
+
+func testOutboxPolicy() events.OutboxRetryPolicy {
+	return events.OutboxRetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour, MaxDelay: time.Hour}
+}
+
+func TestOutboxRelayPoisonRowDoesNotBlockQueue(t *testing.T) {
+	repo := newFakeOutboxRepo("poison", "evt-2", "evt-3")
+	broker := &fakeBroker{poison: map[string]bool{"poison": true}}
+	relay := NewOutboxRelay(repo, broker, testOutboxPolicy(), time.Second, 10, 0)
+
+	if published := relay.RunOnce(context.Background()); published != 2 {
+		t.Errorf("publicados = %d, se esperaba 2", published)
+	}
+	if fmt.Sprint(broker.published) != "[evt-2 evt-3]" {
+		t.Errorf("publicados = %v, se esperaba [evt-2 evt-3]", broker.published)
+	}
+
+	poison := repo.row(1)
+	if poison.sent || poison.parked || poison.msg.Attempts != 1 {
+		t.Errorf("evento fallido: sent=%v parked=%v attempts=%d", poison.sent, poison.parked, poison.msg.Attempts)
+	}
+	if !poison.msg.NextAttemptAt.After(time.Now()) {
+		t.Error("el evento fallido debería reprogramarse con espera")
+	}
+
+	// Hasta que vence la espera no se vuelve a intentar
+	if published := relay.RunOnce(context.Background()); published != 0 || poison.msg.Attempts != 1 {
+		t.Errorf("RunOnce = %d, intentos = %d; el evento fallido no debería reintentarse aún", published, poison.msg.Attempts)
+	}
+}
+
+func TestOutboxRelayParksRowAfterMaxAttempts(t *testing.T) {
+	repo := newFakeOutboxRepo("poison", "evt-2")
+	broker := &fakeBroker{poison: map[string]bool{"poison": true}}
+	relay := NewOutboxRelay(repo, broker, testOutboxPolicy(), time.Second, 10, 0)
+
+	for i := 0; i < 5; i++ {
+		relay.RunOnce(context.Background())
+		repo.makeDue()
+	}
+
+	poison := repo.row(1)
+	if !poison.parked || poison.msg.Attempts != 3 {
+		t.Errorf("evento fallido: parked=%v attempts=%d, se esperaba apartado tras 3 intentos", poison.parked, poison.msg.Attempts)
+	}
+	if !repo.row(2).sent {
+		t.Error("el evento sano debería haberse publicado")
+	}
+}
+
+func TestOutboxRelayReleasesBatchWhenBrokerUnavailable(t *testing.T) {
+	repo := newFakeOutboxRepo("evt-1", "evt-2")
+	broker := &fakeBroker{unavailable: true}
+	relay := NewOutboxRelay(repo, broker, testOutboxPolicy(), time.Second, 10, 0)
+
+	if published := relay.RunOnce(context.Background()); published != 0 {
+		t.Errorf("publicados = %d, se esperaba 0", published)
+	}
+
+	for _, id := range []uint64{1, 2} {
+		row := repo.row(id)
+		if row.msg.Attempts != 0 || row.msg.NextAttemptAt.After(time.Now()) {
+			t.Errorf("evento %d: attempts=%d, turno=%v; se esperaba liberado sin contar el intento", id, row.msg.Attempts, row.msg.NextAttemptAt)
+		}
+	}
+
+	broker.unavailable = false
+	if published := relay.RunOnce(context.Background()); published != 2 {
+		t.Errorf("publicados = %d, se esperaba 2", published)
+	}
+	if fmt.Sprint(broker.published) != "[evt-1 evt-2]" {
+		t.Errorf("orden = %v, se esperaba [evt-1 evt-2]", broker.published)
+	}
+}
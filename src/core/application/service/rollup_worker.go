@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// RollupWorker calcula periódicamente los resúmenes de lecturas y aplica la política de retención
+type RollupWorker struct {
+	rollupRepo application.RollupRepository
+	policy     models.RetentionPolicy
+	interval   time.Duration
+	batchSize  int
+}
+
+// NewRollupWorker crea una nueva instancia de RollupWorker
+func NewRollupWorker(
+	rollupRepo application.RollupRepository,
+	policy models.RetentionPolicy,
+	interval time.Duration,
+	batchSize int,
+) *RollupWorker {
+	return &RollupWorker{
+		rollupRepo: rollupRepo,
+		policy:     policy,
+		interval:   interval,
+		batchSize:  batchSize,
+	}
+}
+
+// Run ejecuta el worker hasta que se cancela el contexto
+func (w *RollupWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce actualiza todos los resúmenes y purga los datos expirados
+func (w *RollupWorker) RunOnce(ctx context.Context) {
+	now := time.Now()
+
+	// Los resúmenes se calculan de la resolución más fina a la más gruesa
+	for _, resolution := range models.RollupResolutions {
+		if err := w.rollupRepo.Rollup(ctx, resolution, now); err != nil {
+			log.Printf("Error calculando resumen de %s: %v", models.FormatBucket(resolution), err)
+			return
+		}
+	}
+
+	// Lecturas sin resumir (resolución 0) y después cada resumen
+	for _, resolution := range append([]time.Duration{0}, models.RollupResolutions...) {
+		retention := w.policy.For(resolution)
+		if retention <= 0 {
+			continue
+		}
+
+		deleted, err := w.purge(ctx, resolution, now.Add(-retention))
+		if err != nil {
+			log.Printf("Error aplicando retención: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Retención aplicada: %d filas eliminadas (resolución %s)", deleted, resolutionName(resolution))
+		}
+	}
+}
+
+// purge elimina por lotes las filas anteriores a before para no bloquear la tabla
+func (w *RollupWorker) purge(ctx context.Context, resolution time.Duration, before time.Time) (int64, error) {
+	var total int64
+
+	for {
+		deleted, err := w.rollupRepo.Purge(ctx, resolution, before, w.batchSize)
+		if err != nil {
+			return total, err
+		}
+		total += deleted
+
+		if deleted < int64(w.batchSize) || ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
+
+// resolutionName devuelve un nombre legible para los logs
+func resolutionName(resolution time.Duration) string {
+	if resolution == 0 {
+		return "lecturas"
+	}
+	return models.FormatBucket(resolution)
+}
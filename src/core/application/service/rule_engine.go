@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+	"ApiSmart/src/core/domain/rules"
+)
+
+// ruleCacheTTL limita cuánto tiempo se reutilizan las reglas en caché, de modo que los
+// cambios hechos por otras instancias terminan aplicándose
+const ruleCacheTTL = time.Minute
+
+// compiledRule es una regla habilitada con su expresión ya analizada
+type compiledRule struct {
+	rule models.AlertRule
+	expr *rules.Expression
+}
+
+// RuleEngine implementa la interfaz RuleEngine
+type RuleEngine struct {
+	ruleRepo     application.AlertRuleRepository
+	sensorRepo   application.SensorRepository
+	maxSampleGap time.Duration // 0 = sin límite
+
+	mu        sync.RWMutex
+	rules     []compiledRule
+	expiresAt time.Time
+}
+
+// NewRuleEngine crea una nueva instancia de RuleEngine. maxSampleGap es la mayor separación
+// entre lecturas con la que se considera que una condición se mantuvo sin interrupción.
+func NewRuleEngine(ruleRepo application.AlertRuleRepository, sensorRepo application.SensorRepository, maxSampleGap time.Duration) application.RuleEngine {
+	return &RuleEngine{
+		ruleRepo:     ruleRepo,
+		sensorRepo:   sensorRepo,
+		maxSampleGap: maxSampleGap,
+	}
+}
+
+// InvalidateRules descarta las reglas en caché
+func (e *RuleEngine) InvalidateRules() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = nil
+	e.expiresAt = time.Time{}
+}
+
+// Evaluate devuelve una alerta por cada regla que se cumple con la lectura. Las reglas con
+// cláusula FOR solo se cumplen si todas las lecturas del dispositivo durante ese tiempo la
+// cumplían también y no faltan lecturas en medio. Si las reglas no se pueden evaluar devuelve un error en lugar de una
+// lista vacía, que daría por resueltas las alertas abiertas de las reglas.
+func (e *RuleEngine) Evaluate(ctx context.Context, data *models.SensorData) ([]models.Alert, error) {
+	alerts := []models.Alert{}
+	current := sensorValues(*data)
+
+	compiled, err := e.rulesFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reglas que se cumplen con la lectura actual, y el tiempo máximo que alguna exige
+	var matching []compiledRule
+	var longest time.Duration
+	for _, r := range compiled {
+		if r.rule.DeviceID != nil && *r.rule.DeviceID != data.DeviceID {
+			continue
+		}
+		if !r.expr.Eval(current) {
+			continue
+		}
+		matching = append(matching, r)
+		if r.expr.Duration() > longest {
+			longest = r.expr.Duration()
+		}
+	}
+
+	if len(matching) == 0 {
+		return alerts, nil
+	}
+
+	// Una sola consulta del histórico sirve para todas las reglas con cláusula FOR
+	var window []models.SensorData
+	if longest > 0 {
+		var err error
+		window, err = e.sensorRepo.GetSensorDataWindow(ctx, data.DeviceID, data.CreatedAt.Add(-longest), data.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error cargando el histórico para las reglas de alerta: %w", err)
+		}
+	}
+
+	for _, r := range matching {
+		if d := r.expr.Duration(); d > 0 && !heldSince(r.expr, window, data.CreatedAt.Add(-d), e.maxSampleGap) {
+			continue
+		}
+		alerts = append(alerts, ruleAlert(r, data, current))
+	}
+
+	return alerts, nil
+}
+
+// rulesFor obtiene las reglas habilitadas, desde la caché si no ha caducado. Si no se
+// pueden cargar se usan las anteriores, y solo falla si aún no se habían cargado nunca.
+func (e *RuleEngine) rulesFor(ctx context.Context) ([]compiledRule, error) {
+	e.mu.RLock()
+	cached, expiresAt := e.rules, e.expiresAt
+	e.mu.RUnlock()
+	if time.Now().Before(expiresAt) {
+		return cached, nil
+	}
+
+	enabled, err := e.ruleRepo.FindEnabled(ctx)
+	if err != nil {
+		if cached == nil {
+			return nil, fmt.Errorf("error cargando reglas de alerta: %w", err)
+		}
+		log.Printf("Error cargando reglas de alerta, se usan las anteriores: %v", err)
+		return cached, nil
+	}
+
+	compiled := make([]compiledRule, 0, len(enabled))
+	for _, rule := range enabled {
+		expr, err := rules.Parse(rule.Expression)
+		if err != nil {
+			log.Printf("Regla de alerta %d ignorada: %v", rule.ID, err)
+			continue
+		}
+		compiled = append(compiled, compiledRule{rule: rule, expr: expr})
+	}
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.expiresAt = time.Now().Add(ruleCacheTTL)
+	e.mu.Unlock()
+
+	return compiled, nil
+}
+
+// heldSince indica si la expresión se cumplió en todas las lecturas de la ventana desde
+// la última anterior o igual a since. La ventana está ordenada de la más antigua a la más reciente.
+// Con maxGap > 0, una separación mayor entre lecturas consecutivas, o entre la primera y since,
+// invalida la ventana: no se sabe qué valores hubo mientras el dispositivo no informó.
+func heldSince(expr *rules.Expression, window []models.SensorData, since time.Time, maxGap time.Duration) bool {
+	start := -1
+	for i, data := range window {
+		if data.CreatedAt.After(since) {
+			break
+		}
+		start = i
+	}
+
+	// Sin lecturas anteriores a since aún no ha pasado el tiempo exigido
+	if start < 0 {
+		return false
+	}
+
+	if maxGap > 0 && since.Sub(window[start].CreatedAt) > maxGap {
+		return false
+	}
+
+	for i, data := range window[start:] {
+		if !expr.Eval(sensorValues(data)) {
+			return false
+		}
+		if maxGap > 0 && i > 0 && data.CreatedAt.Sub(window[start+i-1].CreatedAt) > maxGap {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ruleAlert construye la alerta de una regla. Su valor es el de la primera métrica de la expresión.
+func ruleAlert(r compiledRule, data *models.SensorData, values rules.Values) models.Alert {
+	metrics := r.expr.Metrics()
+
+	parts := make([]string, len(metrics))
+	for i, metric := range metrics {
+		parts[i] = fmt.Sprintf("%s=%.2f", metric, values[metric])
+	}
+
+	var value float64
+	if len(metrics) > 0 {
+		value = values[metrics[0]]
+	}
+
+	ruleID := r.rule.ID
+	return models.Alert{
+		SensorID:   data.ID,
+		SensorType: models.SensorTypeRule,
+		Severity:   r.rule.Severity,
+		Value:      value,
+		Message:    fmt.Sprintf("Regla %q: se cumple %s (%s)", r.rule.Name, r.rule.Expression, strings.Join(parts, ", ")),
+		IsRead:     false,
+		RuleID:     &ruleID,
+	}
+}
+
+// sensorValues expone las métricas de una lectura a las expresiones
+func sensorValues(data models.SensorData) rules.Values {
+	return rules.Values{
+		rules.MetricTemperaturaDHT: data.TemperaturaDHT,
+		rules.MetricLuz:            data.Luz,
+		rules.MetricHumedad:        data.Humedad,
+		rules.MetricHumo:           data.Humo,
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+	"ApiSmart/src/core/domain/rules"
+)
+
+// readingsAt construye lecturas con humedad baja en los minutos indicados desde start
+func readingsAt(start time.Time, minutes ...int) []models.SensorData {
+	window := make([]models.SensorData, len(minutes))
+	for i, minute := range minutes {
+		window[i] = models.SensorData{ID: uint(i + 1), Humedad: 20, CreatedAt: start.Add(time.Duration(minute) * time.Minute)}
+	}
+	return window
+}
+
+func TestHeldSince(t *testing.T) {
+	expr, err := rules.Parse("humedad < 35 FOR 15 minutes")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	since := start.Add(5 * time.Minute) // la lectura actual es la del minuto 20
+
+	tests := []struct {
+		name   string
+		window []models.SensorData
+		maxGap time.Duration
+		want   bool
+	}{
+		{"lecturas regulares", readingsAt(start, 4, 8, 12, 16, 20), 5 * time.Minute, true},
+		{"hueco entre lecturas", readingsAt(start, 4, 6, 19, 20), 5 * time.Minute, false},
+		{"ancla demasiado antigua", readingsAt(start, -60, 6, 10, 14, 18, 20), 5 * time.Minute, false},
+		{"hueco sin límite configurado", readingsAt(start, 4, 6, 19, 20), 0, true},
+		{"sin lecturas anteriores a since", readingsAt(start, 6, 10, 14, 18, 20), 5 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := heldSince(expr, tt.window, since, tt.maxGap); got != tt.want {
+				t.Errorf("heldSince = %v, se esperaba %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"ApiSmart/src/core/application"
+)
+
+// webhookRetryBatchSize limita cuántas entregas se reintentan en cada pasada
+const webhookRetryBatchSize = 100
+
+// WebhookRetryWorker reintenta periódicamente las entregas a webhooks que fallaron, sin
+// retener al consumidor del broker mientras esperan su turno
+type WebhookRetryWorker struct {
+	webhooks application.WebhookService
+	interval time.Duration
+}
+
+// NewWebhookRetryWorker crea una nueva instancia de WebhookRetryWorker
+func NewWebhookRetryWorker(webhooks application.WebhookService, interval time.Duration) *WebhookRetryWorker {
+	return &WebhookRetryWorker{
+		webhooks: webhooks,
+		interval: interval,
+	}
+}
+
+// Run ejecuta el worker hasta que se cancela el contexto
+func (w *WebhookRetryWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce reintenta las entregas vencidas por lotes hasta que no quedan más
+func (w *WebhookRetryWorker) RunOnce(ctx context.Context) {
+	for ctx.Err() == nil {
+		retried, err := w.webhooks.RetryDeliveries(ctx, webhookRetryBatchSize)
+		if err != nil {
+			log.Printf("Error reintentando entregas a webhooks: %v", err)
+			return
+		}
+		if retried < webhookRetryBatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+	"ApiSmart/src/core/domain/rules"
+)
+
+// AlertRuleUseCase implementa los casos de uso relacionados con las reglas de alerta
+type AlertRuleUseCase struct {
+	ruleRepo   application.AlertRuleRepository
+	deviceRepo application.DeviceRepository
+	ruleEngine application.RuleEngine
+}
+
+// NewAlertRuleUseCase crea una nueva instancia de AlertRuleUseCase
+func NewAlertRuleUseCase(
+	ruleRepo application.AlertRuleRepository,
+	deviceRepo application.DeviceRepository,
+	ruleEngine application.RuleEngine,
+) *AlertRuleUseCase {
+	return &AlertRuleUseCase{
+		ruleRepo:   ruleRepo,
+		deviceRepo: deviceRepo,
+		ruleEngine: ruleEngine,
+	}
+}
+
+// CreateRule valida y guarda una nueva regla
+func (uc *AlertRuleUseCase) CreateRule(ctx context.Context, req models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule := &models.AlertRule{}
+	if err := uc.apply(ctx, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := uc.ruleRepo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	uc.ruleEngine.InvalidateRules()
+	return rule, nil
+}
+
+// GetRules obtiene todas las reglas
+func (uc *AlertRuleUseCase) GetRules(ctx context.Context) ([]models.AlertRule, error) {
+	return uc.ruleRepo.FindAll(ctx)
+}
+
+// GetRule obtiene una regla por su ID
+func (uc *AlertRuleUseCase) GetRule(ctx context.Context, id uint) (*models.AlertRule, error) {
+	return uc.ruleRepo.FindByID(ctx, id)
+}
+
+// UpdateRule valida y reemplaza una regla existente
+func (uc *AlertRuleUseCase) UpdateRule(ctx context.Context, id uint, req models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule, err := uc.ruleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.apply(ctx, rule, req); err != nil {
+		return nil, err
+	}
+
+	if err := uc.ruleRepo.Update(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	uc.ruleEngine.InvalidateRules()
+	return rule, nil
+}
+
+// DeleteRule elimina una regla
+func (uc *AlertRuleUseCase) DeleteRule(ctx context.Context, id uint) error {
+	if err := uc.ruleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	uc.ruleEngine.InvalidateRules()
+	return nil
+}
+
+// apply comprueba la expresión y el dispositivo de la solicitud y los copia en la regla
+func (uc *AlertRuleUseCase) apply(ctx context.Context, rule *models.AlertRule, req models.AlertRuleRequest) error {
+	expr, err := rules.Parse(req.Expression)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidAlertRule, err)
+	}
+
+	if req.DeviceID != nil {
+		if _, err := uc.deviceRepo.FindByID(ctx, *req.DeviceID); err != nil {
+			return err
+		}
+	}
+
+	rule.Name = req.Name
+	rule.Expression = expr.String()
+	rule.DeviceID = req.DeviceID
+	rule.Severity = req.Severity
+	if rule.Severity == "" {
+		rule.Severity = models.SeverityWarning
+	}
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+
+	return nil
+}
@@ -0,0 +1,141 @@
+package use_case
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// alertEmailData son los datos con los que se generan los correos de alerta
+type alertEmailData struct {
+	Username   string
+	Alert      *events.SensorThresholdAlert
+	Suppressed int // avisos de la misma alerta descartados desde el anterior
+}
+
+const alertSubjectTemplate = `[Smart Garden] Alerta de {{.Alert.SensorType}} en el dispositivo {{.Alert.DeviceID}}`
+
+const alertTextTemplate = `Hola {{.Username}},
+
+Se ha detectado una alerta en el dispositivo {{.Alert.DeviceID}}:
+
+  Sensor:   {{.Alert.SensorType}}
+  Gravedad: {{.Alert.Severity}}
+  Valor:    {{printf "%.2f" .Alert.Value}}
+  Mensaje:  {{.Alert.Message}}
+  Fecha:    {{.Alert.CreatedAt.Format "02/01/2006 15:04:05"}}
+{{if .Suppressed}}
+Desde el aviso anterior se produjeron {{.Suppressed}} alertas más como esta que no se notificaron.
+{{end}}
+Puedes desactivar estos correos en las preferencias de notificación.
+`
+
+const alertHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+  <p>Hola {{.Username}},</p>
+  <p>Se ha detectado una alerta en el dispositivo <strong>{{.Alert.DeviceID}}</strong>:</p>
+  <table cellpadding="6" style="border-collapse: collapse;">
+    <tr><td><strong>Sensor</strong></td><td>{{.Alert.SensorType}}</td></tr>
+    <tr><td><strong>Gravedad</strong></td><td>{{.Alert.Severity}}</td></tr>
+    <tr><td><strong>Valor</strong></td><td>{{printf "%.2f" .Alert.Value}}</td></tr>
+    <tr><td><strong>Mensaje</strong></td><td>{{.Alert.Message}}</td></tr>
+    <tr><td><strong>Fecha</strong></td><td>{{.Alert.CreatedAt.Format "02/01/2006 15:04:05"}}</td></tr>
+  </table>
+  {{if .Suppressed}}<p>Desde el aviso anterior se produjeron {{.Suppressed}} alertas más como esta que no se notificaron.</p>{{end}}
+  <p style="font-size: 12px; color: #888;">Puedes desactivar estos correos en las preferencias de notificación.</p>
+</body>
+</html>
+`
+
+const escalationSubjectTemplate = `[Smart Garden] ESCALADA: alerta crítica sin reconocer en el dispositivo {{.DeviceID}}`
+
+const escalationTextTemplate = `La siguiente alerta crítica sigue abierta sin que nadie la haya reconocido:
+
+  Alerta:      #{{.ID}}
+  Dispositivo: {{.DeviceID}}
+  Sensor:      {{.SensorType}}
+  Valor:       {{printf "%.2f" .Value}} (último: {{printf "%.2f" .LastValue}})
+  Ocurrencias: {{.Occurrences}}
+  Mensaje:     {{.Message}}
+  Abierta:     {{.CreatedAt.Format "02/01/2006 15:04:05"}}
+
+Reconócela o resuélvela desde la aplicación para detener el aviso.
+`
+
+const escalationHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #333;">
+  <p>La siguiente alerta <strong style="color: #c0392b;">crítica</strong> sigue abierta sin que nadie la haya reconocido:</p>
+  <table cellpadding="6" style="border-collapse: collapse;">
+    <tr><td><strong>Alerta</strong></td><td>#{{.ID}}</td></tr>
+    <tr><td><strong>Dispositivo</strong></td><td>{{.DeviceID}}</td></tr>
+    <tr><td><strong>Sensor</strong></td><td>{{.SensorType}}</td></tr>
+    <tr><td><strong>Valor</strong></td><td>{{printf "%.2f" .Value}} (último: {{printf "%.2f" .LastValue}})</td></tr>
+    <tr><td><strong>Ocurrencias</strong></td><td>{{.Occurrences}}</td></tr>
+    <tr><td><strong>Mensaje</strong></td><td>{{.Message}}</td></tr>
+    <tr><td><strong>Abierta</strong></td><td>{{.CreatedAt.Format "02/01/2006 15:04:05"}}</td></tr>
+  </table>
+  <p style="font-size: 12px; color: #888;">Reconócela o resuélvela desde la aplicación para detener el aviso.</p>
+</body>
+</html>
+`
+
+// Plantillas de los correos de alerta; el HTML escapa los valores automáticamente
+var (
+	alertSubject = texttemplate.Must(texttemplate.New("subject").Parse(alertSubjectTemplate))
+	alertText    = texttemplate.Must(texttemplate.New("text").Parse(alertTextTemplate))
+	alertHTML    = htmltemplate.Must(htmltemplate.New("html").Parse(alertHTMLTemplate))
+
+	escalationSubject = texttemplate.Must(texttemplate.New("subject").Parse(escalationSubjectTemplate))
+	escalationText    = texttemplate.Must(texttemplate.New("text").Parse(escalationTextTemplate))
+	escalationHTML    = htmltemplate.Must(htmltemplate.New("html").Parse(escalationHTMLTemplate))
+)
+
+// renderAlertEmail genera el correo de una alerta para un destinatario
+func renderAlertEmail(user models.User, alert *events.SensorThresholdAlert, suppressed int) (models.Notification, error) {
+	data := alertEmailData{Username: user.Username, Alert: alert, Suppressed: suppressed}
+
+	var subject, text, html bytes.Buffer
+	if err := alertSubject.Execute(&subject, data); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el asunto: %w", err)
+	}
+	if err := alertText.Execute(&text, data); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el texto: %w", err)
+	}
+	if err := alertHTML.Execute(&html, data); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el HTML: %w", err)
+	}
+
+	return models.Notification{
+		To:      user.Email,
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// renderEscalationEmail genera el correo con el que se escala una alerta crítica sin reconocer
+func renderEscalationEmail(to string, alert *models.Alert) (models.Notification, error) {
+	var subject, text, html bytes.Buffer
+	if err := escalationSubject.Execute(&subject, alert); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el asunto: %w", err)
+	}
+	if err := escalationText.Execute(&text, alert); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el texto: %w", err)
+	}
+	if err := escalationHTML.Execute(&html, alert); err != nil {
+		return models.Notification{}, fmt.Errorf("error generando el HTML: %w", err)
+	}
+
+	return models.Notification{
+		To:      to,
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
@@ -12,17 +12,24 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// streamTicketTTL es la validez de un ticket de streaming: solo debe cubrir el tiempo
+// entre pedirlo y abrir la conexión
+const streamTicketTTL = 30 * time.Second
+
 // AuthUseCase implementa los casos de uso relacionados con autenticación
 type AuthUseCase struct {
 	userRepo        application.UserRepository
 	eventDispatcher application.EventDispatcher
 	jwtService      JWTService
+	streamTickets   *replayGuard
 }
 
 // JWTService define la interfaz para el servicio JWT
 type JWTService interface {
 	GenerateToken(userID uint, username string) (string, error)
 	ValidateToken(token string) (uint, error)
+	GenerateStreamTicket(userID uint, ttl time.Duration) (ticket string, ticketID string, expiresAt time.Time, err error)
+	ValidateStreamTicket(ticket string) (userID uint, ticketID string, expiresAt time.Time, err error)
 }
 
 // NewAuthUseCase crea una nueva instancia de AuthUseCase
@@ -35,6 +42,7 @@ func NewAuthUseCase(
 		userRepo:        userRepo,
 		eventDispatcher: eventDispatcher,
 		jwtService:      jwtService,
+		streamTickets:   newReplayGuard(),
 	}
 }
 
@@ -75,18 +83,13 @@ func (uc *AuthUseCase) Register(ctx context.Context, req models.RegisterRequest)
 
 	// Publicar evento de registro
 	if uc.eventDispatcher != nil {
-		eventData := map[string]interface{}{
-			"user_id":  user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+		payload := events.UserRegistered{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
 		}
 
-		if err := uc.eventDispatcher.Dispatch(
-			ctx,
-			events.EventTypeUserRegistered,
-			events.TopicUserEvents,
-			eventData,
-		); err != nil {
+		if err := uc.eventDispatcher.Dispatch(ctx, events.TopicUserEvents, payload); err != nil {
 			log.Printf("Error al publicar evento de registro: %v", err)
 		}
 	}
@@ -120,18 +123,13 @@ func (uc *AuthUseCase) Login(ctx context.Context, req models.LoginRequest) (*mod
 
 	// Publicar evento de autenticación
 	if uc.eventDispatcher != nil {
-		eventData := map[string]interface{}{
-			"user_id":  user.ID,
-			"username": user.Username,
-			"email":    user.Email,
+		payload := events.UserAuthenticated{
+			UserID:   user.ID,
+			Username: user.Username,
+			Email:    user.Email,
 		}
 
-		if err := uc.eventDispatcher.Dispatch(
-			ctx,
-			events.EventTypeUserAuthenticated,
-			events.TopicUserEvents,
-			eventData,
-		); err != nil {
+		if err := uc.eventDispatcher.Dispatch(ctx, events.TopicUserEvents, payload); err != nil {
 			log.Printf("Error al publicar evento de autenticación: %v", err)
 		}
 	}
@@ -147,3 +145,27 @@ func (uc *AuthUseCase) Login(ctx context.Context, req models.LoginRequest) (*mod
 func (uc *AuthUseCase) ValidateToken(token string) (uint, error) {
 	return uc.jwtService.ValidateToken(token)
 }
+
+// IssueStreamTicket emite un ticket de streaming para el usuario autenticado
+func (uc *AuthUseCase) IssueStreamTicket(userID uint) (*models.StreamTicket, error) {
+	ticket, _, expiresAt, err := uc.jwtService.GenerateStreamTicket(userID, streamTicketTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StreamTicket{Ticket: ticket, ExpiresAt: expiresAt}, nil
+}
+
+// RedeemStreamTicket valida un ticket de streaming y lo consume, de modo que no puede
+// reutilizarse aunque quede registrado en algún log
+func (uc *AuthUseCase) RedeemStreamTicket(ticket string) (uint, error) {
+	userID, ticketID, expiresAt, err := uc.jwtService.ValidateStreamTicket(ticket)
+	if err != nil {
+		return 0, models.ErrInvalidStreamTicket
+	}
+	if !uc.streamTickets.remember(ticketID, expiresAt) {
+		return 0, models.ErrInvalidStreamTicket
+	}
+
+	return userID, nil
+}
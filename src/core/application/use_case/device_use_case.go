@@ -0,0 +1,189 @@
+package use_case
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// signatureTolerance es la diferencia máxima aceptada entre el reloj del dispositivo y el servidor
+const signatureTolerance = 5 * time.Minute
+
+// DeviceUseCase implementa los casos de uso relacionados con dispositivos
+type DeviceUseCase struct {
+	deviceRepo application.DeviceRepository
+	replay     *replayGuard
+}
+
+// NewDeviceUseCase crea una nueva instancia de DeviceUseCase
+func NewDeviceUseCase(deviceRepo application.DeviceRepository) *DeviceUseCase {
+	return &DeviceUseCase{
+		deviceRepo: deviceRepo,
+		replay:     newReplayGuard(),
+	}
+}
+
+// CreateDevice registra un nuevo dispositivo y emite su clave
+func (uc *DeviceUseCase) CreateDevice(ctx context.Context, req models.CreateDeviceRequest) (*models.DeviceCredentials, error) {
+	apiKey, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now()
+	device := &models.Device{
+		Name:        req.Name,
+		Location:    req.Location,
+		Description: req.Description,
+		APIKey:      apiKey,
+		KeyIssuedAt: &issuedAt,
+	}
+
+	if err := uc.deviceRepo.Create(ctx, device); err != nil {
+		return nil, err
+	}
+
+	return &models.DeviceCredentials{Device: *device, APIKey: apiKey}, nil
+}
+
+// GetDevice obtiene un dispositivo por su ID
+func (uc *DeviceUseCase) GetDevice(ctx context.Context, id uint) (*models.Device, error) {
+	return uc.deviceRepo.FindByID(ctx, id)
+}
+
+// GetAllDevices obtiene todos los dispositivos registrados
+func (uc *DeviceUseCase) GetAllDevices(ctx context.Context) ([]models.Device, error) {
+	return uc.deviceRepo.FindAll(ctx)
+}
+
+// UpdateDevice actualiza los datos de un dispositivo
+func (uc *DeviceUseCase) UpdateDevice(ctx context.Context, id uint, req models.UpdateDeviceRequest) (*models.Device, error) {
+	device, err := uc.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	device.Name = req.Name
+	device.Location = req.Location
+	device.Description = req.Description
+
+	if err := uc.deviceRepo.Update(ctx, device); err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// DeleteDevice elimina un dispositivo
+func (uc *DeviceUseCase) DeleteDevice(ctx context.Context, id uint) error {
+	return uc.deviceRepo.Delete(ctx, id)
+}
+
+// RotateDeviceKey emite una nueva clave para el dispositivo; la anterior deja de ser válida
+func (uc *DeviceUseCase) RotateDeviceKey(ctx context.Context, id uint) (*models.DeviceCredentials, error) {
+	device, err := uc.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := time.Now()
+	if err := uc.deviceRepo.SetAPIKey(ctx, id, apiKey, issuedAt); err != nil {
+		return nil, err
+	}
+
+	device.APIKey = apiKey
+	device.KeyIssuedAt = &issuedAt
+
+	return &models.DeviceCredentials{Device: *device, APIKey: apiKey}, nil
+}
+
+// RevokeDeviceKey revoca la clave del dispositivo hasta que se rote una nueva
+func (uc *DeviceUseCase) RevokeDeviceKey(ctx context.Context, id uint) error {
+	return uc.deviceRepo.RevokeAPIKey(ctx, id)
+}
+
+// AuthenticateKey verifica la clave enviada en la cabecera X-Device-Key
+func (uc *DeviceUseCase) AuthenticateKey(ctx context.Context, id uint, apiKey string) error {
+	device, err := uc.activeDevice(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(device.APIKey), []byte(apiKey)) != 1 {
+		return models.ErrInvalidDeviceCredentials
+	}
+
+	return nil
+}
+
+// VerifySignature verifica una firma HMAC-SHA256 de "<timestamp>.<body>" con la clave del dispositivo.
+// La marca de tiempo (segundos Unix) debe estar dentro de signatureTolerance y cada firma
+// solo se acepta una vez.
+func (uc *DeviceUseCase) VerifySignature(ctx context.Context, id uint, timestamp string, body []byte, signature string) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return models.ErrInvalidDeviceCredentials
+	}
+
+	signedAt := time.Unix(unix, 0)
+	now := time.Now()
+	if signedAt.Before(now.Add(-signatureTolerance)) || signedAt.After(now.Add(signatureTolerance)) {
+		return models.ErrSignatureExpired
+	}
+
+	device, err := uc.activeDevice(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	provided, err := hex.DecodeString(signature)
+	if err != nil {
+		return models.ErrInvalidDeviceCredentials
+	}
+
+	mac := hmac.New(sha256.New, []byte(device.APIKey))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	if !hmac.Equal(mac.Sum(nil), provided) {
+		return models.ErrInvalidDeviceCredentials
+	}
+
+	// Recordar la firma hasta que salga de la ventana de tolerancia
+	if !uc.replay.remember(strconv.FormatUint(uint64(id), 10)+":"+hex.EncodeToString(provided), signedAt.Add(signatureTolerance)) {
+		return models.ErrSignatureReplayed
+	}
+
+	return nil
+}
+
+// activeDevice obtiene el dispositivo y comprueba que tenga una clave vigente
+func (uc *DeviceUseCase) activeDevice(ctx context.Context, id uint) (*models.Device, error) {
+	device, err := uc.deviceRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrDeviceNotFound) {
+			return nil, models.ErrInvalidDeviceCredentials
+		}
+		return nil, err
+	}
+
+	if !device.HasActiveKey() {
+		return nil, models.ErrInvalidDeviceCredentials
+	}
+
+	return device, nil
+}
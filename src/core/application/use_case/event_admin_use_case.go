@@ -0,0 +1,88 @@
+package use_case
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// EventAdminUseCase implementa las operaciones de administración del sistema de eventos
+type EventAdminUseCase struct {
+	deadLetters application.DeadLetterStore
+	eventStore  application.EventStore
+	broker      application.EventBroker
+	replaying   atomic.Bool
+}
+
+// NewEventAdminUseCase crea una nueva instancia de EventAdminUseCase
+func NewEventAdminUseCase(
+	deadLetters application.DeadLetterStore,
+	eventStore application.EventStore,
+	broker application.EventBroker,
+) *EventAdminUseCase {
+	return &EventAdminUseCase{
+		deadLetters: deadLetters,
+		eventStore:  eventStore,
+		broker:      broker,
+	}
+}
+
+// ListDeadLetters obtiene los eventos apartados por agotar sus reintentos
+func (uc *EventAdminUseCase) ListDeadLetters(ctx context.Context, limit int) ([]events.DeadLetter, error) {
+	return uc.deadLetters.ListDeadLetters(ctx, models.NormalizeLimit(limit))
+}
+
+// RequeueDeadLetters vuelve a entregar los eventos apartados indicados (todos si no se indica ninguno)
+func (uc *EventAdminUseCase) RequeueDeadLetters(ctx context.Context, req models.RequeueDeadLettersRequest) (int, error) {
+	return uc.deadLetters.RequeueDeadLetters(ctx, req.EventIDs)
+}
+
+// StartReplay valida la solicitud y reproduce los eventos en segundo plano, sin depender
+// de la cancelación de ctx. Solo admite una reproducción a la vez; el resultado se registra en el log.
+func (uc *EventAdminUseCase) StartReplay(ctx context.Context, req models.ReplayEventsRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	if !uc.replaying.CompareAndSwap(false, true) {
+		return models.ErrReplayInProgress
+	}
+
+	go func() {
+		defer uc.replaying.Store(false)
+
+		replayed, err := uc.ReplayEvents(context.WithoutCancel(ctx), req)
+		if err != nil {
+			log.Printf("Reproducción en %s interrumpida tras %d eventos: %v", req.Topic, replayed, err)
+			return
+		}
+		log.Printf("Eventos reproducidos en %s: %d", req.Topic, replayed)
+	}()
+
+	return nil
+}
+
+// ReplayEvents vuelve a publicar en el topic indicado, en orden y con sus IDs originales,
+// los eventos registrados que cumplen el filtro. Devuelve cuántos se publicaron, también
+// si se interrumpe por un error.
+func (uc *EventAdminUseCase) ReplayEvents(ctx context.Context, req models.ReplayEventsRequest) (int, error) {
+	if err := req.Validate(); err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	err := uc.eventStore.Stream(ctx, req.Filter(), func(logged events.LoggedEvent) error {
+		if err := uc.broker.Publish(ctx, req.Topic, logged.Event); err != nil {
+			return fmt.Errorf("error reproduciendo evento %s: %w", logged.Event.ID, err)
+		}
+		replayed++
+		return nil
+	})
+
+	return replayed, err
+}
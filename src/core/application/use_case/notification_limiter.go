@@ -0,0 +1,96 @@
+package use_case
+
+import (
+	"sync"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+)
+
+// notificationLimiter aplica models.NotificationRateLimit en memoria y cuenta los avisos
+// descartados, para indicarlos en el siguiente que se envía
+type notificationLimiter struct {
+	limit models.NotificationRateLimit
+
+	mu         sync.Mutex
+	lastSent   map[string]time.Time // último aviso por usuario, dispositivo y sensor
+	suppressed map[string]int       // avisos descartados desde el último enviado
+	sentByUser map[uint][]time.Time // avisos enviados a cada usuario en la última hora
+}
+
+// newNotificationLimiter crea un notificationLimiter vacío
+func newNotificationLimiter(limit models.NotificationRateLimit) *notificationLimiter {
+	return &notificationLimiter{
+		limit:      limit,
+		lastSent:   make(map[string]time.Time),
+		suppressed: make(map[string]int),
+		sentByUser: make(map[uint][]time.Time),
+	}
+}
+
+// reserve decide si se puede enviar un aviso y, si es así, lo da por enviado en at.
+// Devuelve también cuántos avisos de la misma clave se descartaron desde el anterior.
+func (l *notificationLimiter) reserve(userID uint, key string, at time.Time) (bool, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.purge(at)
+
+	if last, ok := l.lastSent[key]; ok && at.Sub(last) < l.limit.MinInterval {
+		l.suppressed[key]++
+		return false, 0
+	}
+
+	if l.limit.MaxPerHour > 0 && len(l.sentByUser[userID]) >= l.limit.MaxPerHour {
+		l.suppressed[key]++
+		return false, 0
+	}
+
+	suppressed := l.suppressed[key]
+	delete(l.suppressed, key)
+	l.lastSent[key] = at
+	l.sentByUser[userID] = append(l.sentByUser[userID], at)
+
+	return true, suppressed
+}
+
+// release deshace una reserva cuyo envío falló, para que pueda reintentarse
+func (l *notificationLimiter) release(userID uint, key string, at time.Time, suppressed int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lastSent[key].Equal(at) {
+		delete(l.lastSent, key)
+	}
+	l.suppressed[key] += suppressed
+
+	sent := l.sentByUser[userID]
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].Equal(at) {
+			l.sentByUser[userID] = append(sent[:i], sent[i+1:]...)
+			break
+		}
+	}
+}
+
+// purge olvida los envíos que ya no afectan a ningún límite
+func (l *notificationLimiter) purge(now time.Time) {
+	for key, last := range l.lastSent {
+		if now.Sub(last) >= l.limit.MinInterval && l.suppressed[key] == 0 {
+			delete(l.lastSent, key)
+		}
+	}
+
+	hourAgo := now.Add(-time.Hour)
+	for userID, sent := range l.sentByUser {
+		i := 0
+		for i < len(sent) && !sent[i].After(hourAgo) {
+			i++
+		}
+		if i == len(sent) {
+			delete(l.sentByUser, userID)
+		} else {
+			l.sentByUser[userID] = sent[i:]
+		}
+	}
+}
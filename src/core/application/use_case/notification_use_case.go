@@ -0,0 +1,163 @@
+package use_case
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// NotificationUseCase implementa los casos de uso relacionados con las notificaciones
+type NotificationUseCase struct {
+	prefRepo      application.NotificationPreferenceRepository
+	sensorRepo    application.SensorRepository
+	alertReadRepo application.AlertReadRepository
+	notifier      application.Notifier
+	limiter       *notificationLimiter
+	escalationTo  string
+}
+
+// NewNotificationUseCase crea una nueva instancia de NotificationUseCase. Las alertas
+// escaladas se envían a escalationTo, sin límite de avisos.
+func NewNotificationUseCase(
+	prefRepo application.NotificationPreferenceRepository,
+	sensorRepo application.SensorRepository,
+	alertReadRepo application.AlertReadRepository,
+	notifier application.Notifier,
+	limit models.NotificationRateLimit,
+	escalationTo string,
+) *NotificationUseCase {
+	return &NotificationUseCase{
+		prefRepo:      prefRepo,
+		sensorRepo:    sensorRepo,
+		alertReadRepo: alertReadRepo,
+		notifier:      notifier,
+		limiter:       newNotificationLimiter(limit),
+		escalationTo:  escalationTo,
+	}
+}
+
+// GetPreferences obtiene las preferencias de notificación del usuario
+func (uc *NotificationUseCase) GetPreferences(ctx context.Context, userID uint) (*models.NotificationPreferences, error) {
+	return uc.prefRepo.Get(ctx, userID)
+}
+
+// UpdatePreferences guarda las preferencias de notificación del usuario
+func (uc *NotificationUseCase) UpdatePreferences(ctx context.Context, userID uint, req models.UpdateNotificationPreferencesRequest) (*models.NotificationPreferences, error) {
+	prefs := &models.NotificationPreferences{
+		UserID:      userID,
+		EmailAlerts: *req.EmailAlerts,
+	}
+
+	if err := uc.prefRepo.Save(ctx, prefs); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// GetInbox obtiene la bandeja del usuario: las alertas más recientes (solo las no leídas si
+// unreadOnly) y cuántas tiene sin leer
+func (uc *NotificationUseCase) GetInbox(ctx context.Context, userID uint, unreadOnly bool, limit int) (*models.NotificationInbox, error) {
+	if limit <= 0 {
+		limit = models.DefaultInboxLimit
+	}
+	if limit > models.MaxInboxLimit {
+		limit = models.MaxInboxLimit
+	}
+
+	query := models.AlertQuery{AlertFilter: models.AlertFilter{UserID: userID}, Limit: limit}
+	if unreadOnly {
+		isRead := false
+		query.IsRead = &isRead
+	}
+
+	page, err := uc.sensorRepo.GetAlerts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := uc.alertReadRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := &models.NotificationInbox{
+		UnreadBySeverity: counts,
+		Alerts:           page.Data,
+	}
+	for _, count := range counts {
+		inbox.Unread += count
+	}
+
+	return inbox, nil
+}
+
+// MarkRead marca varias alertas como leídas para el usuario
+func (uc *NotificationUseCase) MarkRead(ctx context.Context, userID uint, alertIDs []uint) (int64, error) {
+	return uc.alertReadRepo.MarkRead(ctx, userID, models.AlertSelection{IDs: alertIDs}, time.Now())
+}
+
+// MarkAllRead marca todas las alertas como leídas para el usuario
+func (uc *NotificationUseCase) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	return uc.alertReadRepo.MarkRead(ctx, userID, models.AlertSelection{Filter: &models.AlertFilter{UserID: userID}}, time.Now())
+}
+
+// NotifyAlert envía la alerta por correo a los usuarios que lo han activado, respetando el
+// límite de avisos. Si algún envío falla devuelve el error para que el broker reintente; los
+// usuarios ya avisados no se repiten porque el límite los descarta.
+func (uc *NotificationUseCase) NotifyAlert(ctx context.Context, alert *events.SensorThresholdAlert) error {
+	recipients, err := uc.prefRepo.FindAlertRecipients(ctx)
+	if err != nil {
+		return fmt.Errorf("error obteniendo destinatarios: %w", err)
+	}
+
+	var errs []error
+	for _, user := range recipients {
+		key := fmt.Sprintf("%d:%d:%s", user.ID, alert.DeviceID, alert.SensorType)
+		now := time.Now()
+
+		ok, suppressed := uc.limiter.reserve(user.ID, key, now)
+		if !ok {
+			continue
+		}
+
+		notification, err := renderAlertEmail(user, alert, suppressed)
+		if err == nil {
+			err = uc.notifier.Notify(ctx, notification)
+		}
+		if err != nil {
+			uc.limiter.release(user.ID, key, now, suppressed)
+			errs = append(errs, fmt.Errorf("usuario %d: %w", user.ID, err))
+			continue
+		}
+
+		log.Printf("Alerta %d notificada a %s", alert.ID, user.Email)
+	}
+
+	return errors.Join(errs...)
+}
+
+// NotifyEscalation avisa al destinatario de escalado de una alerta crítica que sigue sin reconocer
+func (uc *NotificationUseCase) NotifyEscalation(ctx context.Context, alert *models.Alert) error {
+	if uc.escalationTo == "" {
+		return models.ErrNoEscalationTarget
+	}
+
+	notification, err := renderEscalationEmail(uc.escalationTo, alert)
+	if err != nil {
+		return err
+	}
+
+	if err := uc.notifier.Notify(ctx, notification); err != nil {
+		return err
+	}
+
+	log.Printf("Alerta %d escalada a %s", alert.ID, uc.escalationTo)
+	return nil
+}
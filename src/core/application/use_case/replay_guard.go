@@ -0,0 +1,71 @@
+package use_case
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// replayGuard recuerda firmas ya utilizadas hasta su expiración. Las entradas se guardan
+// además en un montículo ordenado por expiración, de modo que purgar solo recorre las
+// que ya han expirado en lugar de todo el mapa.
+type replayGuard struct {
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	expires replayExpiries
+}
+
+// newReplayGuard crea un replayGuard vacío
+func newReplayGuard() *replayGuard {
+	return &replayGuard{
+		seen: make(map[string]time.Time),
+	}
+}
+
+// remember registra la clave y devuelve false si ya había sido vista y no ha expirado
+func (g *replayGuard) remember(key string, expiresAt time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+
+	// Purgar entradas expiradas, de la más antigua a la más reciente
+	for len(g.expires) > 0 && now.After(g.expires[0].expiresAt) {
+		entry := heap.Pop(&g.expires).(replayEntry)
+		if g.seen[entry.key].Equal(entry.expiresAt) {
+			delete(g.seen, entry.key)
+		}
+	}
+
+	if exp, ok := g.seen[key]; ok && now.Before(exp) {
+		return false
+	}
+
+	g.seen[key] = expiresAt
+	heap.Push(&g.expires, replayEntry{key: key, expiresAt: expiresAt})
+	return true
+}
+
+// replayEntry es una clave recordada y su expiración
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// replayExpiries implementa heap.Interface con la expiración más próxima en la raíz
+type replayExpiries []replayEntry
+
+func (h replayExpiries) Len() int           { return len(h) }
+func (h replayExpiries) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h replayExpiries) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *replayExpiries) Push(x interface{}) {
+	*h = append(*h, x.(replayEntry))
+}
+
+func (h *replayExpiries) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
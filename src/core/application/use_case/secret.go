@@ -0,0 +1,16 @@
+package use_case
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// generateSecret genera un secreto aleatorio de 256 bits codificado en hexadecimal, como
+// las claves de los dispositivos o los secretos de firma de los webhooks
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
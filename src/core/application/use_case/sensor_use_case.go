@@ -2,8 +2,8 @@ package use_case
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"time"
 
 	"ApiSmart/src/core/application"
 	"ApiSmart/src/core/domain/events"
@@ -13,139 +13,303 @@ import (
 // SensorUseCase implementa los casos de uso relacionados con sensores
 type SensorUseCase struct {
 	sensorRepo      application.SensorRepository
+	alertReadRepo   application.AlertReadRepository
+	deviceRepo      application.DeviceRepository
+	outboxRepo      application.OutboxRepository
+	transactor      application.Transactor
 	alertService    application.AlertService
+	ruleEngine      application.RuleEngine
+	alertTracker    application.AlertTracker
 	eventDispatcher application.EventDispatcher
+	realtime        application.RealtimePublisher
 }
 
 // NewSensorUseCase crea una nueva instancia de SensorUseCase
 func NewSensorUseCase(
 	sensorRepo application.SensorRepository,
+	alertReadRepo application.AlertReadRepository,
+	deviceRepo application.DeviceRepository,
+	outboxRepo application.OutboxRepository,
+	transactor application.Transactor,
 	alertService application.AlertService,
+	ruleEngine application.RuleEngine,
+	alertTracker application.AlertTracker,
 	eventDispatcher application.EventDispatcher,
+	realtime application.RealtimePublisher,
 ) *SensorUseCase {
 	return &SensorUseCase{
 		sensorRepo:      sensorRepo,
+		alertReadRepo:   alertReadRepo,
+		deviceRepo:      deviceRepo,
+		outboxRepo:      outboxRepo,
+		transactor:      transactor,
 		alertService:    alertService,
+		ruleEngine:      ruleEngine,
+		alertTracker:    alertTracker,
 		eventDispatcher: eventDispatcher,
+		realtime:        realtime,
 	}
 }
 
 // SaveSensorData guarda datos de un sensor y genera alertas si es necesario
 func (uc *SensorUseCase) SaveSensorData(ctx context.Context, data *models.SensorData) error {
-	// Guardar los datos del sensor
-	if err := uc.sensorRepo.SaveSensorData(ctx, data); err != nil {
+	// Toda lectura debe pertenecer a un dispositivo registrado
+	if data.DeviceID == 0 {
+		return models.ErrDeviceIDRequired
+	}
+	if _, err := uc.deviceRepo.FindByID(ctx, data.DeviceID); err != nil {
 		return err
 	}
 
-	// Publicar evento de creación de datos
-	if uc.eventDispatcher != nil {
-		if err := uc.publishSensorDataCreatedEvent(ctx, data); err != nil {
-			log.Printf("Error al publicar evento de creación de datos: %v", err)
+	// La lectura, sus alertas y los eventos correspondientes se guardan en una única
+	// transacción; el relay de la bandeja de salida publica los eventos después
+	var alerts []models.Alert
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := uc.sensorRepo.SaveSensorData(ctx, data); err != nil {
+			return err
+		}
+		if err := uc.enqueueSensorDataCreatedEvent(ctx, data); err != nil {
+			return err
 		}
-	}
-
-	// Verificar si se deben generar alertas
-	alerts := uc.alertService.CheckAndCreateAlerts(data)
 
-	// Guardar y publicar las alertas generadas
-	for _, alert := range alerts {
-		if err := uc.sensorRepo.SaveAlert(ctx, &alert); err != nil {
+		// Verificar si se deben generar alertas por umbrales o por reglas; solo se
+		// publican las que se abren con esta lectura, no cada ocurrencia de las abiertas
+		var err error
+		alerts, err = uc.alertTracker.Track(ctx, data, func(firing map[string]bool) ([]models.Alert, error) {
+			active, err := uc.alertService.CheckAndCreateAlerts(ctx, data, firing)
+			if err != nil {
+				return nil, err
+			}
+			ruleAlerts, err := uc.ruleEngine.Evaluate(ctx, data)
+			if err != nil {
+				return nil, err
+			}
+			return append(active, ruleAlerts...), nil
+		})
+		if err != nil {
 			return err
 		}
 
-		// Publicar evento de alerta
-		if uc.eventDispatcher != nil {
-			if err := uc.publishAlertEvent(ctx, &alert); err != nil {
-				log.Printf("Error al publicar evento de alerta: %v", err)
+		for i := range alerts {
+			if err := uc.enqueueAlertEvent(ctx, data.DeviceID, &alerts[i]); err != nil {
+				return err
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notificar a los clientes conectados en tiempo real, solo con los datos ya confirmados
+	if uc.realtime != nil {
+		uc.realtime.Publish(events.TopicSensorData, events.EventTypeSensorDataCreated, data.DeviceID, data)
+		for _, alert := range alerts {
+			uc.realtime.Publish(events.TopicSensorAlerts, events.EventTypeSensorThresholdAlert, data.DeviceID, alert)
+		}
 	}
 
 	return nil
 }
 
-// GetAllSensorData obtiene todos los datos de sensores
-func (uc *SensorUseCase) GetAllSensorData(ctx context.Context) ([]models.SensorData, error) {
+// GetAllSensorData obtiene una página del histórico de datos de sensores
+func (uc *SensorUseCase) GetAllSensorData(ctx context.Context, query models.SensorDataQuery) (*models.SensorDataPage, error) {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return nil, models.ErrInvalidTimeRange
+	}
+
 	// Publicar evento de solicitud de datos
 	if uc.eventDispatcher != nil {
-		uc.publishSensorDataRequestedEvent(ctx, "all")
+		if err := uc.publishSensorDataRequestedEvent(ctx, "all"); err != nil {
+			log.Printf("Error al publicar evento de solicitud de datos: %v", err)
+		}
 	}
 
-	return uc.sensorRepo.GetAllSensorData(ctx)
+	return uc.sensorRepo.GetAllSensorData(ctx, query)
 }
 
-// GetLatestSensorData obtiene los datos más recientes del sensor
-func (uc *SensorUseCase) GetLatestSensorData(ctx context.Context) (*models.SensorData, error) {
+// GetLatestSensorData obtiene los datos más recientes del sensor, opcionalmente de un dispositivo
+func (uc *SensorUseCase) GetLatestSensorData(ctx context.Context, deviceID *uint) (*models.SensorData, error) {
 	// Publicar evento de solicitud de datos
 	if uc.eventDispatcher != nil {
-		uc.publishSensorDataRequestedEvent(ctx, "latest")
+		if err := uc.publishSensorDataRequestedEvent(ctx, "latest"); err != nil {
+			log.Printf("Error al publicar evento de solicitud de datos: %v", err)
+		}
+	}
+
+	return uc.sensorRepo.GetLatestSensorData(ctx, deviceID)
+}
+
+// AggregateSensorData agrega las lecturas en intervalos de tiempo y devuelve una serie por métrica
+func (uc *SensorUseCase) AggregateSensorData(ctx context.Context, query models.SensorAggregateQuery) (*models.SensorAggregate, error) {
+	if len(query.Metrics) == 0 {
+		query.Metrics = models.SensorMetrics
+	}
+	if len(query.Functions) == 0 {
+		query.Functions = []string{models.AggregateAvg}
+	}
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	series, err := uc.sensorRepo.AggregateSensorData(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SensorAggregate{
+		Bucket: models.FormatBucket(query.Bucket),
+		From:   query.From,
+		To:     query.To,
+		Series: series,
+	}, nil
+}
+
+// ExportSensorData recorre las lecturas del rango, de la más antigua a la más reciente
+func (uc *SensorUseCase) ExportSensorData(ctx context.Context, query models.SensorDataQuery, fn func(models.SensorData) error) error {
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		return models.ErrInvalidTimeRange
+	}
+
+	return uc.sensorRepo.StreamSensorData(ctx, query, fn)
+}
+
+// GetAlerts obtiene una página de las alertas del filtro, de la más reciente a la más antigua
+func (uc *SensorUseCase) GetAlerts(ctx context.Context, query models.AlertQuery) (*models.AlertPage, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return uc.sensorRepo.GetAlerts(ctx, query)
+}
+
+// CountAlerts cuenta las alertas del filtro agrupadas por tipo de sensor y/o día
+func (uc *SensorUseCase) CountAlerts(ctx context.Context, query models.AlertCountQuery) ([]models.AlertCount, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
 	}
 
-	return uc.sensorRepo.GetLatestSensorData(ctx)
+	return uc.sensorRepo.CountAlerts(ctx, query)
 }
 
-// GetAlerts obtiene las alertas filtradas por estado
-func (uc *SensorUseCase) GetAlerts(ctx context.Context, isRead *bool) ([]models.Alert, error) {
-	return uc.sensorRepo.GetAlerts(ctx, isRead)
+// GetAlertStates obtiene el estado de las condiciones de alerta, opcionalmente de un dispositivo
+func (uc *SensorUseCase) GetAlertStates(ctx context.Context, deviceID *uint) ([]models.AlertState, error) {
+	return uc.alertTracker.GetStates(ctx, deviceID)
+}
+
+// ExportAlerts recorre las alertas que cumplen el filtro, de la más antigua a la más reciente
+func (uc *SensorUseCase) ExportAlerts(ctx context.Context, filter models.AlertFilter, fn func(models.Alert) error) error {
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	return uc.sensorRepo.StreamAlerts(ctx, filter, fn)
 }
 
-// MarkAlertAsRead marca una alerta como leída
-func (uc *SensorUseCase) MarkAlertAsRead(ctx context.Context, alertID uint) error {
-	return uc.sensorRepo.MarkAlertAsRead(ctx, alertID)
+// MarkAlertAsRead marca una alerta como leída para el usuario
+func (uc *SensorUseCase) MarkAlertAsRead(ctx context.Context, userID, alertID uint) error {
+	if _, err := uc.sensorRepo.GetAlert(ctx, alertID, userID); err != nil {
+		return err
+	}
+
+	_, err := uc.alertReadRepo.MarkRead(ctx, userID, models.AlertSelection{IDs: []uint{alertID}}, time.Now())
+	return err
+}
+
+// UpdateAlerts marca como leídas o no leídas para el usuario las alertas seleccionadas y
+// devuelve cuántas cambiaron
+func (uc *SensorUseCase) UpdateAlerts(ctx context.Context, userID uint, req models.AlertBulkUpdateRequest) (int64, error) {
+	selection, err := req.Selection(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if *req.IsRead {
+		return uc.alertReadRepo.MarkRead(ctx, userID, selection, time.Now())
+	}
+	return uc.alertReadRepo.MarkUnread(ctx, userID, selection)
+}
+
+// DeleteAlerts elimina para todos las alertas seleccionadas y devuelve cuántas se eliminaron
+func (uc *SensorUseCase) DeleteAlerts(ctx context.Context, req models.AlertBulkRequest) (int64, error) {
+	selection, err := req.DeleteSelection()
+	if err != nil {
+		return 0, err
+	}
+
+	return uc.sensorRepo.DeleteAlerts(ctx, selection)
+}
+
+// AcknowledgeAlert marca una alerta abierta como reconocida por el usuario
+func (uc *SensorUseCase) AcknowledgeAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error) {
+	if _, err := uc.sensorRepo.GetAlert(ctx, alertID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := uc.sensorRepo.AcknowledgeAlert(ctx, alertID, userID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return uc.sensorRepo.GetAlert(ctx, alertID, userID)
+}
+
+// ResolveAlert resuelve en nombre del usuario una alerta abierta o reconocida
+func (uc *SensorUseCase) ResolveAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error) {
+	if _, err := uc.sensorRepo.GetAlert(ctx, alertID, userID); err != nil {
+		return nil, err
+	}
+
+	err := uc.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return uc.alertTracker.Resolve(ctx, alertID, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.sensorRepo.GetAlert(ctx, alertID, userID)
 }
 
 // Métodos auxiliares para publicar eventos
 
-// publishSensorDataCreatedEvent publica un evento de creación de datos del sensor
-func (uc *SensorUseCase) publishSensorDataCreatedEvent(ctx context.Context, data *models.SensorData) error {
-	eventData := map[string]interface{}{
-		"id":             data.ID,
-		"temperaturaDHT": data.TemperaturaDHT,
-		"luz":            data.Luz,
-		"humedad":        data.Humedad,
-		"humo":           data.Humo,
-		"created_at":     data.CreatedAt,
-	}
-
-	return uc.eventDispatcher.Dispatch(
-		ctx,
-		events.EventTypeSensorDataCreated,
-		events.TopicSensorData,
-		eventData,
-	)
-}
-
-// publishAlertEvent publica un evento de alerta
-func (uc *SensorUseCase) publishAlertEvent(ctx context.Context, alert *models.Alert) error {
-	eventData := map[string]interface{}{
-		"id":          alert.ID,
-		"sensor_id":   alert.SensorID,
-		"sensor_type": alert.SensorType,
-		"value":       alert.Value,
-		"message":     alert.Message,
-		"is_read":     alert.IsRead,
-		"created_at":  alert.CreatedAt,
-	}
-
-	return uc.eventDispatcher.Dispatch(
-		ctx,
-		events.EventTypeSensorThresholdAlert,
-		events.TopicSensorAlerts,
-		eventData,
-	)
+// enqueueSensorDataCreatedEvent guarda en la bandeja de salida el evento de creación de datos del sensor
+func (uc *SensorUseCase) enqueueSensorDataCreatedEvent(ctx context.Context, data *models.SensorData) error {
+	payload := events.SensorDataCreated{
+		ID:             data.ID,
+		DeviceID:       data.DeviceID,
+		TemperaturaDHT: data.TemperaturaDHT,
+		Luz:            data.Luz,
+		Humedad:        data.Humedad,
+		Humo:           data.Humo,
+		CreatedAt:      data.CreatedAt,
+	}
+
+	return uc.outboxRepo.Enqueue(ctx, events.TopicSensorData, payload)
+}
+
+// enqueueAlertEvent guarda en la bandeja de salida el evento de alerta
+func (uc *SensorUseCase) enqueueAlertEvent(ctx context.Context, deviceID uint, alert *models.Alert) error {
+	payload := events.SensorThresholdAlert{
+		ID:         alert.ID,
+		DeviceID:   deviceID,
+		SensorID:   alert.SensorID,
+		SensorType: alert.SensorType,
+		Severity:   alert.Severity,
+		Value:      alert.Value,
+		Message:    alert.Message,
+		IsRead:     alert.IsRead,
+		CreatedAt:  alert.CreatedAt,
+	}
+
+	return uc.outboxRepo.Enqueue(ctx, events.TopicSensorAlerts, payload)
 }
 
 // publishSensorDataRequestedEvent publica un evento de solicitud de datos
 func (uc *SensorUseCase) publishSensorDataRequestedEvent(ctx context.Context, requestType string) error {
-	eventData := map[string]interface{}{
-		"request_type": requestType,
-		"timestamp":    fmt.Sprintf("%v", ctx.Value("timestamp")),
+	payload := events.SensorDataRequested{
+		RequestType: requestType,
 	}
 
-	return uc.eventDispatcher.Dispatch(
-		ctx,
-		events.EventTypeSensorDataRequested,
-		events.TopicSensorData,
-		eventData,
-	)
+	return uc.eventDispatcher.Dispatch(ctx, events.TopicSensorData, payload)
 }
@@ -0,0 +1,96 @@
+package use_case
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// ThresholdUseCase implementa los casos de uso relacionados con los umbrales de alertas
+type ThresholdUseCase struct {
+	thresholdRepo application.ThresholdRepository
+	deviceRepo    application.DeviceRepository
+	alertService  application.AlertService
+}
+
+// NewThresholdUseCase crea una nueva instancia de ThresholdUseCase
+func NewThresholdUseCase(
+	thresholdRepo application.ThresholdRepository,
+	deviceRepo application.DeviceRepository,
+	alertService application.AlertService,
+) *ThresholdUseCase {
+	return &ThresholdUseCase{
+		thresholdRepo: thresholdRepo,
+		deviceRepo:    deviceRepo,
+		alertService:  alertService,
+	}
+}
+
+// GetThresholds obtiene los umbrales efectivos del ámbito indicado. Si un dispositivo no tiene
+// umbrales propios se devuelven los globales (con device_id nulo).
+func (uc *ThresholdUseCase) GetThresholds(ctx context.Context, deviceID *uint) (*models.ThresholdSettings, error) {
+	if deviceID != nil {
+		if _, err := uc.deviceRepo.FindByID(ctx, *deviceID); err != nil {
+			return nil, err
+		}
+
+		settings, err := uc.thresholdRepo.Get(ctx, deviceID)
+		if err == nil {
+			return settings, nil
+		}
+		if !errors.Is(err, models.ErrThresholdsNotFound) {
+			return nil, err
+		}
+	}
+
+	settings, err := uc.thresholdRepo.Get(ctx, nil)
+	if err != nil {
+		if errors.Is(err, models.ErrThresholdsNotFound) {
+			return &models.ThresholdSettings{AlertThresholds: models.DefaultAlertThresholds}, nil
+		}
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// UpdateThresholds valida y guarda los umbrales del ámbito indicado
+func (uc *ThresholdUseCase) UpdateThresholds(ctx context.Context, deviceID *uint, req models.UpdateThresholdsRequest) (*models.ThresholdSettings, error) {
+	thresholds := req.ToThresholds()
+	if err := thresholds.Validate(); err != nil {
+		return nil, err
+	}
+
+	if deviceID != nil {
+		if _, err := uc.deviceRepo.FindByID(ctx, *deviceID); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := &models.ThresholdSettings{
+		DeviceID:        deviceID,
+		AlertThresholds: thresholds,
+		UpdatedAt:       time.Now(),
+	}
+
+	if err := uc.thresholdRepo.Save(ctx, settings); err != nil {
+		return nil, err
+	}
+
+	uc.alertService.InvalidateThresholds(deviceID)
+
+	return settings, nil
+}
+
+// DeleteThresholds elimina los umbrales propios de un dispositivo, que vuelve a heredar los globales
+func (uc *ThresholdUseCase) DeleteThresholds(ctx context.Context, deviceID uint) error {
+	if err := uc.thresholdRepo.Delete(ctx, deviceID); err != nil {
+		return err
+	}
+
+	uc.alertService.InvalidateThresholds(&deviceID)
+	return nil
+}
@@ -0,0 +1,305 @@
+package use_case
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// webhookEventTypes son los tipos de evento a los que se puede suscribir un webhook
+var webhookEventTypes = map[string]bool{
+	events.EventTypeSensorDataCreated:    true,
+	events.EventTypeSensorThresholdAlert: true,
+}
+
+// webhookRetryLease es cuánto se aplaza un reintento reclamado: si la instancia cae
+// mientras lo entrega, otra lo retoma pasado ese tiempo
+const webhookRetryLease = 2 * time.Minute
+
+// WebhookUseCase implementa los casos de uso relacionados con webhooks
+type WebhookUseCase struct {
+	webhookRepo application.WebhookRepository
+	sender      application.WebhookSender
+	policy      models.WebhookDeliveryPolicy
+}
+
+// NewWebhookUseCase crea una nueva instancia de WebhookUseCase
+func NewWebhookUseCase(
+	webhookRepo application.WebhookRepository,
+	sender application.WebhookSender,
+	policy models.WebhookDeliveryPolicy,
+) *WebhookUseCase {
+	return &WebhookUseCase{
+		webhookRepo: webhookRepo,
+		sender:      sender,
+		policy:      policy,
+	}
+}
+
+// CreateWebhook registra un webhook del usuario y emite la clave con la que se firman sus entregas
+func (uc *WebhookUseCase) CreateWebhook(ctx context.Context, userID uint, req models.CreateWebhookRequest) (*models.WebhookCredentials, error) {
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		UserID:     userID,
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+		Active:     true,
+	}
+
+	if err := uc.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return &models.WebhookCredentials{Webhook: *webhook, Secret: secret}, nil
+}
+
+// GetWebhooks obtiene los webhooks del usuario
+func (uc *WebhookUseCase) GetWebhooks(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	return uc.webhookRepo.FindByUser(ctx, userID)
+}
+
+// GetWebhook obtiene un webhook del usuario
+func (uc *WebhookUseCase) GetWebhook(ctx context.Context, userID, id uint) (*models.Webhook, error) {
+	webhook, err := uc.webhookRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Los webhooks de otros usuarios se tratan como inexistentes
+	if webhook.UserID != userID {
+		return nil, models.ErrWebhookNotFound
+	}
+
+	return webhook, nil
+}
+
+// UpdateWebhook actualiza la URL, los tipos de evento y el estado de un webhook del usuario
+func (uc *WebhookUseCase) UpdateWebhook(ctx context.Context, userID, id uint, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	if err := validateWebhookEventTypes(req.EventTypes); err != nil {
+		return nil, err
+	}
+
+	webhook, err := uc.GetWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = req.URL
+	webhook.EventTypes = req.EventTypes
+	if req.Active != nil {
+		if *req.Active && !webhook.Active {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Active = *req.Active
+	}
+
+	if err := uc.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook elimina un webhook del usuario junto con su historial de entregas
+func (uc *WebhookUseCase) DeleteWebhook(ctx context.Context, userID, id uint) error {
+	if _, err := uc.GetWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return uc.webhookRepo.Delete(ctx, id)
+}
+
+// GetDeliveries obtiene los últimos intentos de entrega de un webhook del usuario
+func (uc *WebhookUseCase) GetDeliveries(ctx context.Context, userID, id uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := uc.GetWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	return uc.webhookRepo.ListDeliveries(ctx, id, models.NormalizeLimit(limit))
+}
+
+// DeliverEvent hace el primer intento de entrega del evento a todos los webhooks activos
+// suscritos a su tipo, en paralelo, y deja programados los reintentos de los que fallen.
+// Solo devuelve error si no se pudieron obtener los webhooks: los fallos de entrega quedan
+// en el historial y no deben provocar que el broker reentregue el evento a los webhooks que
+// sí lo recibieron.
+func (uc *WebhookUseCase) DeliverEvent(ctx context.Context, event events.Event) error {
+	if !webhookEventTypes[event.Type] {
+		return nil
+	}
+
+	webhooks, err := uc.webhookRepo.FindActiveByEventType(ctx, event.Type)
+	if err != nil {
+		return fmt.Errorf("error obteniendo webhooks: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range webhooks {
+		wg.Add(1)
+		go func(webhook *models.Webhook) {
+			defer wg.Done()
+			uc.deliver(ctx, webhook, event, 1, nil)
+		}(&webhooks[i])
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// RetryDeliveries reintenta hasta limit entregas pendientes cuyo turno ya ha llegado y
+// devuelve cuántas ha reintentado
+func (uc *WebhookUseCase) RetryDeliveries(ctx context.Context, limit int) (int, error) {
+	retries, err := uc.webhookRepo.ClaimDueRetries(ctx, time.Now(), webhookRetryLease, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error obteniendo reintentos: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range retries {
+		wg.Add(1)
+		go func(retry *models.WebhookRetry) {
+			defer wg.Done()
+			uc.retry(ctx, retry)
+		}(&retries[i])
+	}
+	wg.Wait()
+
+	return len(retries), nil
+}
+
+// retry vuelve a entregar una entrega pendiente si el webhook sigue activo
+func (uc *WebhookUseCase) retry(ctx context.Context, retry *models.WebhookRetry) {
+	webhook, err := uc.webhookRepo.FindByID(ctx, retry.WebhookID)
+	if err != nil && !errors.Is(err, models.ErrWebhookNotFound) {
+		log.Printf("Error obteniendo el webhook %d: %v", retry.WebhookID, err)
+		return
+	}
+
+	// Los webhooks eliminados, deshabilitados o que ya no reciben este tipo de evento
+	// descartan sus reintentos
+	if webhook == nil || !webhook.Active || !webhook.Subscribes(retry.Event.Type) {
+		if err := uc.webhookRepo.DeleteRetry(ctx, retry.ID); err != nil {
+			log.Printf("Error descartando el reintento %d: %v", retry.ID, err)
+		}
+		return
+	}
+
+	uc.deliver(ctx, webhook, retry.Event, retry.Attempt, retry)
+}
+
+// deliver hace el intento attempt de entregar el evento a un webhook. Si falla y quedan
+// intentos, programa el siguiente con espera creciente; si se agotan, suma un fallo
+// consecutivo al webhook. retry es la entrega pendiente que se reintenta, o nil en el
+// primer intento.
+func (uc *WebhookUseCase) deliver(ctx context.Context, webhook *models.Webhook, event events.Event, attempt int, retry *models.WebhookRetry) {
+	if uc.attempt(ctx, webhook, event, attempt) {
+		uc.dropRetry(ctx, retry)
+		if webhook.ConsecutiveFailures > 0 {
+			if err := uc.webhookRepo.RecordSuccess(ctx, webhook.ID); err != nil {
+				log.Printf("Error actualizando el webhook %d: %v", webhook.ID, err)
+			}
+		}
+		return
+	}
+
+	if attempt < uc.policy.MaxAttempts {
+		nextAttemptAt := time.Now().Add(uc.policy.Delay(attempt))
+
+		var err error
+		if retry == nil {
+			err = uc.webhookRepo.ScheduleRetry(ctx, &models.WebhookRetry{
+				WebhookID:     webhook.ID,
+				Event:         event,
+				Attempt:       attempt + 1,
+				NextAttemptAt: nextAttemptAt,
+			})
+		} else {
+			err = uc.webhookRepo.RescheduleRetry(ctx, retry.ID, attempt+1, nextAttemptAt)
+		}
+		if err != nil {
+			log.Printf("Error programando el reintento del evento %s al webhook %d: %v", event.ID, webhook.ID, err)
+		}
+		return
+	}
+
+	uc.dropRetry(ctx, retry)
+
+	if uc.policy.MaxConsecutiveFailures <= 0 {
+		return
+	}
+
+	disabled, err := uc.webhookRepo.RecordFailure(ctx, webhook.ID, uc.policy.MaxConsecutiveFailures)
+	if err != nil {
+		log.Printf("Error actualizando el webhook %d: %v", webhook.ID, err)
+		return
+	}
+	if disabled {
+		log.Printf("Webhook %d deshabilitado tras %d entregas fallidas seguidas", webhook.ID, uc.policy.MaxConsecutiveFailures)
+	}
+}
+
+// dropRetry elimina la entrega pendiente ya resuelta, si la hay
+func (uc *WebhookUseCase) dropRetry(ctx context.Context, retry *models.WebhookRetry) {
+	if retry == nil {
+		return
+	}
+	if err := uc.webhookRepo.DeleteRetry(ctx, retry.ID); err != nil {
+		log.Printf("Error eliminando el reintento %d: %v", retry.ID, err)
+	}
+}
+
+// attempt realiza un intento de entrega y lo guarda en el historial
+func (uc *WebhookUseCase) attempt(ctx context.Context, webhook *models.Webhook, event events.Event, attempt int) bool {
+	start := time.Now()
+	status, err := uc.sender.Send(ctx, webhook, event)
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		EventID:     event.ID,
+		EventType:   event.Type,
+		Attempt:     attempt,
+		Success:     err == nil,
+		DurationMs:  time.Since(start).Milliseconds(),
+		DeliveredAt: start,
+	}
+	if status != 0 {
+		delivery.StatusCode = &status
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+		log.Printf("Error entregando el evento %s al webhook %d (intento %d): %v", event.ID, webhook.ID, attempt, err)
+	}
+
+	if err := uc.webhookRepo.RecordDelivery(ctx, delivery); err != nil {
+		log.Printf("Error guardando la entrega al webhook %d: %v", webhook.ID, err)
+	}
+
+	return delivery.Success
+}
+
+// validateWebhookEventTypes comprueba que todos los tipos de evento sean admitidos
+func validateWebhookEventTypes(eventTypes []string) error {
+	for _, eventType := range eventTypes {
+		if !webhookEventTypes[eventType] {
+			return fmt.Errorf("%w: %s", models.ErrInvalidWebhookEventType, eventType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package use_case
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// fakeWebhookRepo es un WebhookRepository en memoria
+type fakeWebhookRepo struct {
+	mu         sync.Mutex
+	webhooks   map[uint]*models.Webhook
+	deliveries []models.WebhookDelivery
+	retries    map[uint64]*models.WebhookRetry
+	nextRetry  uint64
+}
+
+func newFakeWebhookRepo(webhooks ...models.Webhook) *fakeWebhookRepo {
+	repo := &fakeWebhookRepo{
+		webhooks: map[uint]*models.Webhook{},
+		retries:  map[uint64]*models.WebhookRetry{},
+	}
+	for i := range webhooks {
+		repo.webhooks[webhooks[i].ID] = &webhooks[i]
+	}
+	return repo
+}
+
+func (r *fakeWebhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	return errors.New("no implementado")
+}
+
+func (r *fakeWebhookRepo) FindByID(ctx context.Context, id uint) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, models.ErrWebhookNotFound
+	}
+	stored := *webhook
+	return &stored, nil
+}
+
+func (r *fakeWebhookRepo) FindByUser(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	return nil, errors.New("no implementado")
+}
+
+func (r *fakeWebhookRepo) FindActiveByEventType(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range r.webhooks {
+		if webhook.Active && webhook.Subscribes(eventType) {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (r *fakeWebhookRepo) Update(ctx context.Context, webhook *models.Webhook) error {
+	return errors.New("no implementado")
+}
+
+func (r *fakeWebhookRepo) Delete(ctx context.Context, id uint) error {
+	return errors.New("no implementado")
+}
+
+func (r *fakeWebhookRepo) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *fakeWebhookRepo) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	return nil, errors.New("no implementado")
+}
+
+func (r *fakeWebhookRepo) RecordSuccess(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.webhooks[id].ConsecutiveFailures = 0
+	return nil
+}
+
+func (r *fakeWebhookRepo) RecordFailure(ctx context.Context, id uint, maxFailures int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	webhook := r.webhooks[id]
+	webhook.ConsecutiveFailures++
+	if webhook.Active && webhook.ConsecutiveFailures >= maxFailures {
+		now := time.Now()
+		webhook.Active = false
+		webhook.DisabledAt = &now
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *fakeWebhookRepo) ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextRetry++
+	retry.ID = r.nextRetry
+	stored := *retry
+	r.retries[retry.ID] = &stored
+	return nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookRetry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []models.WebhookRetry{}
+	for _, retry := range r.retries {
+		if len(claimed) == limit {
+			break
+		}
+		if retry.NextAttemptAt.After(now) {
+			continue
+		}
+		retry.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, *retry)
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) RescheduleRetry(ctx context.Context, id uint64, attempt int, nextAttemptAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retries[id].Attempt = attempt
+	r.retries[id].NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (r *fakeWebhookRepo) DeleteRetry(ctx context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.retries, id)
+	return nil
+}
+
+func (r *fakeWebhookRepo) attempts() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := []int{}
+	for _, delivery := range r.deliveries {
+		attempts = append(attempts, delivery.Attempt)
+	}
+	return attempts
+}
+
+func (r *fakeWebhookRepo) pendingRetries() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.retries)
+}
+
+// fakeWebhookSender responde con los errores indicados en orden y después acepta las entregas
+type fakeWebhookSender struct {
+	mu     sync.Mutex
+	errors []error
+	sent   int
+}
+
+func (s *fakeWebhookSender) Send(ctx context.Context, webhook *models.Webhook, event events.Event) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent++
+	if len(s.errors) == 0 {
+		return 200, nil
+	}
+	err := s.errors[0]
+	s.errors = s.errors[1:]
+	return 500, err
+}
+
+func failures(n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = models.ErrWebhookRejected
+	}
+	return errs
+}
+
+func testWebhook() models.Webhook {
+	return models.Webhook{
+		ID:         1,
+		URL:        "https://example.com/hook",
+		EventTypes: []string{events.EventTypeSensorDataCreated},
+		Active:     true,
+	}
+}
+
+func sensorDataEvent(id string) events.Event {
+	return events.NewEvent(id, events.SensorDataCreated{ID: 1, DeviceID: 1}, time.Now())
+}
+
+// retryUntilIdle reintenta las entregas pendientes hasta que no queda ninguna vencida
+func retryUntilIdle(t *testing.T, uc *WebhookUseCase) {
+	t.Helper()
+
+	for i := 0; i < 10; i++ {
+		retried, err := uc.RetryDeliveries(context.Background(), 10)
+		if err != nil {
+			t.Fatalf("RetryDeliveries: %v", err)
+		}
+		if retried == 0 {
+			return
+		}
+	}
+	t.Fatal("los reintentos no terminan")
+}
+
+func TestDeliverEventMakesOnlyTheFirstAttempt(t *testing.T) {
+	repo := newFakeWebhookRepo(testWebhook())
+	sender := &fakeWebhookSender{errors: failures(1)}
+	uc := NewWebhookUseCase(repo, sender, models.WebhookDeliveryPolicy{MaxAttempts: 3, InitialDelay: time.Hour})
+
+	if err := uc.DeliverEvent(context.Background(), sensorDataEvent("evt-1")); err != nil {
+		t.Fatalf("DeliverEvent: %v", err)
+	}
+
+	if sender.sent != 1 {
+		t.Errorf("envíos = %d, se esperaba 1", sender.sent)
+	}
+	if repo.pendingRetries() != 1 {
+		t.Fatalf("reintentos pendientes = %d, se esperaba 1", repo.pendingRetries())
+	}
+
+	// El reintento no ha vencido todavía
+	if retried, err := uc.RetryDeliveries(context.Background(), 10); err != nil || retried != 0 {
+		t.Errorf("RetryDeliveries = %d, %v; se esperaba 0", retried, err)
+	}
+}
+
+func TestRetryDeliveriesStopsAfterMaxAttempts(t *testing.T) {
+	repo := newFakeWebhookRepo(testWebhook())
+	sender := &fakeWebhookSender{errors: failures(10)}
+	uc := NewWebhookUseCase(repo, sender, models.WebhookDeliveryPolicy{MaxAttempts: 3, MaxConsecutiveFailures: 5})
+
+	if err := uc.DeliverEvent(context.Background(), sensorDataEvent("evt-1")); err != nil {
+		t.Fatalf("DeliverEvent: %v", err)
+	}
+	retryUntilIdle(t, uc)
+
+	attempts := repo.attempts()
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 3 {
+		t.Errorf("intentos = %v, se esperaba [1 2 3]", attempts)
+	}
+	if repo.pendingRetries() != 0 {
+		t.Errorf("reintentos pendientes = %d, se esperaba 0", repo.pendingRetries())
+	}
+
+	webhook, _ := repo.FindByID(context.Background(), 1)
+	if webhook.ConsecutiveFailures != 1 {
+		t.Errorf("fallos consecutivos = %d, se esperaba 1", webhook.ConsecutiveFailures)
+	}
+}
+
+func TestRetryDeliveriesResetsFailuresOnSuccess(t *testing.T) {
+	webhook := testWebhook()
+	webhook.ConsecutiveFailures = 2
+	repo := newFakeWebhookRepo(webhook)
+	sender := &fakeWebhookSender{errors: failures(1)}
+	uc := NewWebhookUseCase(repo, sender, models.WebhookDeliveryPolicy{MaxAttempts: 3, MaxConsecutiveFailures: 5})
+
+	if err := uc.DeliverEvent(context.Background(), sensorDataEvent("evt-1")); err != nil {
+		t.Fatalf("DeliverEvent: %v", err)
+	}
+	retryUntilIdle(t, uc)
+
+	if sender.sent != 2 {
+		t.Errorf("envíos = %d, se esperaba 2", sender.sent)
+	}
+	if repo.pendingRetries() != 0 {
+		t.Errorf("reintentos pendientes = %d, se esperaba 0", repo.pendingRetries())
+	}
+
+	stored, _ := repo.FindByID(context.Background(), 1)
+	if stored.ConsecutiveFailures != 0 {
+		t.Errorf("fallos consecutivos = %d, se esperaba 0", stored.ConsecutiveFailures)
+	}
+}
+
+func TestDeliverEventDisablesWebhookAfterConsecutiveFailures(t *testing.T) {
+	repo := newFakeWebhookRepo(testWebhook())
+	sender := &fakeWebhookSender{errors: failures(10)}
+	uc := NewWebhookUseCase(repo, sender, models.WebhookDeliveryPolicy{MaxAttempts: 1, MaxConsecutiveFailures: 3})
+
+	for _, id := range []string{"evt-1", "evt-2", "evt-3", "evt-4"} {
+		if err := uc.DeliverEvent(context.Background(), sensorDataEvent(id)); err != nil {
+			t.Fatalf("DeliverEvent(%s): %v", id, err)
+		}
+	}
+
+	// El cuarto evento ya no se entrega: el webhook quedó deshabilitado con el tercero
+	if sender.sent != 3 {
+		t.Errorf("envíos = %d, se esperaba 3", sender.sent)
+	}
+
+	webhook, _ := repo.FindByID(context.Background(), 1)
+	if webhook.Active || webhook.DisabledAt == nil {
+		t.Error("el webhook debería quedar deshabilitado")
+	}
+	if webhook.ConsecutiveFailures != 3 {
+		t.Errorf("fallos consecutivos = %d, se esperaba 3", webhook.ConsecutiveFailures)
+	}
+}
+
+func TestRetryDeliveriesDropsRetriesOfDisabledWebhooks(t *testing.T) {
+	repo := newFakeWebhookRepo(testWebhook())
+	sender := &fakeWebhookSender{errors: failures(10)}
+	uc := NewWebhookUseCase(repo, sender, models.WebhookDeliveryPolicy{MaxAttempts: 3})
+
+	if err := uc.DeliverEvent(context.Background(), sensorDataEvent("evt-1")); err != nil {
+		t.Fatalf("DeliverEvent: %v", err)
+	}
+	repo.webhooks[1].Active = false
+	retryUntilIdle(t, uc)
+
+	if sender.sent != 1 {
+		t.Errorf("envíos = %d, se esperaba 1", sender.sent)
+	}
+	if repo.pendingRetries() != 0 {
+		t.Errorf("reintentos pendientes = %d, se esperaba 0", repo.pendingRetries())
+	}
+}
@@ -0,0 +1,19 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter es un evento que agotó sus reintentos (o que no se pudo procesar) y quedó
+// apartado en la cola de mensajes muertos de una suscripción
+type DeadLetter struct {
+	EventID   string          `json:"event_id"`
+	Queue     string          `json:"queue"`
+	Topic     string          `json:"topic"`
+	EventType string          `json:"event_type"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error,omitempty"`
+	DeadAt    time.Time       `json:"dead_at"`
+	Body      json.RawMessage `json:"body"`
+}
@@ -1,17 +1,79 @@
 package events
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 )
 
-// Event representa un evento en el sistema
+// Event representa un evento en el sistema. Data es el contenido tipado del evento
+// (ver payloads.go) en la versión de esquema SchemaVersion.
 type Event struct {
-	ID        string                 `json:"id"`
-	Type      string                 `json:"type"`
-	Data      map[string]interface{} `json:"data"`
-	Timestamp time.Time              `json:"timestamp"`
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	SchemaVersion int       `json:"schema_version"`
+	Data          Payload   `json:"data"`
+	Timestamp     time.Time `json:"timestamp"`
 }
 
+// NewEvent crea un evento con el tipo y la versión de esquema actuales del contenido
+func NewEvent(id string, payload Payload, timestamp time.Time) Event {
+	return Event{
+		ID:            id,
+		Type:          payload.EventType(),
+		SchemaVersion: CurrentSchemaVersion(payload.EventType()),
+		Data:          payload,
+		Timestamp:     timestamp,
+	}
+}
+
+// UnmarshalJSON decodifica el sobre y convierte el contenido al tipo registrado para
+// Type, actualizándolo a la versión de esquema vigente. Los eventos sin schema_version
+// se consideran de la versión 1.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var envelope struct {
+		ID            string          `json:"id"`
+		Type          string          `json:"type"`
+		SchemaVersion int             `json:"schema_version"`
+		Data          json.RawMessage `json:"data"`
+		Timestamp     time.Time       `json:"timestamp"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		return err
+	}
+	if envelope.SchemaVersion == 0 {
+		envelope.SchemaVersion = 1
+	}
+
+	payload, version, err := DefaultRegistry.Decode(envelope.Type, envelope.SchemaVersion, envelope.Data)
+	if err != nil {
+		return err
+	}
+
+	*e = Event{
+		ID:            envelope.ID,
+		Type:          envelope.Type,
+		SchemaVersion: version,
+		Data:          payload,
+		Timestamp:     envelope.Timestamp,
+	}
+	return nil
+}
+
+// ErrUnhandledEvent indica que un manejador no procesa ese tipo de evento. Reintentarlo no
+// sirve de nada, por lo que los brokers lo envían directamente a la cola de mensajes muertos.
+var ErrUnhandledEvent = errors.New("tipo de evento no manejado")
+
+// ErrBrokerUnavailable indica que el broker está desconectado o cerrado. La publicación
+// falla de inmediato en lugar de esperar a que se recupere la conexión.
+var ErrBrokerUnavailable = errors.New("broker de eventos no disponible")
+
+// Errores de confirmación de la publicación de un evento
+var (
+	ErrEventUnroutable   = errors.New("ninguna cola recibe el evento")
+	ErrEventNotConfirmed = errors.New("el broker no confirmó el evento")
+)
+
 // EventTypes define los tipos de eventos disponibles en el sistema
 const (
 	EventTypeSensorDataCreated    = "sensor.data.created"
@@ -27,3 +89,8 @@ const (
 	TopicSensorAlerts = "sensor.alerts"
 	TopicUserEvents   = "user.events"
 )
+
+// TopicReplayPrefix es el prefijo de los topics en los que se reproducen eventos. Son
+// exclusivos de la reproducción para que los consumidores en vivo (correos, webhooks) no
+// vuelvan a procesar eventos antiguos; un consumidor nuevo se suscribe a replay.<nombre>.
+const TopicReplayPrefix = "replay."
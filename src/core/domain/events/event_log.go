@@ -0,0 +1,13 @@
+package events
+
+import (
+	"time"
+)
+
+// LoggedEvent es un evento publicado tal como quedó registrado en el almacén de eventos
+type LoggedEvent struct {
+	ID         uint64
+	Topic      string
+	Event      Event
+	RecordedAt time.Time
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"time"
+)
+
+// OutboxMessage es un evento guardado en la misma transacción que los datos que lo
+// originan, pendiente de publicarse en el broker. NextAttemptAt es cuándo toca publicarlo.
+type OutboxMessage struct {
+	ID            uint64
+	Topic         string
+	Event         Event
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// OutboxRetryPolicy define cuántas veces se intenta publicar un evento de la bandeja de
+// salida antes de apartarlo y la espera entre intentos, que se duplica hasta MaxDelay
+type OutboxRetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// Exhausted indica si un evento con attempts intentos fallidos debe apartarse
+func (p OutboxRetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// Delay devuelve la espera antes del intento que sigue al intento fallido número attempt
+func (p OutboxRetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"time"
+)
+
+// Payload es el contenido tipado de un evento
+type Payload interface {
+	EventType() string
+}
+
+// DevicePayload lo implementan los contenidos que se refieren a un dispositivo
+type DevicePayload interface {
+	Payload
+	EventDeviceID() uint
+}
+
+// SensorDataCreated es el contenido de sensor.data.created.
+// Versión 2: añade device_id.
+type SensorDataCreated struct {
+	ID             uint      `json:"id"`
+	DeviceID       uint      `json:"device_id"`
+	TemperaturaDHT float64   `json:"temperaturaDHT"`
+	Luz            float64   `json:"luz"`
+	Humedad        float64   `json:"humedad"`
+	Humo           float64   `json:"humo"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// EventType implementa Payload
+func (SensorDataCreated) EventType() string { return EventTypeSensorDataCreated }
+
+// EventDeviceID implementa DevicePayload
+func (p SensorDataCreated) EventDeviceID() uint { return p.DeviceID }
+
+// SensorThresholdAlert es el contenido de sensor.threshold.alert.
+// Versión 2: añade device_id. Versión 3: añade severity.
+type SensorThresholdAlert struct {
+	ID         uint      `json:"id"`
+	DeviceID   uint      `json:"device_id"`
+	SensorID   uint      `json:"sensor_id"`
+	SensorType string    `json:"sensor_type"`
+	Severity   string    `json:"severity"`
+	Value      float64   `json:"value"`
+	Message    string    `json:"message"`
+	IsRead     bool      `json:"is_read"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EventType implementa Payload
+func (SensorThresholdAlert) EventType() string { return EventTypeSensorThresholdAlert }
+
+// EventDeviceID implementa DevicePayload
+func (p SensorThresholdAlert) EventDeviceID() uint { return p.DeviceID }
+
+// SensorDataRequested es el contenido de sensor.data.requested
+type SensorDataRequested struct {
+	RequestType string `json:"request_type"`
+}
+
+// EventType implementa Payload
+func (SensorDataRequested) EventType() string { return EventTypeSensorDataRequested }
+
+// UserRegistered es el contenido de user.registered
+type UserRegistered struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// EventType implementa Payload
+func (UserRegistered) EventType() string { return EventTypeUserRegistered }
+
+// UserAuthenticated es el contenido de user.authenticated
+type UserAuthenticated struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// EventType implementa Payload
+func (UserAuthenticated) EventType() string { return EventTypeUserAuthenticated }
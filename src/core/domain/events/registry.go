@@ -0,0 +1,169 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Errores al decodificar el contenido de un evento
+var (
+	ErrInvalidPayload           = errors.New("contenido de evento inválido")
+	ErrUnsupportedSchemaVersion = errors.New("versión de esquema de evento no soportada")
+)
+
+// Upcaster transforma el contenido de un evento de una versión de esquema a la siguiente,
+// trabajando sobre el JSON ya decodificado como mapa
+type Upcaster func(data map[string]interface{}) (map[string]interface{}, error)
+
+// payloadSchema describe el contenido de un tipo de evento
+type payloadSchema struct {
+	version   int
+	factory   func() Payload
+	upcasters map[int]Upcaster // por versión de origen
+}
+
+// Registry relaciona cada tipo de evento con su contenido tipado, su versión de esquema
+// vigente y los upcasters desde versiones anteriores
+type Registry struct {
+	schemas map[string]*payloadSchema
+}
+
+// NewRegistry crea un registro vacío
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas: make(map[string]*payloadSchema),
+	}
+}
+
+// Register registra el contenido de un tipo de evento en su versión de esquema vigente.
+// factory debe devolver un puntero a un valor vacío del tipo.
+func (r *Registry) Register(eventType string, version int, factory func() Payload) {
+	r.schemas[eventType] = &payloadSchema{
+		version:   version,
+		factory:   factory,
+		upcasters: make(map[int]Upcaster),
+	}
+}
+
+// RegisterUpcaster registra la transformación de la versión from a from+1
+func (r *Registry) RegisterUpcaster(eventType string, from int, upcaster Upcaster) {
+	r.schemas[eventType].upcasters[from] = upcaster
+}
+
+// CurrentVersion devuelve la versión de esquema vigente de un tipo de evento (1 si no está registrado)
+func (r *Registry) CurrentVersion(eventType string) int {
+	if schema, ok := r.schemas[eventType]; ok {
+		return schema.version
+	}
+	return 1
+}
+
+// Decode convierte el contenido recibido en la versión version al tipo registrado,
+// aplicando los upcasters hasta la versión vigente. Los tipos no registrados se
+// conservan como RawPayload para que el manejador decida.
+func (r *Registry) Decode(eventType string, version int, data json.RawMessage) (Payload, int, error) {
+	schema, ok := r.schemas[eventType]
+	if !ok {
+		return RawPayload{Type: eventType, Data: data}, version, nil
+	}
+	if version > schema.version {
+		return nil, version, fmt.Errorf("%w: %s v%d (vigente v%d)", ErrUnsupportedSchemaVersion, eventType, version, schema.version)
+	}
+
+	if version < schema.version {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, version, fmt.Errorf("%w: %s: %v", ErrInvalidPayload, eventType, err)
+		}
+		if fields == nil {
+			fields = map[string]interface{}{}
+		}
+
+		for ; version < schema.version; version++ {
+			upcaster, ok := schema.upcasters[version]
+			if !ok {
+				return nil, version, fmt.Errorf("%w: %s v%d no se puede actualizar", ErrUnsupportedSchemaVersion, eventType, version)
+			}
+			var err error
+			if fields, err = upcaster(fields); err != nil {
+				return nil, version, fmt.Errorf("%w: %s v%d: %v", ErrInvalidPayload, eventType, version, err)
+			}
+		}
+
+		upcasted, err := json.Marshal(fields)
+		if err != nil {
+			return nil, version, err
+		}
+		data = upcasted
+	}
+
+	payload := schema.factory()
+	if len(data) > 0 && string(data) != "null" {
+		if err := json.Unmarshal(data, payload); err != nil {
+			return nil, version, fmt.Errorf("%w: %s: %v", ErrInvalidPayload, eventType, err)
+		}
+	}
+
+	return payload, version, nil
+}
+
+// RawPayload es el contenido sin decodificar de un tipo de evento no registrado
+type RawPayload struct {
+	Type string
+	Data json.RawMessage
+}
+
+// EventType implementa Payload
+func (p RawPayload) EventType() string { return p.Type }
+
+// MarshalJSON serializa el contenido tal como se recibió
+func (p RawPayload) MarshalJSON() ([]byte, error) {
+	if len(p.Data) == 0 {
+		return []byte("null"), nil
+	}
+	return p.Data, nil
+}
+
+// DefaultRegistry contiene los eventos del sistema
+var DefaultRegistry = newDefaultRegistry()
+
+// CurrentSchemaVersion devuelve la versión de esquema vigente en DefaultRegistry
+func CurrentSchemaVersion(eventType string) int {
+	return DefaultRegistry.CurrentVersion(eventType)
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.Register(EventTypeSensorDataCreated, 2, func() Payload { return &SensorDataCreated{} })
+	r.RegisterUpcaster(EventTypeSensorDataCreated, 1, addDeviceID)
+
+	r.Register(EventTypeSensorThresholdAlert, 3, func() Payload { return &SensorThresholdAlert{} })
+	r.RegisterUpcaster(EventTypeSensorThresholdAlert, 1, addDeviceID)
+	r.RegisterUpcaster(EventTypeSensorThresholdAlert, 2, addSeverity)
+
+	r.Register(EventTypeSensorDataRequested, 1, func() Payload { return &SensorDataRequested{} })
+	r.Register(EventTypeUserRegistered, 1, func() Payload { return &UserRegistered{} })
+	r.Register(EventTypeUserAuthenticated, 1, func() Payload { return &UserAuthenticated{} })
+
+	return r
+}
+
+// addDeviceID actualiza a la versión 2 los eventos de sensores anteriores a los
+// dispositivos registrados, que no indicaban device_id (0 = desconocido)
+func addDeviceID(data map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := data["device_id"]; !ok {
+		data["device_id"] = 0
+	}
+	return data, nil
+}
+
+// addSeverity actualiza a la versión 3 las alertas anteriores a las gravedades, que se
+// consideran avisos ("warning")
+func addSeverity(data map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := data["severity"]; !ok {
+		data["severity"] = "warning"
+	}
+	return data, nil
+}
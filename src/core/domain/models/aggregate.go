@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Métricas de SensorData que se pueden agregar (mismos nombres que en el JSON)
+const (
+	MetricTemperaturaDHT = "temperaturaDHT"
+	MetricLuz            = "luz"
+	MetricHumedad        = "humedad"
+	MetricHumo           = "humo"
+)
+
+// Funciones de agregación disponibles
+const (
+	AggregateAvg   = "avg"
+	AggregateMin   = "min"
+	AggregateMax   = "max"
+	AggregateCount = "count"
+)
+
+// SensorMetrics son todas las métricas agregables, en el orden en que se devuelven por defecto
+var SensorMetrics = []string{MetricTemperaturaDHT, MetricLuz, MetricHumedad, MetricHumo}
+
+// AggregateFunctions son todas las funciones de agregación admitidas
+var AggregateFunctions = []string{AggregateAvg, AggregateMin, AggregateMax, AggregateCount}
+
+// MaxAggregateBuckets limita el número de intervalos que puede devolver una consulta
+const MaxAggregateBuckets = 5000
+
+var (
+	ErrInvalidBucket     = errors.New("intervalo de agregación inválido")
+	ErrInvalidMetric     = errors.New("métrica inválida")
+	ErrInvalidAggregate  = errors.New("función de agregación inválida")
+	ErrTooManyBuckets    = fmt.Errorf("la consulta supera el máximo de %d intervalos", MaxAggregateBuckets)
+	ErrTimeRangeRequired = errors.New("los parámetros from y to son obligatorios")
+	ErrSensorDataPurged  = errors.New("las lecturas de parte del rango ya se eliminaron; alinee from y to al intervalo de los resúmenes")
+)
+
+// SensorAggregateQuery son las opciones para agregar lecturas en intervalos de tiempo
+type SensorAggregateQuery struct {
+	DeviceID  *uint
+	From      time.Time // inclusivo
+	To        time.Time // exclusivo
+	Bucket    time.Duration
+	Metrics   []string
+	Functions []string
+}
+
+// Validate comprueba la coherencia de la consulta
+func (q SensorAggregateQuery) Validate() error {
+	if q.From.IsZero() || q.To.IsZero() {
+		return ErrTimeRangeRequired
+	}
+	if !q.From.Before(q.To) {
+		return ErrInvalidTimeRange
+	}
+	if q.Bucket < time.Minute || q.Bucket%time.Minute != 0 {
+		return ErrInvalidBucket
+	}
+	if int64(q.To.Sub(q.From)/q.Bucket) > MaxAggregateBuckets {
+		return ErrTooManyBuckets
+	}
+	for _, m := range q.Metrics {
+		if !contains(SensorMetrics, m) {
+			return fmt.Errorf("%w: %s", ErrInvalidMetric, m)
+		}
+	}
+	for _, fn := range q.Functions {
+		if !contains(AggregateFunctions, fn) {
+			return fmt.Errorf("%w: %s", ErrInvalidAggregate, fn)
+		}
+	}
+	return nil
+}
+
+// HasFunction indica si la consulta solicita la función de agregación fn
+func (q SensorAggregateQuery) HasFunction(fn string) bool {
+	return contains(q.Functions, fn)
+}
+
+// AggregatePoint es el resultado de una métrica en un intervalo
+type AggregatePoint struct {
+	Time  time.Time `json:"time"`
+	Avg   *float64  `json:"avg,omitempty"`
+	Min   *float64  `json:"min,omitempty"`
+	Max   *float64  `json:"max,omitempty"`
+	Count *int64    `json:"count,omitempty"`
+}
+
+// MetricSeries es la serie temporal agregada de una métrica
+type MetricSeries struct {
+	Metric string           `json:"metric"`
+	Points []AggregatePoint `json:"points"`
+}
+
+// SensorAggregate es la respuesta del endpoint de agregación
+type SensorAggregate struct {
+	Bucket string         `json:"bucket"`
+	From   time.Time      `json:"from"`
+	To     time.Time      `json:"to"`
+	Series []MetricSeries `json:"series"`
+}
+
+// ParseBucket interpreta un intervalo como "5m", "1h", "1d" o "1w". Los intervalos de
+// semanas empiezan en lunes.
+func ParseBucket(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, ErrInvalidBucket
+	}
+
+	unit := value[len(value)-1]
+	amount, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || amount <= 0 {
+		return 0, ErrInvalidBucket
+	}
+
+	switch unit {
+	case 'm':
+		return time.Duration(amount) * time.Minute, nil
+	case 'h':
+		return time.Duration(amount) * time.Hour, nil
+	case 'd':
+		return time.Duration(amount) * 24 * time.Hour, nil
+	case 'w':
+		return time.Duration(amount) * 7 * 24 * time.Hour, nil
+	default:
+		return 0, ErrInvalidBucket
+	}
+}
+
+// FormatBucket es la operación inversa de ParseBucket
+func FormatBucket(d time.Duration) string {
+	switch {
+	case d%(7*24*time.Hour) == 0:
+		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
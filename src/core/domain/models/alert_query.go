@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidAlertFilter    = errors.New("filtro de alertas inválido")
+	ErrInvalidAlertSelection = errors.New("indica ids o filter, solo uno de los dos")
+)
+
+// alertSensorTypes son los valores de sensor_type que puede tener una alerta
+var alertSensorTypes = map[string]bool{
+	"temperatura": true, "luz": true, "humedad": true, "humo": true, SensorTypeRule: true,
+}
+
+// alertSeverities son las gravedades que puede tener una alerta
+var alertSeverities = map[string]bool{
+	SeverityInfo: true, SeverityWarning: true, SeverityCritical: true,
+}
+
+// AlertFilter son las condiciones para seleccionar alertas. IsRead se refiere al estado de
+// lectura de UserID; las listas vacías no filtran.
+type AlertFilter struct {
+	UserID      uint
+	IsRead      *bool
+	DeviceID    *uint
+	SensorTypes []string
+	Severities  []string
+	From        *time.Time // inclusivo
+	To          *time.Time // exclusivo
+}
+
+// Validate comprueba el rango de fechas y los tipos de sensor y gravedades del filtro
+func (f AlertFilter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return ErrInvalidTimeRange
+	}
+
+	for _, sensorType := range f.SensorTypes {
+		if !alertSensorTypes[sensorType] {
+			return fmt.Errorf("%w: tipo de sensor desconocido %q", ErrInvalidAlertFilter, sensorType)
+		}
+	}
+
+	for _, severity := range f.Severities {
+		if !alertSeverities[severity] {
+			return fmt.Errorf("%w: gravedad desconocida %q", ErrInvalidAlertFilter, severity)
+		}
+	}
+
+	return nil
+}
+
+// hasCriteria indica si el filtro restringe las alertas por algo más que el estado de lectura
+func (f AlertFilter) hasCriteria() bool {
+	return f.DeviceID != nil || len(f.SensorTypes) > 0 || len(f.Severities) > 0 || f.From != nil || f.To != nil
+}
+
+// AlertQuery son las opciones para listar alertas, de la más reciente a la más antigua
+type AlertQuery struct {
+	AlertFilter
+	Limit  int
+	Cursor *Cursor
+}
+
+// AlertPage es una página del listado de alertas
+type AlertPage struct {
+	Data       []Alert `json:"data"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// AlertCountQuery agrupa las alertas del filtro por tipo de sensor, por día o por ambos
+type AlertCountQuery struct {
+	AlertFilter
+	ByType bool
+	ByDay  bool
+}
+
+// AlertCount es el número de alertas de un grupo. SensorType y Day solo se incluyen si se agrupa por ellos.
+type AlertCount struct {
+	SensorType string `json:"sensor_type,omitempty"`
+	Day        string `json:"day,omitempty"` // AAAA-MM-DD
+	Count      int    `json:"count"`
+}
+
+// AlertSelection selecciona alertas por sus IDs o por un filtro
+type AlertSelection struct {
+	IDs    []uint
+	Filter *AlertFilter
+}
+
+// AlertFilterRequest es el filtro de alertas de las operaciones masivas
+type AlertFilterRequest struct {
+	IsRead      *bool      `json:"is_read"`
+	DeviceID    *uint      `json:"device_id"`
+	SensorTypes []string   `json:"sensor_type"`
+	Severities  []string   `json:"severity"`
+	From        *time.Time `json:"from"`
+	To          *time.Time `json:"to"`
+}
+
+// AlertBulkRequest selecciona las alertas de una operación masiva: una lista de IDs o un
+// filtro. Para marcar como leídas un filtro vacío selecciona todas; para eliminar, no.
+type AlertBulkRequest struct {
+	IDs    []uint              `json:"ids" binding:"max=1000"`
+	Filter *AlertFilterRequest `json:"filter"`
+}
+
+// AlertBulkUpdateRequest cambia el estado de lectura de varias alertas para el usuario
+type AlertBulkUpdateRequest struct {
+	AlertBulkRequest
+	IsRead *bool `json:"is_read" binding:"required"`
+}
+
+// Selection convierte la solicitud en una selección de alertas, con el estado de lectura de userID
+func (r AlertBulkRequest) Selection(userID uint) (AlertSelection, error) {
+	if (len(r.IDs) == 0) == (r.Filter == nil) {
+		return AlertSelection{}, ErrInvalidAlertSelection
+	}
+
+	if r.Filter == nil {
+		return AlertSelection{IDs: r.IDs}, nil
+	}
+
+	filter := AlertFilter{
+		UserID:      userID,
+		IsRead:      r.Filter.IsRead,
+		DeviceID:    r.Filter.DeviceID,
+		SensorTypes: r.Filter.SensorTypes,
+		Severities:  r.Filter.Severities,
+		From:        r.Filter.From,
+		To:          r.Filter.To,
+	}
+	if err := filter.Validate(); err != nil {
+		return AlertSelection{}, err
+	}
+
+	return AlertSelection{Filter: &filter}, nil
+}
+
+// DeleteSelection convierte la solicitud en la selección de alertas a eliminar. Las alertas
+// son de todo el equipo, así que el filtro debe tener algún criterio y no puede usar el
+// estado de lectura, que es propio de cada usuario.
+func (r AlertBulkRequest) DeleteSelection() (AlertSelection, error) {
+	if r.Filter != nil && r.Filter.IsRead != nil {
+		return AlertSelection{}, fmt.Errorf("%w: no se puede eliminar por estado de lectura", ErrInvalidAlertSelection)
+	}
+
+	selection, err := r.Selection(0)
+	if err != nil {
+		return AlertSelection{}, err
+	}
+
+	if selection.Filter != nil && !selection.Filter.hasCriteria() {
+		return AlertSelection{}, fmt.Errorf("%w: el filtro para eliminar no puede estar vacío", ErrInvalidAlertSelection)
+	}
+
+	return selection, nil
+}
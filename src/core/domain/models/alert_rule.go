@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// SensorTypeRule es el sensor_type de las alertas generadas por reglas
+const SensorTypeRule = "regla"
+
+// AlertRule es una regla de alerta definida con una expresión del paquete rules, por ejemplo
+// "humedad < 35 AND temperaturaDHT > 28 FOR 15 minutes". DeviceID nil la aplica a todos los dispositivos.
+type AlertRule struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Expression string    `json:"expression"`
+	DeviceID   *uint     `json:"device_id"`
+	Severity   string    `json:"severity"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AlertRuleRequest crea o reemplaza una regla. Severity es "warning" y Enabled es true si no se indican.
+type AlertRuleRequest struct {
+	Name       string `json:"name" binding:"required,max=100"`
+	Expression string `json:"expression" binding:"required"`
+	DeviceID   *uint  `json:"device_id"`
+	Severity   string `json:"severity" binding:"omitempty,oneof=info warning critical"`
+	Enabled    *bool  `json:"enabled"`
+}
+
+// Errores de dominio para reglas de alerta
+var (
+	ErrAlertRuleNotFound = errors.New("regla de alerta no encontrada")
+	ErrInvalidAlertRule  = errors.New("regla de alerta inválida")
+)
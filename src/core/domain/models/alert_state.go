@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Estados del seguimiento de una condición de alerta
+const (
+	AlertStatePending  = "pending"  // La condición se cumple, pero aún no el tiempo mínimo
+	AlertStateFiring   = "firing"   // La alerta está abierta
+	AlertStateResolved = "resolved" // La condición dejó de cumplirse
+)
+
+// AlertState es el estado de una condición de alerta (Alert.Key) en un dispositivo. AlertID
+// es la última alerta abierta por la condición, si llegó a abrirse alguna.
+type AlertState struct {
+	DeviceID  uint      `json:"device_id"`
+	Key       string    `json:"key"`
+	Status    string    `json:"status"`
+	Since     time.Time `json:"since"` // Desde cuándo está en el estado actual
+	AlertID   *uint     `json:"alert_id"`
+	LastValue float64   `json:"last_value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlertClearBand es el margen que debe recuperar cada métrica respecto a su umbral para
+// resolver una alerta abierta, de modo que un valor que oscila sobre el umbral no la abre
+// y cierra continuamente
+type AlertClearBand struct {
+	Temperatura float64
+	Luz         float64
+	Humedad     float64
+	Humo        float64
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Device representa una placa (ESP32, etc.) que envía lecturas de sensores
+type Device struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Location    string     `json:"location"`
+	Description string     `json:"description"`
+	APIKey      string     `json:"-"` // Secreto compartido, solo se muestra al emitirlo
+	KeyIssuedAt *time.Time `json:"key_issued_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// HasActiveKey indica si el dispositivo tiene una clave vigente
+func (d *Device) HasActiveKey() bool {
+	return d.APIKey != ""
+}
+
+// DeviceCredentials se devuelve al registrar un dispositivo o rotar su clave
+type DeviceCredentials struct {
+	Device
+	APIKey string `json:"api_key"`
+}
+
+type CreateDeviceRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Location    string `json:"location" binding:"max=100"`
+	Description string `json:"description"`
+}
+
+type UpdateDeviceRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Location    string `json:"location" binding:"max=100"`
+	Description string `json:"description"`
+}
+
+// Errores de dominio para dispositivos
+var (
+	ErrDeviceNotFound   = errors.New("dispositivo no encontrado")
+	ErrDeviceIDRequired = errors.New("el campo device_id es obligatorio")
+	ErrDeviceIDMismatch = errors.New("el device_id no coincide con las credenciales del dispositivo")
+
+	ErrInvalidDeviceCredentials = errors.New("credenciales de dispositivo inválidas")
+	ErrSignatureExpired         = errors.New("la marca de tiempo de la firma está fuera de la ventana permitida")
+	ErrSignatureReplayed        = errors.New("la firma ya fue utilizada")
+)
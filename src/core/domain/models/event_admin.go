@@ -0,0 +1,61 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+)
+
+// RequeueDeadLettersRequest indica qué eventos apartados se vuelven a entregar; sin IDs
+// se reencolan todos
+type RequeueDeadLettersRequest struct {
+	EventIDs []string `json:"event_ids"`
+}
+
+var (
+	ErrReplayTopicRequired   = errors.New("se requiere el topic de destino")
+	ErrReplayTopicNotAllowed = errors.New("solo se puede reproducir en topics " + events.TopicReplayPrefix + "*")
+	ErrReplayInProgress      = errors.New("ya hay una reproducción en curso")
+)
+
+// EventLogFilter selecciona eventos del registro por fecha de publicación y tipo
+type EventLogFilter struct {
+	From       *time.Time
+	To         *time.Time
+	EventTypes []string
+}
+
+// ReplayEventsRequest pide volver a publicar en Topic, que debe empezar por
+// events.TopicReplayPrefix, los eventos registrados entre From y To (ambos opcionales) de
+// los tipos EventTypes (todos si está vacío)
+type ReplayEventsRequest struct {
+	Topic      string     `json:"topic" binding:"required"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+	EventTypes []string   `json:"event_types"`
+}
+
+// Validate comprueba el topic y el rango de fechas
+func (r ReplayEventsRequest) Validate() error {
+	if r.Topic == "" {
+		return ErrReplayTopicRequired
+	}
+	if !strings.HasPrefix(r.Topic, events.TopicReplayPrefix) || r.Topic == events.TopicReplayPrefix {
+		return ErrReplayTopicNotAllowed
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
+
+// Filter devuelve el filtro del registro de eventos correspondiente a la solicitud
+func (r ReplayEventsRequest) Filter() EventLogFilter {
+	return EventLogFilter{
+		From:       r.From,
+		To:         r.To,
+		EventTypes: r.EventTypes,
+	}
+}
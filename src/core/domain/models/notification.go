@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// NotificationPreferences son las preferencias de notificación de un usuario. Los correos
+// de alertas están desactivados hasta que el usuario los activa.
+type NotificationPreferences struct {
+	UserID      uint      `json:"user_id"`
+	EmailAlerts bool      `json:"email_alerts"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	EmailAlerts *bool `json:"email_alerts" binding:"required"`
+}
+
+// Notification es un mensaje listo para enviar, con una versión en texto y otra en HTML
+type Notification struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// NotificationRateLimit limita los avisos que recibe cada usuario: como mucho uno cada
+// MinInterval por dispositivo y tipo de sensor, y MaxPerHour en total (0 = sin límite)
+type NotificationRateLimit struct {
+	MinInterval time.Duration
+	MaxPerHour  int
+}
+
+// NotificationInbox es la bandeja de un usuario: sus alertas más recientes, con su propio
+// estado de lectura, y cuántas tiene sin leer en total y por gravedad
+type NotificationInbox struct {
+	Unread           int            `json:"unread"`
+	UnreadBySeverity map[string]int `json:"unread_by_severity"`
+	Alerts           []Alert        `json:"alerts"`
+}
+
+// MarkAlertsReadRequest marca varias alertas como leídas; los IDs que no existen se ignoran
+type MarkAlertsReadRequest struct {
+	AlertIDs []uint `json:"alert_ids" binding:"required,min=1,max=500"`
+}
+
+// Límites de la bandeja de notificaciones
+const (
+	DefaultInboxLimit = 50
+	MaxInboxLimit     = 200
+)
+
+// ErrNoEscalationTarget indica que no hay un destinatario configurado para escalar alertas
+var ErrNoEscalationTarget = errors.New("no hay destinatario de escalado configurado")
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Límites de paginación para los listados
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+var (
+	ErrInvalidCursor    = errors.New("cursor inválido")
+	ErrInvalidTimeRange = errors.New("el parámetro from debe ser anterior a to")
+)
+
+// Cursor identifica la última fila devuelta en una paginación por clave (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode serializa el cursor como una cadena opaca
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor interpreta una cadena generada por Cursor.Encode
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: time.Unix(0, nanos), ID: uint(id)}, nil
+}
+
+// NormalizeLimit aplica el límite por defecto y el máximo permitido
+func NormalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Resoluciones de las tablas de resumen de lecturas
+const (
+	RollupMinute = time.Minute
+	RollupHour   = time.Hour
+	RollupDay    = 24 * time.Hour
+)
+
+// RollupResolutions son las resoluciones de resumen, de la más fina a la más gruesa.
+// Cada una se calcula a partir de la anterior (la de un minuto, a partir de las lecturas).
+var RollupResolutions = []time.Duration{RollupMinute, RollupHour, RollupDay}
+
+// RetentionPolicy indica cuánto tiempo se conservan las lecturas y cada resumen.
+// Un valor 0 conserva los datos indefinidamente.
+type RetentionPolicy struct {
+	Raw    time.Duration
+	Minute time.Duration
+	Hour   time.Duration
+	Day    time.Duration
+}
+
+// For devuelve la retención de una resolución (0 para las lecturas sin resumir)
+func (p RetentionPolicy) For(resolution time.Duration) time.Duration {
+	switch resolution {
+	case 0:
+		return p.Raw
+	case RollupMinute:
+		return p.Minute
+	case RollupHour:
+		return p.Hour
+	case RollupDay:
+		return p.Day
+	default:
+		return 0
+	}
+}
@@ -1,9 +1,14 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type SensorData struct {
 	ID             uint      `json:"id"`
+	DeviceID       uint      `json:"device_id"`
 	TemperaturaDHT float64   `json:"temperaturaDHT"`
 	Luz            float64   `json:"luz"`
 	Humedad        float64   `json:"humedad"`
@@ -11,27 +16,168 @@ type SensorData struct {
 	CreatedAt      time.Time `json:"created_at"`
 }
 
+// SensorDataQuery son las opciones para consultar el histórico de lecturas.
+// Los resultados se ordenan del más reciente al más antiguo.
+type SensorDataQuery struct {
+	DeviceID *uint
+	From     *time.Time // inclusivo
+	To       *time.Time // exclusivo
+	Limit    int
+	Cursor   *Cursor
+}
+
+// SensorDataPage es una página del histórico de lecturas
+type SensorDataPage struct {
+	Data       []SensorData `json:"data"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Alert es una alerta de un dispositivo. Mientras la condición que la abrió se mantiene,
+// cada nueva lectura suma una ocurrencia a la misma alerta en lugar de crear otra. Pasa de
+// abierta a reconocida y a resuelta; AcknowledgedBy y ResolvedBy son el usuario que lo hizo
+// (ResolvedBy nil si se resolvió sola al volver los valores a la normalidad).
 type Alert struct {
-	ID         uint      `json:"id"`
-	SensorID   uint      `json:"sensor_id"`
-	SensorType string    `json:"sensor_type"` // "temperatura", "luz", "humedad", "humo"
-	Value      float64   `json:"value"`
-	Message    string    `json:"message"`
-	IsRead     bool      `json:"is_read"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID             uint       `json:"id"`
+	DeviceID       uint       `json:"device_id"`
+	SensorID       uint       `json:"sensor_id"`   // Lectura que abrió la alerta
+	SensorType     string     `json:"sensor_type"` // "temperatura", "luz", "humedad", "humo", "regla"
+	Value          float64    `json:"value"`
+	Message        string     `json:"message"`
+	IsRead         bool       `json:"is_read"`           // Leída por el usuario que la consulta
+	RuleID         *uint      `json:"rule_id,omitempty"` // Regla que la generó, si no es de umbrales
+	Severity       string     `json:"severity"`
+	Status         string     `json:"status"`
+	Occurrences    int        `json:"occurrences"`
+	LastValue      float64    `json:"last_value"`
+	LastSeenAt     time.Time  `json:"last_seen_at"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+	AcknowledgedBy *uint      `json:"acknowledged_by"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	ResolvedBy     *uint      `json:"resolved_by"`
+	EscalatedAt    *time.Time `json:"escalated_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+
+	// Direction es el sentido del umbral superado (ThresholdAbove o ThresholdBelow). Solo se
+	// rellena al evaluar las lecturas, para distinguir la condición en Key.
+	Direction string `json:"-"`
+}
+
+// Sentido del umbral que supera una alerta de umbrales
+const (
+	ThresholdAbove = "alta"
+	ThresholdBelow = "baja"
+)
+
+// Estados de una alerta
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// Gravedad de una alerta
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Errores de dominio para alertas
+var (
+	ErrAlertNotFound          = errors.New("alerta no encontrada")
+	ErrInvalidAlertTransition = errors.New("la alerta no admite ese cambio de estado")
+)
+
+// Key identifica la condición de la alerta dentro de su dispositivo: el tipo de sensor y el
+// sentido del umbral en las alertas de umbrales ("temperatura_alta") y "regla:<id>" en las
+// de reglas
+func (a Alert) Key() string {
+	if a.RuleID != nil {
+		return fmt.Sprintf("%s:%d", SensorTypeRule, *a.RuleID)
+	}
+	return ThresholdAlertKey(a.SensorType, a.Direction)
+}
+
+// ThresholdAlertKey devuelve la clave de la condición de umbral de un tipo de sensor en un sentido
+func ThresholdAlertKey(sensorType, direction string) string {
+	if direction == "" {
+		return sensorType
+	}
+	return sensorType + "_" + direction
 }
 
 // Umbrales para las alertas
 type AlertThresholds struct {
-	TemperaturaMax float64
-	TemperaturaMin float64
-	LuzMax         float64
-	LuzMin         float64
-	HumedadMax     float64
-	HumedadMin     float64
-	HumoMax        float64
+	TemperaturaMax float64 `json:"temperatura_max"`
+	TemperaturaMin float64 `json:"temperatura_min"`
+	LuzMax         float64 `json:"luz_max"`
+	LuzMin         float64 `json:"luz_min"`
+	HumedadMax     float64 `json:"humedad_max"`
+	HumedadMin     float64 `json:"humedad_min"`
+	HumoMax        float64 `json:"humo_max"`
 }
 
+// ErrInvalidThresholds se devuelve cuando los umbrales no son coherentes
+var ErrInvalidThresholds = errors.New("umbrales inválidos")
+
+// Validate comprueba que cada mínimo sea menor que su máximo
+func (t AlertThresholds) Validate() error {
+	pairs := []struct {
+		name     string
+		min, max float64
+	}{
+		{"temperatura", t.TemperaturaMin, t.TemperaturaMax},
+		{"luz", t.LuzMin, t.LuzMax},
+		{"humedad", t.HumedadMin, t.HumedadMax},
+	}
+
+	for _, p := range pairs {
+		if p.min >= p.max {
+			return fmt.Errorf("%w: %s_min (%.2f) debe ser menor que %s_max (%.2f)", ErrInvalidThresholds, p.name, p.min, p.name, p.max)
+		}
+	}
+
+	if t.HumoMax <= 0 {
+		return fmt.Errorf("%w: humo_max debe ser mayor que 0", ErrInvalidThresholds)
+	}
+
+	return nil
+}
+
+// ThresholdSettings son los umbrales persistidos para un ámbito.
+// DeviceID nil indica los umbrales globales.
+type ThresholdSettings struct {
+	DeviceID *uint `json:"device_id"`
+	AlertThresholds
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateThresholdsRequest struct {
+	TemperaturaMax *float64 `json:"temperatura_max" binding:"required"`
+	TemperaturaMin *float64 `json:"temperatura_min" binding:"required"`
+	LuzMax         *float64 `json:"luz_max" binding:"required"`
+	LuzMin         *float64 `json:"luz_min" binding:"required"`
+	HumedadMax     *float64 `json:"humedad_max" binding:"required"`
+	HumedadMin     *float64 `json:"humedad_min" binding:"required"`
+	HumoMax        *float64 `json:"humo_max" binding:"required"`
+}
+
+// ToThresholds convierte la solicitud en umbrales
+func (r UpdateThresholdsRequest) ToThresholds() AlertThresholds {
+	return AlertThresholds{
+		TemperaturaMax: *r.TemperaturaMax,
+		TemperaturaMin: *r.TemperaturaMin,
+		LuzMax:         *r.LuzMax,
+		LuzMin:         *r.LuzMin,
+		HumedadMax:     *r.HumedadMax,
+		HumedadMin:     *r.HumedadMin,
+		HumoMax:        *r.HumoMax,
+	}
+}
+
+// ErrThresholdsNotFound indica que no hay umbrales persistidos para el ámbito
+var ErrThresholdsNotFound = errors.New("no hay umbrales configurados")
+
 // Valores predeterminados para los umbrales de alertas
 var DefaultAlertThresholds = AlertThresholds{
 	TemperaturaMax: 30.0,
@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidStreamTicket indica un ticket de streaming inválido, expirado o ya utilizado
+var ErrInvalidStreamTicket = errors.New("ticket de streaming inválido o expirado")
 
 type User struct {
 	ID        uint      `json:"id"`
@@ -27,3 +33,10 @@ type AuthResponse struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 }
+
+// StreamTicket es un ticket de corta duración y un solo uso para abrir una conexión de
+// tiempo real (SSE o WebSocket) sin enviar el token de sesión en la URL
+type StreamTicket struct {
+	Ticket    string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+)
+
+// Webhook es una URL registrada por un usuario para recibir los eventos de los tipos indicados
+type Webhook struct {
+	ID                  uint       `json:"id"`
+	UserID              uint       `json:"user_id"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	Secret              string     `json:"-"` // Clave de la firma, solo se muestra al crearlo
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Subscribes indica si el webhook recibe los eventos del tipo indicado
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookCredentials se devuelve al crear un webhook, con la clave para verificar las firmas
+type WebhookCredentials struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery es un intento de entrega de un evento a un webhook. StatusCode es nil si
+// no se obtuvo respuesta (error de red o tiempo agotado).
+type WebhookDelivery struct {
+	ID          uint64    `json:"id"`
+	WebhookID   uint      `json:"webhook_id"`
+	EventID     string    `json:"event_id"`
+	EventType   string    `json:"event_type"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"duration_ms"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// WebhookRetry es una entrega pendiente de reintentar: el evento se vuelve a enviar al
+// webhook con el número de intento Attempt cuando llega NextAttemptAt
+type WebhookRetry struct {
+	ID            uint64
+	WebhookID     uint
+	Event         events.Event
+	Attempt       int
+	NextAttemptAt time.Time
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=500"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+}
+
+// UpdateWebhookRequest reemplaza la URL y los tipos de evento. Active en true reactiva un
+// webhook deshabilitado y pone a cero sus fallos.
+type UpdateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=500"`
+	EventTypes []string `json:"event_types" binding:"required,min=1"`
+	Active     *bool    `json:"active"`
+}
+
+// Errores de dominio para webhooks
+var (
+	ErrWebhookNotFound         = errors.New("webhook no encontrado")
+	ErrInvalidWebhookEventType = errors.New("tipo de evento no admitido para webhooks")
+	ErrWebhookRejected         = errors.New("el webhook respondió con un código de error")
+	ErrWebhookTargetForbidden  = errors.New("la URL del webhook apunta a una dirección no permitida")
+)
+
+// WebhookDeliveryPolicy define los reintentos de cada entrega y cuántas entregas fallidas
+// seguidas deshabilitan un webhook (0 = nunca)
+type WebhookDeliveryPolicy struct {
+	MaxAttempts            int
+	InitialDelay           time.Duration
+	MaxDelay               time.Duration
+	MaxConsecutiveFailures int
+}
+
+// Delay devuelve la espera antes del intento siguiente a attempt: se duplica en cada
+// intento hasta MaxDelay
+func (p WebhookDeliveryPolicy) Delay(attempt int) time.Duration {
+	delay := p.InitialDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,380 @@
+// Package rules implementa el lenguaje de expresiones de las reglas de alerta.
+//
+// Una expresión combina comparaciones entre métricas y números con AND, OR, NOT y
+// paréntesis, y puede terminar con una cláusula FOR que exige que la condición se cumpla
+// de forma continuada durante un tiempo:
+//
+//	humedad < 35 AND temperaturaDHT > 28 FOR 15 minutes
+//	(humo >= 50 OR luz < 5) AND NOT humedad > 90
+//
+// El lenguaje no tiene variables, llamadas ni bucles: una expresión solo puede leer las
+// métricas de una lectura, y su tamaño y anidamiento están limitados.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Límites de las expresiones
+const (
+	MaxExpressionLength = 500
+	MaxDuration         = 24 * time.Hour
+	maxDepth            = 32
+)
+
+// Métricas que se pueden usar en una expresión
+const (
+	MetricTemperaturaDHT = "temperaturaDHT"
+	MetricLuz            = "luz"
+	MetricHumedad        = "humedad"
+	MetricHumo           = "humo"
+)
+
+// metricNames resuelve los nombres admitidos (sin distinguir mayúsculas) a su métrica
+var metricNames = map[string]string{
+	"temperaturadht": MetricTemperaturaDHT,
+	"temperatura":    MetricTemperaturaDHT,
+	"luz":            MetricLuz,
+	"humedad":        MetricHumedad,
+	"humo":           MetricHumo,
+}
+
+// durationUnits son las unidades admitidas en la cláusula FOR
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"seg": time.Second, "segundo": time.Second, "segundos": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"minuto": time.Minute, "minutos": time.Minute,
+	"h": time.Hour, "hour": time.Hour, "hours": time.Hour, "hora": time.Hour, "horas": time.Hour,
+}
+
+// ErrSyntax indica que la expresión no es válida
+var ErrSyntax = errors.New("error de sintaxis")
+
+// Values son los valores de las métricas de una lectura
+type Values map[string]float64
+
+// Expression es una expresión ya analizada, que puede evaluarse cualquier número de veces
+type Expression struct {
+	source   string
+	root     node
+	duration time.Duration
+	metrics  []string
+}
+
+// Parse analiza una expresión
+func Parse(src string) (*Expression, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, fmt.Errorf("%w: la expresión está vacía", ErrSyntax)
+	}
+	if len(src) > MaxExpressionLength {
+		return nil, fmt.Errorf("%w: la expresión supera los %d caracteres", ErrSyntax, MaxExpressionLength)
+	}
+
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, metrics: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &Expression{source: src, root: root}
+
+	if p.peek().kind == tokenFor {
+		p.next()
+		if expr.duration, err = p.parseDuration(); err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, syntaxError(tok.pos, "se esperaba el final de la expresión y se encontró %q", tok.text)
+	}
+
+	for metric := range p.metrics {
+		expr.metrics = append(expr.metrics, metric)
+	}
+	sort.Strings(expr.metrics)
+
+	return expr, nil
+}
+
+// Eval indica si los valores cumplen la condición. Una métrica ausente vale 0.
+func (e *Expression) Eval(values Values) bool {
+	return e.root.eval(values)
+}
+
+// Duration es el tiempo durante el que debe cumplirse la condición (0 sin cláusula FOR)
+func (e *Expression) Duration() time.Duration {
+	return e.duration
+}
+
+// Metrics son las métricas que usa la expresión, ordenadas
+func (e *Expression) Metrics() []string {
+	return e.metrics
+}
+
+// String devuelve la expresión original
+func (e *Expression) String() string {
+	return e.source
+}
+
+// node es un nodo del árbol de la expresión
+type node interface {
+	eval(values Values) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(values Values) bool { return n.left.eval(values) && n.right.eval(values) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(values Values) bool { return n.left.eval(values) || n.right.eval(values) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(values Values) bool { return !n.inner.eval(values) }
+
+// operand es una métrica o, si metric está vacío, un número
+type operand struct {
+	metric string
+	value  float64
+}
+
+func (o operand) resolve(values Values) float64 {
+	if o.metric == "" {
+		return o.value
+	}
+	return values[o.metric]
+}
+
+type comparisonNode struct {
+	left, right operand
+	op          string
+}
+
+func (n comparisonNode) eval(values Values) bool {
+	left, right := n.left.resolve(values), n.right.resolve(values)
+	switch n.op {
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "==":
+		return left == right
+	default: // "!="
+		return left != right
+	}
+}
+
+// parser es un analizador descendente recursivo:
+//
+//	or         = and { OR and }
+//	and        = unary { AND unary }
+//	unary      = NOT unary | primary
+//	primary    = "(" or ")" | comparison
+//	comparison = operand operator operand
+//	operand    = métrica | ["-"] número
+type parser struct {
+	tokens  []token
+	pos     int
+	depth   int
+	metrics map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind != tokenNot {
+		return p.parsePrimary()
+	}
+
+	tok := p.next()
+	if err := p.enter(tok); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	inner, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return notNode{inner: inner}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.peek().kind != tokenLParen {
+		return p.parseComparison()
+	}
+
+	tok := p.next()
+	if err := p.enter(tok); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	inner, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if closing := p.next(); closing.kind != tokenRParen {
+		return nil, syntaxError(closing.pos, "falta el paréntesis de cierre del abierto en la posición %d", tok.pos)
+	}
+	return inner, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, syntaxError(op.pos, "se esperaba un operador de comparación (<, <=, >, >=, ==, !=)")
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	return comparisonNode{left: left, right: right, op: op.text}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenIdent:
+		metric, ok := metricNames[strings.ToLower(tok.text)]
+		if !ok {
+			return operand{}, syntaxError(tok.pos, "métrica desconocida %q (temperaturaDHT, luz, humedad o humo)", tok.text)
+		}
+		p.metrics[metric] = true
+		return operand{metric: metric}, nil
+
+	case tokenMinus:
+		num := p.next()
+		if num.kind != tokenNumber {
+			return operand{}, syntaxError(num.pos, "se esperaba un número después de -")
+		}
+		value, err := parseNumber(num)
+		return operand{value: -value}, err
+
+	case tokenNumber:
+		value, err := parseNumber(tok)
+		return operand{value: value}, err
+
+	default:
+		return operand{}, syntaxError(tok.pos, "se esperaba una métrica o un número")
+	}
+}
+
+// parseDuration analiza "<número> <unidad>" de la cláusula FOR
+func (p *parser) parseDuration() (time.Duration, error) {
+	num := p.next()
+	if num.kind != tokenNumber {
+		return 0, syntaxError(num.pos, "se esperaba la duración después de FOR")
+	}
+	amount, err := parseNumber(num)
+	if err != nil {
+		return 0, err
+	}
+
+	unitTok := p.next()
+	unit, ok := durationUnits[strings.ToLower(unitTok.text)]
+	if unitTok.kind != tokenIdent || !ok {
+		return 0, syntaxError(unitTok.pos, "se esperaba una unidad de tiempo (seconds, minutes u hours)")
+	}
+
+	duration := time.Duration(amount * float64(unit))
+	if duration <= 0 || duration > MaxDuration {
+		return 0, syntaxError(num.pos, "la duración debe ser mayor que 0 y como mucho %s", MaxDuration)
+	}
+
+	return duration, nil
+}
+
+// enter controla la profundidad de anidamiento
+func (p *parser) enter(tok token) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return syntaxError(tok.pos, "la expresión está anidada a más de %d niveles", maxDepth)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func parseNumber(tok token) (float64, error) {
+	value, err := strconv.ParseFloat(tok.text, 64)
+	if err != nil {
+		return 0, syntaxError(tok.pos, "número inválido %q", tok.text)
+	}
+	return value, nil
+}
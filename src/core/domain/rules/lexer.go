@@ -0,0 +1,133 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifica el tipo de un token
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator // <, <=, >, >=, ==, !=
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenFor
+	tokenLParen
+	tokenRParen
+	tokenMinus
+)
+
+// token es una unidad léxica de la expresión, con su posición (desde 1) para los errores
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// keywords son las palabras reservadas, sin distinguir mayúsculas
+var keywords = map[string]tokenKind{
+	"and": tokenAnd,
+	"or":  tokenOr,
+	"not": tokenNot,
+	"for": tokenFor,
+}
+
+// tokenize divide la expresión en tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := string(runes[start:i])
+			kind, ok := keywords[strings.ToLower(text)]
+			if !ok {
+				kind = tokenIdent
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: pos})
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			dot := false
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || (runes[i] == '.' && !dot)) {
+				if runes[i] == '.' {
+					dot = true
+				}
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: pos})
+
+		case r == '<' || r == '>' || r == '=' || r == '!':
+			next := rune(0)
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			switch {
+			case next == '=':
+				op := string([]rune{r, next})
+				tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+				i += 2
+			case r == '!':
+				tokens = append(tokens, token{kind: tokenNot, text: "!", pos: pos})
+				i++
+			case r == '=':
+				// "=" se acepta como "=="
+				tokens = append(tokens, token{kind: tokenOperator, text: "==", pos: pos})
+				i++
+			default:
+				tokens = append(tokens, token{kind: tokenOperator, text: string(r), pos: pos})
+				i++
+			}
+
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, syntaxError(pos, "se esperaba %c%c", r, r)
+			}
+			kind := tokenAnd
+			if r == '|' {
+				kind = tokenOr
+			}
+			tokens = append(tokens, token{kind: kind, text: string([]rune{r, r}), pos: pos})
+			i += 2
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			i++
+
+		case r == '-':
+			tokens = append(tokens, token{kind: tokenMinus, text: "-", pos: pos})
+			i++
+
+		default:
+			return nil, syntaxError(pos, "carácter inesperado %q", r)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// syntaxError construye un error de sintaxis con la posición en la que se produjo
+func syntaxError(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w en la posición %d: %s", ErrSyntax, pos, fmt.Sprintf(format, args...))
+}
@@ -14,11 +14,121 @@ type JWTConfig struct {
 	ExpiryHours int
 }
 
-// RabbitMQConfig define la configuración para RabbitMQ
+// RabbitMQConfig define la configuración para RabbitMQ.
+// EventFormat es "legacy" (events.Event en JSON), "cloudevents-structured" o
+// "cloudevents-binary"; EventSource es el atributo source de los CloudEvents.
 type RabbitMQConfig struct {
 	URL          string
 	ExchangeName string
 	QueueName    string
+	EventFormat  string
+	EventSource  string
+}
+
+// EventRetryConfig define cuántas veces se entrega un evento cuyo manejador falla y la
+// espera entre intentos, que se duplica en cada uno hasta MaxDelayMs
+type EventRetryConfig struct {
+	MaxAttempts    int
+	InitialDelayMs int
+	MaxDelayMs     int
+}
+
+// ConsumerConfig define la concurrencia con la que se consume un topic
+type ConsumerConfig struct {
+	Prefetch        int
+	Workers         int
+	OrderedByDevice bool
+}
+
+// ConsumersConfig agrupa la configuración de los consumidores de cada topic
+type ConsumersConfig struct {
+	SensorData   ConsumerConfig
+	SensorAlerts ConsumerConfig
+	UserEvents   ConsumerConfig
+	Webhooks     ConsumerConfig
+}
+
+// RetentionConfig define la configuración de los resúmenes y la retención de lecturas.
+// Los días en 0 conservan los datos indefinidamente.
+type RetentionConfig struct {
+	RollupEnabled bool
+	IntervalSecs  int
+	BatchSize     int
+	RawDays       int
+	MinuteDays    int
+	HourDays      int
+	DayDays       int
+}
+
+// OutboxConfig define la configuración del relay de la bandeja de salida de eventos.
+// SentRetentionHours en 0 conserva los eventos enviados indefinidamente. Un evento que
+// no se puede publicar se reintenta con espera que se duplica hasta RetryMaxDelayMs y se
+// aparta tras MaxAttempts intentos (0 = nunca).
+type OutboxConfig struct {
+	PollIntervalMs     int
+	BatchSize          int
+	SentRetentionHours int
+	MaxAttempts        int
+	RetryDelayMs       int
+	RetryMaxDelayMs    int
+}
+
+// WebhookConfig define la entrega de eventos a los webhooks: tiempo máximo de cada
+// petición, intentos por entrega con espera que se duplica hasta MaxDelayMs y cuántas
+// entregas fallidas seguidas deshabilitan un webhook (0 = nunca). Los reintentos
+// pendientes se revisan cada RetryIntervalMs. AllowPrivateTargets permite entregar a
+// direcciones locales o privadas, solo para desarrollo y pruebas.
+type WebhookConfig struct {
+	TimeoutMs              int
+	MaxAttempts            int
+	InitialDelayMs         int
+	MaxDelayMs             int
+	MaxConsecutiveFailures int
+	RetryIntervalMs        int
+	AllowPrivateTargets    bool
+}
+
+// SMTPConfig define el servidor de correo saliente. Sin Username no se autentica
+// (por ejemplo, con MailHog en localhost:1025).
+type SMTPConfig struct {
+	Host      string
+	Port      string
+	Username  string
+	Password  string
+	From      string
+	TimeoutMs int
+}
+
+// NotificationConfig define el envío de alertas por correo. Con EmailEnabled en false las
+// notificaciones solo se escriben en el log. Cada usuario recibe como mucho un aviso cada
+// MinIntervalMinutes por dispositivo y sensor, y MaxPerHour en total (0 = sin límite).
+type NotificationConfig struct {
+	EmailEnabled       bool
+	MinIntervalMinutes int
+	MaxPerHour         int
+}
+
+// AlertConfig define cuándo se abren y se cierran las alertas de umbrales. Una alerta abierta
+// solo se resuelve cuando la métrica vuelve al rango permitido con un margen de ClearBand*
+// (histéresis), y una condición debe mantenerse MinDurationSeconds antes de abrir la alerta.
+// Las alertas críticas que siguen abiertas sin reconocer tras EscalationMinutes se escalan
+// por correo a EscalationEmail; sin destinatario no se escala.
+type AlertConfig struct {
+	ClearBandTemperatura   float64
+	ClearBandLuz           float64
+	ClearBandHumedad       float64
+	ClearBandHumo          float64
+	MinDurationSeconds     int
+	RuleMaxSampleGapSecs   int // Mayor separación entre lecturas en las reglas con cláusula FOR
+	EscalationMinutes      int
+	EscalationEmail        string
+	EscalationIntervalSecs int
+}
+
+// AdminConfig define qué usuarios pueden usar las rutas de administración (/api/admin).
+// Sin ninguno, la administración queda deshabilitada.
+type AdminConfig struct {
+	UserIDs []uint
 }
 
 // HTTPConfig define la configuración para el servidor HTTP
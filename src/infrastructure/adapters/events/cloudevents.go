@@ -0,0 +1,226 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"github.com/streadway/amqp"
+)
+
+// Formatos en los que se publican los eventos
+const (
+	EventFormatLegacy     = "legacy"
+	EventFormatStructured = "cloudevents-structured"
+	EventFormatBinary     = "cloudevents-binary"
+)
+
+// Constantes de CloudEvents 1.0 y de su enlace con AMQP
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventsTypePrefix  = "com.smartgarden."
+	// Prefijo de los atributos en modo binario; también se acepta "cloudEvents_"
+	cloudEventsHeaderPrefix = "cloudEvents:"
+	// Extensión con la versión de esquema del contenido
+	cloudEventsSchemaVersion = "schemaversion"
+)
+
+// eventCodec convierte los eventos en mensajes AMQP en el formato configurado y
+// reconoce al recibir cualquiera de los formatos
+type eventCodec struct {
+	format string
+	source string
+}
+
+// newEventCodec valida el formato configurado
+func newEventCodec(format, source string) (eventCodec, error) {
+	switch format {
+	case "", EventFormatLegacy:
+		format = EventFormatLegacy
+	case EventFormatStructured, EventFormatBinary:
+	default:
+		return eventCodec{}, fmt.Errorf("formato de eventos desconocido: %s", format)
+	}
+
+	return eventCodec{format: format, source: source}, nil
+}
+
+// cloudEvent es un CloudEvent en modo estructurado
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	SchemaVersion   int             `json:"schemaversion,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// encode devuelve el cuerpo, el tipo de contenido y las cabeceras del mensaje. Las
+// cabeceras event_id, event_type y schema_version se añaden en todos los formatos.
+func (c eventCodec) encode(event events.Event) ([]byte, string, amqp.Table, error) {
+	headers := amqp.Table{
+		"event_type":     event.Type,
+		"event_id":       event.ID,
+		"schema_version": int32(event.SchemaVersion),
+	}
+
+	if c.format == EventFormatLegacy {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error serializando evento: %w", err)
+		}
+		return body, "application/json", headers, nil
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error serializando evento: %w", err)
+	}
+	subject := eventSubject(event)
+
+	if c.format == EventFormatBinary {
+		headers[cloudEventsHeaderPrefix+"specversion"] = cloudEventsSpecVersion
+		headers[cloudEventsHeaderPrefix+"id"] = event.ID
+		headers[cloudEventsHeaderPrefix+"source"] = c.source
+		headers[cloudEventsHeaderPrefix+"type"] = cloudEventsTypePrefix + event.Type
+		headers[cloudEventsHeaderPrefix+"time"] = event.Timestamp.UTC().Format(time.RFC3339Nano)
+		headers[cloudEventsHeaderPrefix+cloudEventsSchemaVersion] = int32(event.SchemaVersion)
+		if subject != "" {
+			headers[cloudEventsHeaderPrefix+"subject"] = subject
+		}
+		return data, "application/json", headers, nil
+	}
+
+	body, err := json.Marshal(cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          c.source,
+		Type:            cloudEventsTypePrefix + event.Type,
+		Subject:         subject,
+		Time:            event.Timestamp,
+		DataContentType: "application/json",
+		SchemaVersion:   event.SchemaVersion,
+		Data:            data,
+	})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error serializando evento: %w", err)
+	}
+	return body, cloudEventsContentType, headers, nil
+}
+
+// decode reconoce un CloudEvent binario, uno estructurado o el formato events.Event
+func (c eventCodec) decode(d amqp.Delivery) (events.Event, error) {
+	if _, ok := cloudEventsHeader(d.Headers, "specversion"); ok {
+		return decodeBinaryCloudEvent(d)
+	}
+
+	if strings.HasPrefix(d.ContentType, cloudEventsContentType) || isStructuredCloudEvent(d.Body) {
+		var ce cloudEvent
+		if err := json.Unmarshal(d.Body, &ce); err != nil {
+			return events.Event{}, err
+		}
+		return cloudEventToEvent(ce.ID, ce.Type, ce.SchemaVersion, ce.Time, ce.Data)
+	}
+
+	var event events.Event
+	err := json.Unmarshal(d.Body, &event)
+	return event, err
+}
+
+func decodeBinaryCloudEvent(d amqp.Delivery) (events.Event, error) {
+	id, _ := cloudEventsHeader(d.Headers, "id")
+	ceType, _ := cloudEventsHeader(d.Headers, "type")
+
+	var timestamp time.Time
+	if raw, ok := cloudEventsHeader(d.Headers, "time"); ok {
+		switch v := raw.(type) {
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return events.Event{}, fmt.Errorf("atributo time inválido: %w", err)
+			}
+			timestamp = parsed
+		case time.Time:
+			timestamp = v
+		}
+	}
+
+	version := 0
+	if raw, ok := cloudEventsHeader(d.Headers, cloudEventsSchemaVersion); ok {
+		switch v := raw.(type) {
+		case string:
+			version, _ = strconv.Atoi(v)
+		default:
+			version = intValue(v)
+		}
+	}
+
+	idStr, _ := id.(string)
+	typeStr, _ := ceType.(string)
+	return cloudEventToEvent(idStr, typeStr, version, timestamp, d.Body)
+}
+
+// cloudEventToEvent construye el evento interno a partir de los atributos de un CloudEvent
+func cloudEventToEvent(id, ceType string, version int, timestamp time.Time, data json.RawMessage) (events.Event, error) {
+	if version == 0 {
+		version = 1
+	}
+	eventType := strings.TrimPrefix(ceType, cloudEventsTypePrefix)
+
+	payload, version, err := events.DefaultRegistry.Decode(eventType, version, data)
+	if err != nil {
+		return events.Event{}, err
+	}
+
+	return events.Event{
+		ID:            id,
+		Type:          eventType,
+		SchemaVersion: version,
+		Data:          payload,
+		Timestamp:     timestamp,
+	}, nil
+}
+
+// cloudEventsHeader lee un atributo en modo binario con cualquiera de los prefijos admitidos
+func cloudEventsHeader(headers amqp.Table, name string) (interface{}, bool) {
+	if v, ok := headers[cloudEventsHeaderPrefix+name]; ok {
+		return v, true
+	}
+	v, ok := headers["cloudEvents_"+name]
+	return v, ok
+}
+
+// isStructuredCloudEvent detecta un CloudEvent estructurado publicado sin su tipo de contenido
+func isStructuredCloudEvent(body []byte) bool {
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	return json.Unmarshal(body, &probe) == nil && probe.SpecVersion != ""
+}
+
+// eventSubject devuelve el dispositivo al que se refiere el evento, o "" si no se refiere a ninguno
+func eventSubject(event events.Event) string {
+	payload := event.Data
+
+	// El relay de la bandeja de salida publica el contenido sin decodificar
+	if raw, ok := payload.(events.RawPayload); ok {
+		decoded, _, err := events.DefaultRegistry.Decode(event.Type, event.SchemaVersion, raw.Data)
+		if err != nil {
+			return ""
+		}
+		payload = decoded
+	}
+
+	device, ok := payload.(events.DevicePayload)
+	if !ok || device.EventDeviceID() == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(device.EventDeviceID()), 10)
+}
@@ -2,13 +2,11 @@ package events
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 
 	"ApiSmart/src/core/application"
 	"ApiSmart/src/core/domain/events"
-	"ApiSmart/src/core/domain/models"
 )
 
 // SensorDataHandler maneja eventos relacionados con datos de sensores
@@ -28,39 +26,38 @@ func (h *SensorDataHandler) Handle(ctx context.Context, event events.Event) erro
 	switch event.Type {
 	case events.EventTypeSensorDataCreated:
 		return h.handleSensorDataCreated(ctx, event)
+	case events.EventTypeSensorDataRequested:
+		// Solo informativo: se publica en el mismo topic que los datos
+		return nil
 	default:
-		return fmt.Errorf("tipo de evento no manejado: %s", event.Type)
+		return fmt.Errorf("%w: %s", events.ErrUnhandledEvent, event.Type)
 	}
 }
 
 // handleSensorDataCreated procesa eventos de creación de datos de sensores
 func (h *SensorDataHandler) handleSensorDataCreated(ctx context.Context, event events.Event) error {
-	// Convertir datos del evento a SensorData
-	var sensorData models.SensorData
-	dataBytes, err := json.Marshal(event.Data)
-	if err != nil {
-		return fmt.Errorf("error serializando datos del evento: %v", err)
-	}
-
-	if err := json.Unmarshal(dataBytes, &sensorData); err != nil {
-		return fmt.Errorf("error deserializando a SensorData: %v", err)
+	sensorData, ok := event.Data.(*events.SensorDataCreated)
+	if !ok {
+		return unexpectedPayload(event)
 	}
 
-	log.Printf("Procesando datos de sensor: ID=%d, Temperatura=%.2f",
-		sensorData.ID, sensorData.TemperaturaDHT)
+	log.Printf("Procesando datos de sensor: ID=%d, Dispositivo=%d, Temperatura=%.2f",
+		sensorData.ID, sensorData.DeviceID, sensorData.TemperaturaDHT)
 
 	return nil
 }
 
 // AlertHandler maneja eventos relacionados con alertas
 type AlertHandler struct {
-	sensorService application.SensorService
+	sensorService       application.SensorService
+	notificationService application.NotificationService
 }
 
 // NewAlertHandler crea un nuevo manejador de alertas
-func NewAlertHandler(sensorService application.SensorService) *AlertHandler {
+func NewAlertHandler(sensorService application.SensorService, notificationService application.NotificationService) *AlertHandler {
 	return &AlertHandler{
-		sensorService: sensorService,
+		sensorService:       sensorService,
+		notificationService: notificationService,
 	}
 }
 
@@ -70,28 +67,40 @@ func (h *AlertHandler) Handle(ctx context.Context, event events.Event) error {
 	case events.EventTypeSensorThresholdAlert:
 		return h.handleSensorAlert(ctx, event)
 	default:
-		return fmt.Errorf("tipo de evento no manejado: %s", event.Type)
+		return fmt.Errorf("%w: %s", events.ErrUnhandledEvent, event.Type)
 	}
 }
 
 // handleSensorAlert procesa eventos de alertas de sensores
 func (h *AlertHandler) handleSensorAlert(ctx context.Context, event events.Event) error {
-	// Convertir datos del evento a Alert
-	var alert models.Alert
-	dataBytes, err := json.Marshal(event.Data)
-	if err != nil {
-		return fmt.Errorf("error serializando datos del evento: %v", err)
+	alert, ok := event.Data.(*events.SensorThresholdAlert)
+	if !ok {
+		return unexpectedPayload(event)
 	}
 
-	if err := json.Unmarshal(dataBytes, &alert); err != nil {
-		return fmt.Errorf("error deserializando a Alert: %v", err)
-	}
-
-	// Aquí se implementaría la lógica de notificación (email, SMS, etc.)
+	// Los webhooks reciben la alerta desde su propia suscripción (WebhookHandler)
 	log.Printf("Procesando alerta: Tipo=%s, Mensaje=%s",
 		alert.SensorType, alert.Message)
 
-	return nil
+	return h.notificationService.NotifyAlert(ctx, alert)
+}
+
+// WebhookHandler entrega los eventos de sensores a los webhooks de los usuarios. Se
+// suscribe con su propia cola para no competir con los demás manejadores.
+type WebhookHandler struct {
+	webhookService application.WebhookService
+}
+
+// NewWebhookHandler crea un nuevo manejador de webhooks
+func NewWebhookHandler(webhookService application.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// Handle implementa application.EventHandler. Los tipos de evento sin webhooks se ignoran.
+func (h *WebhookHandler) Handle(ctx context.Context, event events.Event) error {
+	return h.webhookService.DeliverEvent(ctx, event)
 }
 
 // UserEventHandler maneja eventos relacionados con usuarios
@@ -110,14 +119,28 @@ func NewUserEventHandler(authService application.AuthService) *UserEventHandler
 func (h *UserEventHandler) Handle(ctx context.Context, event events.Event) error {
 	switch event.Type {
 	case events.EventTypeUserRegistered:
-		log.Printf("Usuario registrado: %v", event.Data["email"])
+		user, ok := event.Data.(*events.UserRegistered)
+		if !ok {
+			return unexpectedPayload(event)
+		}
+		log.Printf("Usuario registrado: %s", user.Email)
 		// Aquí se implementaría lógica adicional (envío de email de bienvenida, etc.)
 		return nil
 	case events.EventTypeUserAuthenticated:
-		log.Printf("Usuario autenticado: %v", event.Data["email"])
+		user, ok := event.Data.(*events.UserAuthenticated)
+		if !ok {
+			return unexpectedPayload(event)
+		}
+		log.Printf("Usuario autenticado: %s", user.Email)
 		// Aquí se implementaría lógica adicional (registro de login, etc.)
 		return nil
 	default:
-		return fmt.Errorf("tipo de evento no manejado: %s", event.Type)
+		return fmt.Errorf("%w: %s", events.ErrUnhandledEvent, event.Type)
 	}
 }
+
+// unexpectedPayload informa de un evento cuyo contenido no es del tipo registrado para
+// su tipo de evento; no tiene sentido reintentarlo
+func unexpectedPayload(event events.Event) error {
+	return fmt.Errorf("%w: %s con contenido %T", events.ErrUnhandledEvent, event.Type, event.Data)
+}
@@ -0,0 +1,333 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/tipo_de_datos"
+)
+
+// memoryMaxDeadLetters limita los eventos apartados que se conservan por cola; al
+// superarse se descartan los más antiguos
+const memoryMaxDeadLetters = 10000
+
+// MemoryBroker implementa application.EventBroker en proceso, sin RabbitMQ.
+// Reproduce el comportamiento del RabbitMQAdapter: una cola por topic suscrito, enlazada
+// con las mismas reglas de un exchange de tipo topic (* y #), entrega asíncrona y
+// reentrega del evento con espera creciente cuando el manejador devuelve un error, hasta
+// agotar los intentos y apartarlo como mensaje muerto.
+type MemoryBroker struct {
+	retry  retryPolicy
+	mu     sync.RWMutex
+	queues map[string]*memoryQueue
+	closed bool
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+// memoryDelivery es un evento pendiente de entregar junto con sus intentos previos
+type memoryDelivery struct {
+	topic    string
+	body     []byte
+	attempts int
+}
+
+// memoryQueue es una cola sin límite con uno o más consumidores que compiten por los eventos
+type memoryQueue struct {
+	binding string
+	mu      sync.Mutex
+	items   []memoryDelivery
+	ready   chan struct{}
+	dead    []events.DeadLetter
+	bodies  map[string]memoryDelivery // cuerpo original de cada mensaje muerto, por ID de evento
+}
+
+// NewMemoryBroker crea una nueva instancia de MemoryBroker
+func NewMemoryBroker(retry tipo_de_datos.EventRetryConfig) *MemoryBroker {
+	return &MemoryBroker{
+		retry:  newRetryPolicy(retry),
+		queues: make(map[string]*memoryQueue),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish entrega el evento a todas las colas cuyo enlace coincide con el topic
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, event events.Event) error {
+	// Serializar como lo haría RabbitMQ, para que los manejadores reciban los mismos tipos
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error serializando evento: %w", err)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return events.ErrBrokerUnavailable
+	}
+
+	for _, q := range b.queues {
+		if topicMatches(q.binding, topic) {
+			q.push(memoryDelivery{topic: topic, body: body})
+		}
+	}
+
+	log.Printf("Evento publicado (memoria): %s - %s", topic, event.ID)
+	return nil
+}
+
+// Subscribe suscribe un manejador a un topic. Las suscripciones al mismo topic comparten
+// cola. Prefetch no se aplica: los eventos se entregan a medida que hay un worker libre.
+func (b *MemoryBroker) Subscribe(topic string, handler application.EventHandler, options application.SubscriptionOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return events.ErrBrokerUnavailable
+	}
+
+	q, ok := b.queues[topic]
+	if !ok {
+		q = &memoryQueue{binding: topic, ready: make(chan struct{}, 1), bodies: make(map[string]memoryDelivery)}
+		b.queues[topic] = q
+	}
+
+	b.wg.Add(1)
+	go b.consume(topic, q, handler, newWorkerPool(options.Workers, options.OrderedByDevice))
+
+	log.Printf("Suscrito al topic (memoria): %s", topic)
+	return nil
+}
+
+// Close detiene los consumidores y espera a que terminen el evento en curso
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// consume procesa los eventos de la cola hasta que se cierra el broker
+func (b *MemoryBroker) consume(topic string, q *memoryQueue, handler application.EventHandler, pool *workerPool) {
+	defer b.wg.Done()
+	defer pool.stop()
+
+	for {
+		d, ok := q.pop()
+		if !ok {
+			select {
+			case <-b.done:
+				return
+			case <-q.ready:
+				continue
+			}
+		}
+
+		var event events.Event
+		if err := json.Unmarshal(d.body, &event); err != nil {
+			log.Printf("Error deserializando evento: %v", err)
+			q.bury(d, event, err)
+			continue
+		}
+
+		pool.submit(event, func() {
+			b.process(topic, q, handler, d, event)
+		})
+	}
+}
+
+// process entrega el evento al manejador y, si falla, lo reprograma o lo aparta
+func (b *MemoryBroker) process(topic string, q *memoryQueue, handler application.EventHandler, d memoryDelivery, event events.Event) {
+	if err := handler.Handle(context.Background(), event); err != nil {
+		log.Printf("Error procesando evento: %v", err)
+		d.attempts++
+		if b.retry.exhausted(d.attempts, err) {
+			log.Printf("Evento %s apartado tras %d intentos", event.ID, d.attempts)
+			q.bury(d, event, err)
+			return
+		}
+		b.redeliver(q, d)
+		return
+	}
+
+	log.Printf("Evento procesado (memoria): %s - %s", topic, event.ID)
+}
+
+// redeliver vuelve a encolar el evento tras una espera creciente, sin bloquear la cola
+func (b *MemoryBroker) redeliver(q *memoryQueue, d memoryDelivery) {
+	time.AfterFunc(b.retry.delay(d.attempts), func() {
+		select {
+		case <-b.done:
+		default:
+			q.push(d)
+		}
+	})
+}
+
+// ListDeadLetters implementa application.DeadLetterStore
+func (b *MemoryBroker) ListDeadLetters(ctx context.Context, limit int) ([]events.DeadLetter, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	letters := []events.DeadLetter{}
+	for _, topic := range b.sortedTopics() {
+		q := b.queues[topic]
+		q.mu.Lock()
+		for _, letter := range q.dead {
+			if len(letters) >= limit {
+				break
+			}
+			letters = append(letters, letter)
+		}
+		q.mu.Unlock()
+	}
+
+	return letters, nil
+}
+
+// RequeueDeadLetters implementa application.DeadLetterStore: vuelve a entregar los eventos
+// apartados indicados (todos si la lista está vacía) con los intentos a cero
+func (b *MemoryBroker) RequeueDeadLetters(ctx context.Context, eventIDs []string) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	ids := eventIDSet(eventIDs)
+	requeued := 0
+	for _, q := range b.queues {
+		q.mu.Lock()
+		var kept []events.DeadLetter
+		var revived []memoryDelivery
+		for _, letter := range q.dead {
+			if !matchesEventID(ids, letter.EventID) {
+				kept = append(kept, letter)
+				continue
+			}
+			d := q.bodies[letter.EventID]
+			delete(q.bodies, letter.EventID)
+			d.attempts = 0
+			revived = append(revived, d)
+		}
+		q.dead = kept
+		q.mu.Unlock()
+
+		for _, d := range revived {
+			q.push(d)
+		}
+		requeued += len(revived)
+	}
+
+	return requeued, nil
+}
+
+// sortedTopics devuelve los topics suscritos en orden estable
+func (b *MemoryBroker) sortedTopics() []string {
+	topics := make([]string, 0, len(b.queues))
+	for topic := range b.queues {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// bury aparta el evento como mensaje muerto
+func (q *memoryQueue) bury(d memoryDelivery, event events.Event, cause error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Sin ID no se podría reencolar individualmente
+	eventID := event.ID
+	if eventID == "" || q.bodies[eventID].body != nil {
+		eventID = fmt.Sprintf("%s-%d", q.binding, time.Now().UnixNano())
+	}
+
+	q.dead = append(q.dead, events.DeadLetter{
+		EventID:   eventID,
+		Queue:     q.binding,
+		Topic:     d.topic,
+		EventType: event.Type,
+		Attempts:  d.attempts,
+		LastError: cause.Error(),
+		DeadAt:    time.Now(),
+		Body:      deadLetterBody(d.body),
+	})
+	q.bodies[eventID] = d
+
+	if len(q.dead) > memoryMaxDeadLetters {
+		delete(q.bodies, q.dead[0].EventID)
+		q.dead = q.dead[1:]
+	}
+}
+
+func (q *memoryQueue) push(d memoryDelivery) {
+	q.mu.Lock()
+	q.items = append(q.items, d)
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (q *memoryQueue) pop() (memoryDelivery, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) == 0 {
+		return memoryDelivery{}, false
+	}
+
+	d := q.items[0]
+	q.items = q.items[1:]
+
+	// Despertar a otro consumidor si quedan eventos
+	if len(q.items) > 0 {
+		select {
+		case q.ready <- struct{}{}:
+		default:
+		}
+	}
+
+	return d, true
+}
+
+// topicMatches aplica las reglas de enlace de un exchange topic de AMQP:
+// "*" sustituye exactamente una palabra y "#" cero o más
+func topicMatches(binding, routingKey string) bool {
+	return matchWords(strings.Split(binding, "."), strings.Split(routingKey, "."))
+}
+
+func matchWords(pattern, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchWords(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchWords(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchWords(pattern[1:], words[1:])
+	}
+}
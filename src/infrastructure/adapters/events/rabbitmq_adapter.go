@@ -2,9 +2,10 @@ package events
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"ApiSmart/src/core/application"
@@ -14,84 +15,125 @@ import (
 	"github.com/streadway/amqp"
 )
 
-// RabbitMQAdapter implementa application.EventBroker usando RabbitMQ
+// Cabeceras con las que se sigue el ciclo de reintentos de un mensaje
+const (
+	headerRetryCount    = "x-retry-count"
+	headerLastError     = "x-last-error"
+	headerOriginalTopic = "x-original-topic"
+	headerDeadAt        = "x-dead-lettered-at"
+)
+
+// RabbitMQAdapter implementa application.EventBroker usando RabbitMQ.
+// Cada cola de suscripción tiene su cola de mensajes muertos (<cola>.dlq, enlazada al
+// exchange <exchange>.dlx) y una cola de espera por reintento (<cola>.retry.<n>.<ms>) cuyo
+// TTL devuelve el mensaje a la cola principal. La espera forma parte del nombre, de modo
+// que cambiar la configuración crea colas nuevas en lugar de chocar con las existentes. Si la conexión se pierde, un supervisor la
+// restablece y vuelve a registrar todas las suscripciones (ver rabbitmq_connection.go).
 type RabbitMQAdapter struct {
-	conn         *amqp.Connection
-	channel      *amqp.Channel
+	url          string
 	exchangeName string
 	queueName    string
+	retry        retryPolicy
+	codec        eventCodec
+
+	mu            sync.RWMutex
+	conn          *amqp.Connection // nil mientras está desconectado
+	channel       *amqp.Channel    // canal de control, para declarar la topología
+	publisher     *confirmPublisher
+	subscriptions []rabbitSubscription
+	closed        bool
+	done          chan struct{}
+}
+
+// rabbitSubscription es una suscripción registrada, que se restablece al reconectar
+type rabbitSubscription struct {
+	topic     string
+	queueName string
+	handler   application.EventHandler
+	options   application.SubscriptionOptions
 }
 
-// NewRabbitMQAdapter crea una nueva instancia de RabbitMQAdapter
-func NewRabbitMQAdapter(config tipo_de_datos.RabbitMQConfig) (*RabbitMQAdapter, error) {
-	// Conectar a RabbitMQ
-	conn, err := amqp.Dial(config.URL)
+// NewRabbitMQAdapter crea una nueva instancia de RabbitMQAdapter. Si RabbitMQ no está
+// disponible el adaptador arranca desconectado y el supervisor sigue intentándolo: hasta
+// entonces Publish falla con events.ErrBrokerUnavailable (la bandeja de salida espera) y
+// las suscripciones se establecen al conectar. Solo falla si la configuración es inválida.
+func NewRabbitMQAdapter(config tipo_de_datos.RabbitMQConfig, retry tipo_de_datos.EventRetryConfig) (*RabbitMQAdapter, error) {
+	codec, err := newEventCodec(config.EventFormat, config.EventSource)
 	if err != nil {
-		return nil, fmt.Errorf("error conectando a RabbitMQ: %w", err)
+		return nil, err
 	}
 
-	// Abrir un canal
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("error abriendo canal: %w", err)
+	a := &RabbitMQAdapter{
+		url:          config.URL,
+		exchangeName: config.ExchangeName,
+		queueName:    config.QueueName,
+		retry:        newRetryPolicy(retry),
+		codec:        codec,
+		done:         make(chan struct{}),
 	}
 
-	// Declarar exchange
-	err = ch.ExchangeDeclare(
-		config.ExchangeName, // nombre
-		"topic",             // tipo
-		true,                // durable
-		false,               // auto-delete
-		false,               // internal
-		false,               // no-wait
-		nil,                 // argumentos
-	)
+	conn, ch, publisher, err := a.connect()
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("error declarando exchange: %w", err)
+		log.Printf("Error conectando a RabbitMQ, se reintentará en segundo plano: %v", err)
+		go a.connectInBackground()
+		return a, nil
 	}
+	a.conn, a.channel, a.publisher = conn, ch, publisher
 
-	return &RabbitMQAdapter{
-		conn:         conn,
-		channel:      ch,
-		exchangeName: config.ExchangeName,
-		queueName:    config.QueueName,
-	}, nil
+	go a.supervise(conn, ch, publisher)
+
+	return a, nil
 }
 
-// Close cierra la conexión con RabbitMQ
+// Close cierra la conexión con RabbitMQ y detiene el supervisor
 func (a *RabbitMQAdapter) Close() error {
-	if err := a.channel.Close(); err != nil {
-		return err
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
 	}
-	return a.conn.Close()
+	a.closed = true
+	close(a.done)
+	conn := a.conn
+	a.conn, a.channel, a.publisher = nil, nil, nil
+	a.mu.Unlock()
+
+	// Cerrar la conexión cierra también sus canales
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
 }
 
-// Publish publica un evento en RabbitMQ
+// Publish publica un evento en RabbitMQ y espera la confirmación del broker, como mucho
+// hasta que venza ctx. Los eventos críticos se publican con mandatory y fallan con
+// events.ErrEventUnroutable si ninguna cola los recibe, salvo en las reproducciones. Durante una caída falla de
+// inmediato con events.ErrBrokerUnavailable; los eventos de la bandeja de salida se
+// reintentan después.
 func (a *RabbitMQAdapter) Publish(ctx context.Context, topic string, event events.Event) error {
-	// Convertir el evento a JSON
-	body, err := json.Marshal(event)
+	publisher := a.currentPublisher()
+	if publisher == nil {
+		return events.ErrBrokerUnavailable
+	}
+
+	// Convertir el evento al formato configurado (events.Event o CloudEvents)
+	body, contentType, headers, err := a.codec.encode(event)
 	if err != nil {
-		return fmt.Errorf("error serializando evento: %w", err)
+		return err
 	}
 
 	// Publicar mensaje
-	err = a.channel.Publish(
-		a.exchangeName, // exchange
-		topic,          // routing key (topic)
-		false,          // mandatory
-		false,          // immediate
+	err = publisher.publish(
+		ctx,
+		a.exchangeName,                 // exchange
+		topic,                          // routing key (topic)
+		publishMandatory(topic, event), // mandatory
 		amqp.Publishing{
-			ContentType:  "application/json",
+			ContentType:  contentType,
 			Body:         body,
 			DeliveryMode: amqp.Persistent,
 			Timestamp:    time.Now(),
-			Headers: amqp.Table{
-				"event_type": event.Type,
-				"event_id":   event.ID,
-			},
+			Headers:      headers,
 		},
 	)
 	if err != nil {
@@ -102,27 +144,48 @@ func (a *RabbitMQAdapter) Publish(ctx context.Context, topic string, event event
 	return nil
 }
 
-// Subscribe suscribe a un topic de RabbitMQ
-func (a *RabbitMQAdapter) Subscribe(topic string, handler application.EventHandler) error {
-	// Crear una cola específica para este consumidor
-	queueName := fmt.Sprintf("%s-%s", a.queueName, topic)
+// Subscribe suscribe a un topic de RabbitMQ. La suscripción se recuerda para volver a
+// establecerla tras una reconexión; si ahora mismo no hay conexión, se establecerá entonces.
+// options.Prefetch limita los mensajes sin confirmar que el servidor entrega a la vez.
+func (a *RabbitMQAdapter) Subscribe(topic string, handler application.EventHandler, options application.SubscriptionOptions) error {
+	sub := rabbitSubscription{
+		topic: topic,
+		// Crear una cola específica para este consumidor
+		queueName: fmt.Sprintf("%s-%s", a.queueName, topic),
+		handler:   handler,
+		options:   options,
+	}
 
-	q, err := a.channel.QueueDeclare(
-		queueName, // nombre
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
-	if err != nil {
-		return fmt.Errorf("error declarando cola: %w", err)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.closed {
+		return events.ErrBrokerUnavailable
+	}
+
+	if a.channel != nil {
+		if err := a.startConsumer(a.conn, a.channel, sub); err != nil {
+			return err
+		}
+	}
+
+	a.subscriptions = append(a.subscriptions, sub)
+	log.Printf("Suscrito al topic: %s", topic)
+	return nil
+}
+
+// startConsumer declara las colas de la suscripción en el canal de control y lanza su
+// consumidor en un canal propio, para que el prefetch se aplique solo a esta suscripción.
+// Los mensajes se procesan en un workerPool; el consumidor termina cuando se cierra su canal.
+func (a *RabbitMQAdapter) startConsumer(conn *amqp.Connection, ch *amqp.Channel, sub rabbitSubscription) error {
+	if err := a.declareQueues(conn, ch, sub.queueName); err != nil {
+		return err
 	}
 
 	// Enlazar la cola al exchange con el routing key (topic)
-	err = a.channel.QueueBind(
-		q.Name,         // nombre de cola
-		topic,          // routing key
+	err := ch.QueueBind(
+		sub.queueName,  // nombre de cola
+		sub.topic,      // routing key
 		a.exchangeName, // exchange
 		false,
 		nil,
@@ -131,46 +194,369 @@ func (a *RabbitMQAdapter) Subscribe(topic string, handler application.EventHandl
 		return fmt.Errorf("error enlazando cola: %w", err)
 	}
 
+	consumerCh, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("error abriendo canal del consumidor: %w", err)
+	}
+
+	if sub.options.Prefetch > 0 {
+		if err := consumerCh.Qos(sub.options.Prefetch, 0, false); err != nil {
+			consumerCh.Close()
+			return fmt.Errorf("error configurando prefetch: %w", err)
+		}
+	}
+
 	// Consumir mensajes
-	msgs, err := a.channel.Consume(
-		q.Name, // cola
-		"",     // consumer
-		false,  // auto-ack (false para confirmar manualmente)
-		false,  // exclusive
-		false,  // no-local
-		false,  // no-wait
-		nil,    // args
+	msgs, err := consumerCh.Consume(
+		sub.queueName, // cola
+		"",            // consumer
+		false,         // auto-ack (false para confirmar manualmente)
+		false,         // exclusive
+		false,         // no-local
+		false,         // no-wait
+		nil,           // args
 	)
 	if err != nil {
+		consumerCh.Close()
 		return fmt.Errorf("error registrando consumidor: %w", err)
 	}
 
 	// Procesar mensajes en una goroutine
 	go func() {
+		pool := newWorkerPool(sub.options.Workers, sub.options.OrderedByDevice)
+
 		for d := range msgs {
-			var event events.Event
-			err := json.Unmarshal(d.Body, &event)
+			// Se aceptan tanto events.Event como CloudEvents (estructurado o binario)
+			event, err := a.codec.decode(d)
 			if err != nil {
+				// Un mensaje ilegible nunca podrá procesarse: se aparta directamente
 				log.Printf("Error deserializando evento: %v", err)
-				d.Nack(false, true) // Rechazar y volver a la cola
+				a.handleFailure(sub.queueName, d, fmt.Errorf("%w: %v", errUndecodableEvent, err))
 				continue
 			}
 
-			// Procesar el evento
-			err = handler.Handle(context.Background(), event)
+			pool.submit(event, func() {
+				a.process(sub, d, event)
+			})
+		}
+
+		pool.stop()
+
+		// Si solo se cerró el canal del consumidor, se fuerza la reconexión para recuperarlo
+		if !a.isClosed() && !conn.IsClosed() {
+			log.Printf("Canal del consumidor de %s cerrado, reconectando", sub.topic)
+			conn.Close()
+		}
+	}()
+
+	return nil
+}
+
+// process entrega el evento al manejador y lo confirma o, si falla, lo reprograma
+func (a *RabbitMQAdapter) process(sub rabbitSubscription, d amqp.Delivery, event events.Event) {
+	if err := sub.handler.Handle(context.Background(), event); err != nil {
+		log.Printf("Error procesando evento: %v", err)
+		a.handleFailure(sub.queueName, d, err)
+		return
+	}
+
+	d.Ack(false) // Confirmar procesamiento
+	log.Printf("Evento procesado: %s - %s", sub.topic, event.ID)
+}
+
+// declareQueues declara la cola principal, su cola de mensajes muertos y las colas de
+// espera de los reintentos
+func (a *RabbitMQAdapter) declareQueues(conn *amqp.Connection, ch *amqp.Channel, queueName string) error {
+	dlx := deadLetterExchange(a.exchangeName)
+
+	_, err := ch.QueueDeclare(queueName+".dlq", true, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("error declarando cola de mensajes muertos: %w", err)
+	}
+	if err := ch.QueueBind(queueName+".dlq", queueName, dlx, false, nil); err != nil {
+		return fmt.Errorf("error enlazando cola de mensajes muertos: %w", err)
+	}
+
+	// La cola principal no lleva argumentos: los mensajes se apartan publicándolos en el
+	// DLX (ver handleFailure). Una cola existente se usa tal cual, con los argumentos con
+	// los que la declarase una versión anterior.
+	if err := declareQueueIfMissing(conn, ch, queueName); err != nil {
+		return fmt.Errorf("error declarando cola %s: %w", queueName, err)
+	}
+
+	// Una cola de espera por intento; al expirar el TTL el mensaje vuelve a la cola principal
+	for attempt := 1; attempt < a.retry.maxAttempts; attempt++ {
+		delay := a.retry.delay(attempt)
+		_, err := ch.QueueDeclare(
+			retryQueueName(queueName, attempt, delay),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl":             int64(delay / time.Millisecond),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("error declarando cola de reintentos: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// declareQueueIfMissing declara una cola duradera sin argumentos si aún no existe. La
+// comprobación se hace en un canal aparte, porque RabbitMQ cierra el canal si no la encuentra.
+func declareQueueIfMissing(conn *amqp.Connection, ch *amqp.Channel, queueName string) error {
+	probe, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	_, err = probe.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err == nil {
+		return probe.Close()
+	}
+
+	var amqpErr *amqp.Error
+	if !errors.As(err, &amqpErr) || amqpErr.Code != amqp.NotFound {
+		return err
+	}
+
+	_, err = ch.QueueDeclare(queueName, true, false, false, false, nil)
+	return err
+}
+
+// handleFailure programa un reintento del mensaje o, si se agotaron los intentos, lo
+// aparta en la cola de mensajes muertos con el motivo del último fallo
+func (a *RabbitMQAdapter) handleFailure(queueName string, d amqp.Delivery, cause error) {
+	attempts := headerInt(d.Headers, headerRetryCount) + 1
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerRetryCount] = int32(attempts)
+	headers[headerLastError] = cause.Error()
+	if _, ok := headers[headerOriginalTopic]; !ok {
+		headers[headerOriginalTopic] = d.RoutingKey
+	}
+
+	exchange, routingKey := "", retryQueueName(queueName, attempts, a.retry.delay(attempts))
+	if a.retry.exhausted(attempts, cause) {
+		exchange, routingKey = deadLetterExchange(a.exchangeName), queueName
+		headers[headerDeadAt] = time.Now()
+		log.Printf("Evento apartado en %s.dlq tras %d intentos", queueName, attempts)
+	}
+
+	publisher := a.currentPublisher()
+	if publisher == nil {
+		// Sin conexión el mensaje no se puede reprogramar; RabbitMQ lo reentregará
+		d.Nack(false, true)
+		return
+	}
+
+	// El original solo se confirma cuando la copia está a salvo en su nueva cola
+	err := publisher.publish(context.Background(), exchange, routingKey, true, amqp.Publishing{
+		ContentType:  d.ContentType,
+		Body:         d.Body,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    d.Timestamp,
+		Headers:      headers,
+	})
+	if err != nil {
+		// Sin poder reprogramarlo, se devuelve a la cola para no perderlo
+		log.Printf("Error reprogramando evento: %v", err)
+		d.Nack(false, true)
+		return
+	}
+
+	d.Ack(false)
+}
+
+// ListDeadLetters implementa application.DeadLetterStore. Los mensajes se leen sin
+// confirmarlos y vuelven a su cola al cerrar el canal.
+func (a *RabbitMQAdapter) ListDeadLetters(ctx context.Context, limit int) ([]events.DeadLetter, error) {
+	conn := a.currentConnection()
+	if conn == nil {
+		return nil, events.ErrBrokerUnavailable
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("error abriendo canal: %w", err)
+	}
+	defer ch.Close()
+
+	letters := []events.DeadLetter{}
+	for _, queueName := range a.subscribedQueues() {
+		for len(letters) < limit {
+			d, ok, err := ch.Get(queueName+".dlq", false)
 			if err != nil {
-				log.Printf("Error procesando evento: %v", err)
-				d.Nack(false, true) // Rechazar y volver a la cola
+				return nil, fmt.Errorf("error leyendo cola de mensajes muertos: %w", err)
+			}
+			if !ok {
+				break
+			}
+			letters = append(letters, deadLetterFromDelivery(queueName, d))
+		}
+	}
+
+	return letters, nil
+}
+
+// RequeueDeadLetters implementa application.DeadLetterStore: vuelve a publicar en su topic
+// original los mensajes muertos indicados (todos si la lista está vacía), con los intentos a cero
+func (a *RabbitMQAdapter) RequeueDeadLetters(ctx context.Context, eventIDs []string) (int, error) {
+	conn := a.currentConnection()
+	if conn == nil {
+		return 0, events.ErrBrokerUnavailable
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("error abriendo canal: %w", err)
+	}
+	// Los mensajes no confirmados (los que no se reencolan) vuelven a la cola al cerrar
+	defer ch.Close()
+
+	publisher, err := newConfirmPublisher(ch)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := eventIDSet(eventIDs)
+	requeued := 0
+	for _, queueName := range a.subscribedQueues() {
+		// Recorrer como mucho los mensajes presentes al empezar
+		q, err := ch.QueueInspect(queueName + ".dlq")
+		if err != nil {
+			return requeued, fmt.Errorf("error consultando cola de mensajes muertos: %w", err)
+		}
+
+		for i := 0; i < q.Messages; i++ {
+			if err := ctx.Err(); err != nil {
+				return requeued, err
+			}
+
+			d, ok, err := ch.Get(queueName+".dlq", false)
+			if err != nil {
+				return requeued, fmt.Errorf("error leyendo cola de mensajes muertos: %w", err)
+			}
+			if !ok {
+				break
+			}
+
+			letter := deadLetterFromDelivery(queueName, d)
+			if !matchesEventID(ids, letter.EventID) {
 				continue
 			}
 
-			d.Ack(false) // Confirmar procesamiento
-			log.Printf("Evento procesado: %s - %s", topic, event.ID)
+			headers := amqp.Table{}
+			for k, v := range d.Headers {
+				if k != headerRetryCount && k != headerLastError && k != headerDeadAt && k != "x-death" {
+					headers[k] = v
+				}
+			}
+
+			// Publicar directamente en la cola de la suscripción, no en el topic, para no
+			// duplicar el evento en otras suscripciones que sí lo procesaron
+			err = publisher.publish(ctx, "", queueName, true, amqp.Publishing{
+				ContentType:  d.ContentType,
+				Body:         d.Body,
+				DeliveryMode: amqp.Persistent,
+				Timestamp:    d.Timestamp,
+				Headers:      headers,
+			})
+			if err != nil {
+				return requeued, fmt.Errorf("error reencolando evento: %w", err)
+			}
+			if err := d.Ack(false); err != nil {
+				return requeued, err
+			}
+			requeued++
 		}
-	}()
+	}
 
-	log.Printf("Suscrito al topic: %s", topic)
-	return nil
+	return requeued, nil
+}
+
+// subscribedQueues devuelve las colas de las suscripciones registradas
+func (a *RabbitMQAdapter) subscribedQueues() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	queues := make([]string, 0, len(a.subscriptions))
+	for _, sub := range a.subscriptions {
+		queues = append(queues, sub.queueName)
+	}
+	return queues
+}
+
+// deadLetterFromDelivery describe un mensaje leído de una cola de mensajes muertos
+func deadLetterFromDelivery(queueName string, d amqp.Delivery) events.DeadLetter {
+	letter := events.DeadLetter{
+		Queue:    queueName,
+		Attempts: headerInt(d.Headers, headerRetryCount),
+		Body:     deadLetterBody(d.Body),
+	}
+
+	letter.EventID, _ = d.Headers["event_id"].(string)
+	letter.EventType, _ = d.Headers["event_type"].(string)
+	letter.LastError, _ = d.Headers[headerLastError].(string)
+	letter.Topic, _ = d.Headers[headerOriginalTopic].(string)
+	if letter.Topic == "" {
+		letter.Topic = d.RoutingKey
+	}
+	if deadAt, ok := d.Headers[headerDeadAt].(time.Time); ok {
+		letter.DeadAt = deadAt
+	} else {
+		letter.DeadAt = d.Timestamp
+	}
+
+	// Mensajes rechazados por ilegibles: no pasaron por handleFailure
+	if letter.LastError == "" {
+		letter.LastError = "mensaje rechazado por el consumidor"
+	}
+	if letter.EventID == "" {
+		letter.EventID = d.MessageId
+	}
+
+	return letter
+}
+
+// headerInt lee una cabecera numérica
+func headerInt(headers amqp.Table, key string) int {
+	return intValue(headers[key])
+}
+
+// intValue convierte un valor numérico de una cabecera; los clientes AMQP pueden
+// codificarlo con distintos tamaños
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int8:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// deadLetterExchange devuelve el nombre del exchange de mensajes muertos
+func deadLetterExchange(exchangeName string) string {
+	return exchangeName + ".dlx"
+}
+
+// retryQueueName devuelve la cola de espera previa al reintento número attempt, que
+// retiene los mensajes durante delay
+func retryQueueName(queueName string, attempt int, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%d.%d", queueName, attempt, delay.Milliseconds())
 }
 
 // EventDispatcherAdapter implementa application.EventDispatcher
@@ -185,14 +571,10 @@ func NewEventDispatcherAdapter(broker application.EventBroker) *EventDispatcherA
 	}
 }
 
-// Dispatch envía un evento al broker
-func (d *EventDispatcherAdapter) Dispatch(ctx context.Context, eventType string, topic string, data map[string]interface{}) error {
-	event := events.Event{
-		ID:        uuid.New().String(),
-		Type:      eventType,
-		Data:      data,
-		Timestamp: time.Now(),
-	}
+// Dispatch envía un evento al broker. El error incluye el resultado de la confirmación
+// (events.ErrEventNotConfirmed, events.ErrEventUnroutable o events.ErrBrokerUnavailable).
+func (d *EventDispatcherAdapter) Dispatch(ctx context.Context, topic string, payload events.Payload) error {
+	event := events.NewEvent(uuid.New().String(), payload, time.Now())
 
 	return d.broker.Publish(ctx, topic, event)
 }
@@ -0,0 +1,187 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Espera entre intentos de reconexión con RabbitMQ
+const (
+	reconnectInitialDelay = time.Second
+	reconnectMaxDelay     = 30 * time.Second
+)
+
+// connect abre una conexión con un canal de control y otro, en modo
+// confirmación, para publicar, y declara los exchanges
+func (a *RabbitMQAdapter) connect() (*amqp.Connection, *amqp.Channel, *confirmPublisher, error) {
+	// Conectar a RabbitMQ
+	conn, err := amqp.Dial(a.url)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error conectando a RabbitMQ: %w", err)
+	}
+
+	// Abrir un canal
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("error abriendo canal: %w", err)
+	}
+
+	// Declarar exchange
+	err = ch.ExchangeDeclare(
+		a.exchangeName, // nombre
+		"topic",        // tipo
+		true,           // durable
+		false,          // auto-delete
+		false,          // internal
+		false,          // no-wait
+		nil,            // argumentos
+	)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("error declarando exchange: %w", err)
+	}
+
+	// Declarar el exchange de mensajes muertos
+	err = ch.ExchangeDeclare(deadLetterExchange(a.exchangeName), "direct", true, false, false, false, nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("error declarando exchange de mensajes muertos: %w", err)
+	}
+
+	pubCh, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, fmt.Errorf("error abriendo canal de publicación: %w", err)
+	}
+	publisher, err := newConfirmPublisher(pubCh)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	return conn, ch, publisher, nil
+}
+
+// connectInBackground establece la primera conexión, reintentándola como tras una caída,
+// y después la supervisa
+func (a *RabbitMQAdapter) connectInBackground() {
+	conn, ch, publisher := a.reconnect()
+	if conn == nil {
+		return
+	}
+	a.supervise(conn, ch, publisher)
+}
+
+// supervise espera a que se cierre la conexión o alguno de sus canales y los restablece,
+// hasta que se cierra el adaptador
+func (a *RabbitMQAdapter) supervise(conn *amqp.Connection, ch *amqp.Channel, publisher *confirmPublisher) {
+	for {
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+		pubClosed := publisher.ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-a.done:
+			return
+		case err := <-connClosed:
+			if a.isClosed() {
+				return
+			}
+			log.Printf("Conexión con RabbitMQ perdida: %v", err)
+		case err := <-chClosed:
+			if a.isClosed() {
+				return
+			}
+			// Un canal cerrado por el servidor no se puede reutilizar: se rehace todo
+			log.Printf("Canal de RabbitMQ cerrado: %v", err)
+			conn.Close()
+		case err := <-pubClosed:
+			if a.isClosed() {
+				return
+			}
+			log.Printf("Canal de publicación de RabbitMQ cerrado: %v", err)
+			conn.Close()
+		}
+
+		a.mu.Lock()
+		a.conn, a.channel, a.publisher = nil, nil, nil
+		a.mu.Unlock()
+
+		conn, ch, publisher = a.reconnect()
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// reconnect reintenta la conexión con espera creciente y restablece las suscripciones.
+// Devuelve nil si el adaptador se cierra antes de conseguirlo.
+func (a *RabbitMQAdapter) reconnect() (*amqp.Connection, *amqp.Channel, *confirmPublisher) {
+	delay := reconnectInitialDelay
+
+	for {
+		select {
+		case <-a.done:
+			return nil, nil, nil
+		case <-time.After(delay):
+		}
+
+		conn, ch, publisher, err := a.connect()
+		if err != nil {
+			log.Printf("Error reconectando a RabbitMQ (nuevo intento en %s): %v", delay, err)
+			delay *= 2
+			if delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+			continue
+		}
+
+		a.mu.Lock()
+		if a.closed {
+			a.mu.Unlock()
+			conn.Close()
+			return nil, nil, nil
+		}
+		a.conn, a.channel, a.publisher = conn, ch, publisher
+		for _, sub := range a.subscriptions {
+			// Si falla, el canal se habrá cerrado y el supervisor volverá a intentarlo
+			if err := a.startConsumer(conn, ch, sub); err != nil {
+				log.Printf("Error restableciendo la suscripción a %s: %v", sub.topic, err)
+			}
+		}
+		a.mu.Unlock()
+
+		log.Println("Conexión con RabbitMQ restablecida")
+		return conn, ch, publisher
+	}
+}
+
+// currentPublisher devuelve el publicador activo o nil si está desconectado
+func (a *RabbitMQAdapter) currentPublisher() *confirmPublisher {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.publisher
+}
+
+// currentConnection devuelve la conexión activa o nil si está desconectado
+func (a *RabbitMQAdapter) currentConnection() *amqp.Connection {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.conn
+}
+
+// Connected indica si hay conexión con RabbitMQ en este momento
+func (a *RabbitMQAdapter) Connected() bool {
+	return a.currentConnection() != nil
+}
+
+// isClosed indica si el adaptador se cerró a propósito
+func (a *RabbitMQAdapter) isClosed() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.closed
+}
@@ -0,0 +1,190 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// publishConfirmTimeout limita la espera de la confirmación cuando el contexto del
+// llamador no tiene plazo
+const publishConfirmTimeout = 5 * time.Second
+
+// mandatoryEventTypes son los eventos críticos que se publican con mandatory: si ninguna
+// cola los recibe, RabbitMQ los devuelve y la publicación falla en lugar de perderlos
+var mandatoryEventTypes = map[string]bool{
+	events.EventTypeSensorThresholdAlert: true,
+}
+
+// publishMandatory indica si un evento se publica con mandatory en el topic dado. Las
+// reproducciones (topics replay.*) nunca lo son: nadie tiene por qué escuchar el topic de
+// destino y una alerta sin ruta no debe interrumpir la reproducción.
+func publishMandatory(topic string, event events.Event) bool {
+	if strings.HasPrefix(topic, events.TopicReplayPrefix) {
+		return false
+	}
+	return mandatoryEventTypes[event.Type]
+}
+
+// confirmPublisher publica en un canal en modo confirmación y espera, para cada mensaje,
+// el ack o nack del broker y la posible devolución por no tener ruta
+type confirmPublisher struct {
+	ch *amqp.Channel
+
+	mu          sync.Mutex
+	nextTag     uint64
+	pending     map[uint64]*pendingPublish
+	byMessageID map[string]uint64
+	closed      bool
+}
+
+// pendingPublish es un mensaje publicado a la espera de confirmación
+type pendingPublish struct {
+	messageID string
+	returned  *amqp.Return
+	result    chan error
+}
+
+// newConfirmPublisher pone el canal en modo confirmación y empieza a escuchar las respuestas
+func newConfirmPublisher(ch *amqp.Channel) (*confirmPublisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("error activando confirmaciones: %w", err)
+	}
+
+	p := &confirmPublisher{
+		ch:          ch,
+		pending:     make(map[uint64]*pendingPublish),
+		byMessageID: make(map[string]uint64),
+	}
+
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 256))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 256))
+	go p.listen(confirms, returns)
+
+	return p, nil
+}
+
+// publish publica el mensaje y espera su confirmación, como mucho hasta que venza ctx
+func (p *confirmPublisher) publish(ctx context.Context, exchange, routingKey string, mandatory bool, msg amqp.Publishing) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, publishConfirmTimeout)
+		defer cancel()
+	}
+
+	// Cada publicación lleva su propio ID para asociarle una devolución
+	msg.MessageId = uuid.New().String()
+	wait := &pendingPublish{messageID: msg.MessageId, result: make(chan error, 1)}
+
+	// Las etiquetas de entrega se asignan en el orden de publicación, por eso se publica
+	// con el mutex tomado
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return events.ErrBrokerUnavailable
+	}
+	if err := p.ch.Publish(exchange, routingKey, mandatory, false, msg); err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.nextTag++
+	p.pending[p.nextTag] = wait
+	p.byMessageID[msg.MessageId] = p.nextTag
+	p.mu.Unlock()
+
+	select {
+	case err := <-wait.result:
+		return err
+	case <-ctx.Done():
+		// El mensaje pudo llegar igualmente; quien reintenta debe tolerar duplicados
+		return fmt.Errorf("%w: %v", events.ErrEventNotConfirmed, ctx.Err())
+	}
+}
+
+// listen resuelve las publicaciones pendientes hasta que se cierra el canal
+func (p *confirmPublisher) listen(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			p.markReturned(ret)
+		case confirm, ok := <-confirms:
+			if !ok {
+				p.failAll()
+				return
+			}
+			// RabbitMQ envía la devolución antes que el ack del mismo mensaje: procesar
+			// primero las devoluciones ya recibidas
+			p.drainReturns(returns)
+			p.resolve(confirm)
+		}
+	}
+}
+
+func (p *confirmPublisher) drainReturns(returns <-chan amqp.Return) {
+	for returns != nil {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+			p.markReturned(ret)
+		default:
+			return
+		}
+	}
+}
+
+func (p *confirmPublisher) markReturned(ret amqp.Return) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if tag, ok := p.byMessageID[ret.MessageId]; ok {
+		p.pending[tag].returned = &ret
+	}
+}
+
+func (p *confirmPublisher) resolve(confirm amqp.Confirmation) {
+	p.mu.Lock()
+	wait, ok := p.pending[confirm.DeliveryTag]
+	if ok {
+		delete(p.pending, confirm.DeliveryTag)
+		delete(p.byMessageID, wait.messageID)
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	switch {
+	case !confirm.Ack:
+		wait.result <- fmt.Errorf("%w: rechazado por el broker", events.ErrEventNotConfirmed)
+	case wait.returned != nil:
+		wait.result <- fmt.Errorf("%w: %s", events.ErrEventUnroutable, wait.returned.ReplyText)
+	default:
+		wait.result <- nil
+	}
+}
+
+// failAll resuelve como no disponibles las publicaciones pendientes al cerrarse el canal
+func (p *confirmPublisher) failAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for tag, wait := range p.pending {
+		wait.result <- events.ErrBrokerUnavailable
+		delete(p.pending, tag)
+	}
+	p.byMessageID = make(map[string]uint64)
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+)
+
+// routeUnbound emula un exchange sin colas enlazadas: RabbitMQ devuelve los mensajes
+// publicados con mandatory y descarta el resto
+func routeUnbound(topic string, event events.Event) error {
+	if publishMandatory(topic, event) {
+		return events.ErrEventUnroutable
+	}
+	return nil
+}
+
+func TestReplayOfMixedLogToUnboundTopicIsNotAborted(t *testing.T) {
+	now := time.Now()
+	logged := []events.Event{
+		events.NewEvent("evt-1", events.SensorDataCreated{ID: 1, DeviceID: 1}, now),
+		events.NewEvent("evt-2", events.SensorThresholdAlert{ID: 1, DeviceID: 1, SensorType: "temperatura"}, now),
+		events.NewEvent("evt-3", events.SensorDataCreated{ID: 2, DeviceID: 1}, now),
+		events.NewEvent("evt-4", events.SensorThresholdAlert{ID: 2, DeviceID: 1, SensorType: "humedad"}, now),
+	}
+
+	replayed := 0
+	for _, event := range logged {
+		if err := routeUnbound(events.TopicReplayPrefix+"auditoria", event); err != nil {
+			t.Fatalf("reproducción interrumpida en %s: %v", event.ID, err)
+		}
+		replayed++
+	}
+	if replayed != len(logged) {
+		t.Errorf("reproducidos = %d, se esperaba %d", replayed, len(logged))
+	}
+}
+
+func TestLiveAlertsArePublishedAsMandatory(t *testing.T) {
+	alert := events.NewEvent("evt-1", events.SensorThresholdAlert{ID: 1}, time.Now())
+	if !publishMandatory(events.TopicSensorAlerts, alert) {
+		t.Error("las alertas en vivo deben publicarse con mandatory")
+	}
+
+	data := events.NewEvent("evt-2", events.SensorDataCreated{ID: 1}, time.Now())
+	if publishMandatory(events.TopicSensorData, data) {
+		t.Error("los datos de sensores no deben publicarse con mandatory")
+	}
+}
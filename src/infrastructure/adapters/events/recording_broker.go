@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+)
+
+// RecordingBroker envuelve un application.EventBroker y registra en el almacén de eventos
+// todo lo que se publica antes de entregarlo al broker
+type RecordingBroker struct {
+	application.EventBroker
+	store application.EventStore
+}
+
+// NewRecordingBroker crea una nueva instancia de RecordingBroker
+func NewRecordingBroker(broker application.EventBroker, store application.EventStore) *RecordingBroker {
+	return &RecordingBroker{
+		EventBroker: broker,
+		store:       store,
+	}
+}
+
+// Publish registra el evento y lo publica. Si no se puede registrar no se publica, para
+// que quien reintenta (el relay de la bandeja de salida) no deje huecos en el registro.
+func (b *RecordingBroker) Publish(ctx context.Context, topic string, event events.Event) error {
+	if err := b.store.Append(ctx, topic, event); err != nil {
+		return fmt.Errorf("error registrando evento: %w", err)
+	}
+
+	return b.EventBroker.Publish(ctx, topic, event)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/tipo_de_datos"
+)
+
+// errUndecodableEvent indica un mensaje que no se puede deserializar; nunca se reintenta
+var errUndecodableEvent = errors.New("evento ilegible")
+
+// retryPolicy decide si un evento fallido se reintenta y tras cuánta espera
+type retryPolicy struct {
+	maxAttempts  int
+	initialDelay time.Duration
+	maxDelay     time.Duration
+}
+
+// newRetryPolicy crea la política a partir de la configuración; al menos se hace un intento
+func newRetryPolicy(config tipo_de_datos.EventRetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxAttempts:  config.MaxAttempts,
+		initialDelay: time.Duration(config.InitialDelayMs) * time.Millisecond,
+		maxDelay:     time.Duration(config.MaxDelayMs) * time.Millisecond,
+	}
+	if policy.maxAttempts < 1 {
+		policy.maxAttempts = 1
+	}
+	if policy.maxDelay < policy.initialDelay {
+		policy.maxDelay = policy.initialDelay
+	}
+	return policy
+}
+
+// exhausted indica si, tras attempts intentos fallidos con el error err, el evento debe
+// apartarse en lugar de reintentarse
+func (p retryPolicy) exhausted(attempts int, err error) bool {
+	return attempts >= p.maxAttempts || errors.Is(err, events.ErrUnhandledEvent) || errors.Is(err, errUndecodableEvent)
+}
+
+// delay devuelve la espera antes del reintento que sigue al intento fallido número attempt
+func (p retryPolicy) delay(attempt int) time.Duration {
+	delay := p.initialDelay
+	for i := 1; i < attempt && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return delay
+}
+
+// deadLetterBody devuelve el cuerpo del mensaje como JSON; si no lo es, como cadena
+func deadLetterBody(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// matchesEventID indica si id está en ids; una lista vacía coincide con todos
+func matchesEventID(ids map[string]bool, id string) bool {
+	return len(ids) == 0 || ids[id]
+}
+
+// eventIDSet convierte la lista de IDs en un conjunto
+func eventIDSet(eventIDs []string) map[string]bool {
+	ids := make(map[string]bool, len(eventIDs))
+	for _, id := range eventIDs {
+		ids[id] = true
+	}
+	return ids
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"sync"
+
+	"ApiSmart/src/core/domain/events"
+)
+
+// workerPool reparte el procesamiento de los eventos de una suscripción entre varias
+// goroutines. Con ordered, cada dispositivo se asigna siempre al mismo worker, de modo
+// que sus eventos se procesan en el orden de llegada.
+type workerPool struct {
+	lanes   []chan func()
+	ordered bool
+	wg      sync.WaitGroup
+}
+
+// newWorkerPool arranca workers goroutines (al menos una)
+func newWorkerPool(workers int, ordered bool) *workerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &workerPool{ordered: ordered}
+	if ordered {
+		// Un canal por worker
+		for i := 0; i < workers; i++ {
+			lane := make(chan func())
+			p.lanes = append(p.lanes, lane)
+			p.start(lane)
+		}
+	} else {
+		// Un canal compartido por todos los workers
+		lane := make(chan func())
+		p.lanes = []chan func(){lane}
+		for i := 0; i < workers; i++ {
+			p.start(lane)
+		}
+	}
+
+	return p
+}
+
+func (p *workerPool) start(lane chan func()) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for task := range lane {
+			task()
+		}
+	}()
+}
+
+// submit entrega la tarea a un worker, esperando a que haya uno libre
+func (p *workerPool) submit(event events.Event, task func()) {
+	lane := 0
+	if p.ordered {
+		lane = int(deviceKey(event) % uint(len(p.lanes)))
+	}
+	p.lanes[lane] <- task
+}
+
+// stop espera a que terminen las tareas en curso y detiene los workers
+func (p *workerPool) stop() {
+	for _, lane := range p.lanes {
+		close(lane)
+	}
+	p.wg.Wait()
+}
+
+// deviceKey devuelve el dispositivo del evento; los eventos sin dispositivo comparten la clave 0
+func deviceKey(event events.Event) uint {
+	if device, ok := event.Data.(events.DevicePayload); ok {
+		return device.EventDeviceID()
+	}
+	return 0
+}
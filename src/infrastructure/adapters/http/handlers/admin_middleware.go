@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware solo deja pasar a los usuarios de adminUserIDs. Debe ir detrás de
+// AuthMiddleware, que guarda el userID en el contexto.
+func AdminMiddleware(adminUserIDs []uint) gin.HandlerFunc {
+	admins := make(map[uint]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		if !admins[c.GetUint("userID")] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "se requieren permisos de administrador"})
+			return
+		}
+
+		c.Next()
+	}
+}
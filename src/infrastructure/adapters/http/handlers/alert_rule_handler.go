@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertRuleHandler maneja las solicitudes HTTP relacionadas con las reglas de alerta
+type AlertRuleHandler struct {
+	alertRuleUseCase *use_case.AlertRuleUseCase
+}
+
+// NewAlertRuleHandler crea una nueva instancia de AlertRuleHandler
+func NewAlertRuleHandler(alertRuleUseCase *use_case.AlertRuleUseCase) *AlertRuleHandler {
+	return &AlertRuleHandler{
+		alertRuleUseCase: alertRuleUseCase,
+	}
+}
+
+// CreateRule crea una regla de alerta
+func (h *AlertRuleHandler) CreateRule(c *gin.Context) {
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.alertRuleUseCase.CreateRule(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(alertRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// GetRules obtiene todas las reglas de alerta
+func (h *AlertRuleHandler) GetRules(c *gin.Context) {
+	rules, err := h.alertRuleUseCase.GetRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetRule obtiene una regla de alerta por su ID
+func (h *AlertRuleHandler) GetRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de regla inválido"})
+		return
+	}
+
+	rule, err := h.alertRuleUseCase.GetRule(c.Request.Context(), uint(ruleID))
+	if err != nil {
+		c.JSON(alertRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// UpdateRule reemplaza una regla de alerta
+func (h *AlertRuleHandler) UpdateRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de regla inválido"})
+		return
+	}
+
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.alertRuleUseCase.UpdateRule(c.Request.Context(), uint(ruleID), req)
+	if err != nil {
+		c.JSON(alertRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule elimina una regla de alerta
+func (h *AlertRuleHandler) DeleteRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de regla inválido"})
+		return
+	}
+
+	if err := h.alertRuleUseCase.DeleteRule(c.Request.Context(), uint(ruleID)); err != nil {
+		c.JSON(alertRuleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Regla de alerta eliminada correctamente"})
+}
+
+// alertRuleErrorStatus traduce los errores de dominio de reglas de alerta a códigos HTTP
+func alertRuleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidAlertRule):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrAlertRuleNotFound):
+		return http.StatusNotFound
+	default:
+		return deviceErrorStatus(err)
+	}
+}
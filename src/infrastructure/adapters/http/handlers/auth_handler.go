@@ -64,17 +64,30 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extraer el token del encabezado
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "formato de token inválido"})
+		h.authenticate(c, authHeader)
+	}
+}
+
+// StreamAuthMiddleware valida la cabecera Authorization igual que AuthMiddleware o, en su
+// defecto, un ticket de streaming en ?ticket=, ya que EventSource y WebSocket del navegador
+// no permiten enviar cabeceras. El ticket caduca en segundos y solo sirve una vez, así que
+// no expone el token de sesión en la URL.
+func (h *AuthHandler) StreamAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+			h.authenticate(c, authHeader)
+			return
+		}
+
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token de autenticación no proporcionado"})
 			return
 		}
 
-		token := parts[1]
-		userID, err := h.authUseCase.ValidateToken(token)
+		userID, err := h.authUseCase.RedeemStreamTicket(ticket)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token inválido o expirado"})
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
 
@@ -82,3 +95,34 @@ func (h *AuthHandler) AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// IssueStreamTicket emite un ticket de streaming para el usuario autenticado
+func (h *AuthHandler) IssueStreamTicket(c *gin.Context) {
+	ticket, err := h.authUseCase.IssueStreamTicket(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ticket)
+}
+
+// authenticate valida el encabezado "Bearer <token>" y guarda el userID en el contexto
+func (h *AuthHandler) authenticate(c *gin.Context, authHeader string) {
+	// Extraer el token del encabezado
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "formato de token inválido"})
+		return
+	}
+
+	token := parts[1]
+	userID, err := h.authUseCase.ValidateToken(token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token inválido o expirado"})
+		return
+	}
+
+	c.Set("userID", userID)
+	c.Next()
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxDeviceBodyBytes limita el cuerpo que se lee para verificar firmas
+const maxDeviceBodyBytes = 1 << 20
+
+// DeviceHandler maneja las solicitudes HTTP relacionadas con dispositivos
+type DeviceHandler struct {
+	deviceUseCase *use_case.DeviceUseCase
+}
+
+// NewDeviceHandler crea una nueva instancia de DeviceHandler
+func NewDeviceHandler(deviceUseCase *use_case.DeviceUseCase) *DeviceHandler {
+	return &DeviceHandler{
+		deviceUseCase: deviceUseCase,
+	}
+}
+
+// CreateDevice registra un nuevo dispositivo
+func (h *DeviceHandler) CreateDevice(c *gin.Context) {
+	var req models.CreateDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credentials, err := h.deviceUseCase.CreateDevice(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// GetAllDevices obtiene todos los dispositivos
+func (h *DeviceHandler) GetAllDevices(c *gin.Context) {
+	devices, err := h.deviceUseCase.GetAllDevices(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// GetDevice obtiene un dispositivo por su ID
+func (h *DeviceHandler) GetDevice(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	device, err := h.deviceUseCase.GetDevice(c.Request.Context(), uint(deviceID))
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// UpdateDevice actualiza un dispositivo
+func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	var req models.UpdateDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := h.deviceUseCase.UpdateDevice(c.Request.Context(), uint(deviceID), req)
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// DeleteDevice elimina un dispositivo
+func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	if err := h.deviceUseCase.DeleteDevice(c.Request.Context(), uint(deviceID)); err != nil {
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dispositivo eliminado correctamente"})
+}
+
+// RotateDeviceKey emite una nueva clave para el dispositivo
+func (h *DeviceHandler) RotateDeviceKey(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	credentials, err := h.deviceUseCase.RotateDeviceKey(c.Request.Context(), uint(deviceID))
+	if err != nil {
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+// RevokeDeviceKey revoca la clave del dispositivo
+func (h *DeviceHandler) RevokeDeviceKey(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	if err := h.deviceUseCase.RevokeDeviceKey(c.Request.Context(), uint(deviceID)); err != nil {
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Clave del dispositivo revocada"})
+}
+
+// DeviceAuthMiddleware middleware para autenticar dispositivos IoT.
+// Requiere la cabecera X-Device-ID y, o bien X-Device-Key con la clave del dispositivo,
+// o bien X-Timestamp y X-Signature con el HMAC-SHA256 hexadecimal de "<timestamp>.<body>".
+func (h *DeviceHandler) DeviceAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deviceID, err := strconv.ParseUint(c.GetHeader("X-Device-ID"), 10, 32)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "cabecera X-Device-ID no proporcionada o inválida"})
+			return
+		}
+
+		ctx := c.Request.Context()
+		apiKey := c.GetHeader("X-Device-Key")
+		signature := c.GetHeader("X-Signature")
+		timestamp := c.GetHeader("X-Timestamp")
+
+		switch {
+		case apiKey != "":
+			err = h.deviceUseCase.AuthenticateKey(ctx, uint(deviceID), apiKey)
+		case signature != "" && timestamp != "":
+			var body []byte
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxDeviceBodyBytes))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "no se pudo leer el cuerpo de la solicitud"})
+				return
+			}
+			// Restaurar el cuerpo para el handler siguiente
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			err = h.deviceUseCase.VerifySignature(ctx, uint(deviceID), timestamp, body, signature)
+		default:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "credenciales de dispositivo no proporcionadas"})
+			return
+		}
+
+		if err != nil {
+			c.AbortWithStatusJSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set("deviceID", uint(deviceID))
+		c.Next()
+	}
+}
+
+// deviceErrorStatus traduce los errores de dominio de dispositivos a códigos HTTP
+func deviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrDeviceIDRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrDeviceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInvalidDeviceCredentials),
+		errors.Is(err, models.ErrSignatureExpired),
+		errors.Is(err, models.ErrSignatureReplayed):
+		return http.StatusUnauthorized
+	case errors.Is(err, models.ErrDeviceIDMismatch):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// EventAdminHandler maneja las solicitudes HTTP de administración del sistema de eventos
+type EventAdminHandler struct {
+	eventAdminUseCase *use_case.EventAdminUseCase
+}
+
+// NewEventAdminHandler crea una nueva instancia de EventAdminHandler
+func NewEventAdminHandler(eventAdminUseCase *use_case.EventAdminUseCase) *EventAdminHandler {
+	return &EventAdminHandler{
+		eventAdminUseCase: eventAdminUseCase,
+	}
+}
+
+// ListDeadLetters lista los eventos apartados en las colas de mensajes muertos (?limit=)
+func (h *EventAdminHandler) ListDeadLetters(c *gin.Context) {
+	limit, err := optionalIntQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	letters, err := h.eventAdminUseCase.ListDeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, letters)
+}
+
+// RequeueDeadLetters vuelve a entregar los eventos apartados indicados en event_ids, o
+// todos si la lista está vacía
+func (h *EventAdminHandler) RequeueDeadLetters(c *gin.Context) {
+	var req models.RequeueDeadLettersRequest
+	// Un cuerpo vacío equivale a reencolar todos
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	requeued, err := h.eventAdminUseCase.RequeueDeadLetters(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "requeued": requeued})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"requeued": requeued})
+}
+
+// ReplayEvents inicia en segundo plano la reproducción en un topic replay.* de los eventos
+// registrados de un rango de fechas y de los tipos indicados
+func (h *EventAdminHandler) ReplayEvents(c *gin.Context) {
+	var req models.ReplayEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.eventAdminUseCase.StartReplay(c.Request.Context(), req); err != nil {
+		c.JSON(replayErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Reproducción iniciada", "topic": req.Topic})
+}
+
+// replayErrorStatus traduce los errores de la reproducción de eventos a códigos HTTP
+func replayErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrReplayTopicRequired),
+		errors.Is(err, models.ErrReplayTopicNotAllowed),
+		errors.Is(err, models.ErrInvalidTimeRange):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrReplayInProgress):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
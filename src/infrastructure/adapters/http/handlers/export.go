@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// exportFlushEvery indica cada cuántas filas se vacían los buffers hacia el cliente
+const exportFlushEvery = 500
+
+// Formatos de exportación admitidos
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+)
+
+// exportWriter escribe filas en CSV o NDJSON directamente en la respuesta, comprimidas con
+// gzip si el cliente lo acepta. Las cabeceras se envían con la primera fila, de modo que un
+// error previo todavía puede responderse como JSON.
+type exportWriter struct {
+	c        *gin.Context
+	format   string
+	filename string
+	columns  []string
+
+	started bool
+	rows    int
+	gz      *gzip.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+// newExportWriter valida el formato solicitado y prepara el escritor
+func newExportWriter(c *gin.Context, name string, columns []string) (*exportWriter, error) {
+	format := c.DefaultQuery("format", exportFormatCSV)
+	if format != exportFormatCSV && format != exportFormatNDJSON {
+		return nil, fmt.Errorf("formato de exportación inválido: %s (csv o ndjson)", format)
+	}
+
+	return &exportWriter{
+		c:        c,
+		format:   format,
+		filename: fmt.Sprintf("%s_%s.%s", name, time.Now().Format("20060102_150405"), format),
+		columns:  columns,
+	}, nil
+}
+
+// start envía las cabeceras HTTP y, en CSV, la fila de encabezados
+func (w *exportWriter) start() error {
+	w.started = true
+
+	header := w.c.Writer.Header()
+	if w.format == exportFormatCSV {
+		header.Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.filename))
+	header.Set("Vary", "Accept-Encoding")
+
+	var out io.Writer = w.c.Writer
+	if strings.Contains(w.c.GetHeader("Accept-Encoding"), "gzip") {
+		header.Set("Content-Encoding", "gzip")
+		w.gz = gzip.NewWriter(w.c.Writer)
+		out = w.gz
+	}
+
+	w.c.Status(http.StatusOK)
+
+	if w.format == exportFormatCSV {
+		w.csv = csv.NewWriter(out)
+		return w.csv.Write(w.columns)
+	}
+
+	w.json = json.NewEncoder(out)
+	return nil
+}
+
+// Write escribe una fila: record en CSV u obj en NDJSON
+func (w *exportWriter) Write(record []string, obj interface{}) error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if w.csv != nil {
+		err = w.csv.Write(record)
+	} else {
+		err = w.json.Encode(obj)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.rows++
+	if w.rows%exportFlushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// flush vacía los buffers intermedios hasta el cliente
+func (w *exportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+// Close termina la exportación; si no hubo filas envía igualmente las cabeceras
+func (w *exportWriter) Close() error {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
+
+// finish cierra la exportación o informa del error. Si la respuesta ya había empezado
+// solo puede interrumpirse, porque el código de estado ya fue enviado.
+func (w *exportWriter) finish(err error) {
+	if err == nil {
+		err = w.Close()
+		if err == nil {
+			return
+		}
+	}
+
+	if !w.started {
+		w.c.JSON(queryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	_ = w.c.Error(err)
+	w.c.Abort()
+}
+
+var sensorDataExportColumns = []string{"id", "device_id", "temperaturaDHT", "luz", "humedad", "humo", "created_at"}
+
+// sensorDataRecord convierte una lectura en una fila CSV
+func sensorDataRecord(data models.SensorData) []string {
+	return []string{
+		strconv.FormatUint(uint64(data.ID), 10),
+		strconv.FormatUint(uint64(data.DeviceID), 10),
+		strconv.FormatFloat(data.TemperaturaDHT, 'f', -1, 64),
+		strconv.FormatFloat(data.Luz, 'f', -1, 64),
+		strconv.FormatFloat(data.Humedad, 'f', -1, 64),
+		strconv.FormatFloat(data.Humo, 'f', -1, 64),
+		data.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+var alertExportColumns = []string{"id", "sensor_id", "sensor_type", "value", "message", "is_read", "created_at"}
+
+// alertRecord convierte una alerta en una fila CSV
+func alertRecord(alert models.Alert) []string {
+	return []string{
+		strconv.FormatUint(uint64(alert.ID), 10),
+		strconv.FormatUint(uint64(alert.SensorID), 10),
+		alert.SensorType,
+		strconv.FormatFloat(alert.Value, 'f', -1, 64),
+		alert.Message,
+		strconv.FormatBool(alert.IsRead),
+		alert.CreatedAt.Format(time.RFC3339),
+	}
+}
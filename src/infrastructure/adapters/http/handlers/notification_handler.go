@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler maneja las solicitudes HTTP relacionadas con las notificaciones del usuario autenticado
+type NotificationHandler struct {
+	notificationUseCase *use_case.NotificationUseCase
+}
+
+// NewNotificationHandler crea una nueva instancia de NotificationHandler
+func NewNotificationHandler(notificationUseCase *use_case.NotificationUseCase) *NotificationHandler {
+	return &NotificationHandler{
+		notificationUseCase: notificationUseCase,
+	}
+}
+
+// GetPreferences obtiene las preferencias de notificación del usuario
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.notificationUseCase.GetPreferences(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences activa o desactiva los correos de alertas del usuario
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := h.notificationUseCase.UpdatePreferences(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// GetInbox obtiene la bandeja de notificaciones del usuario (?unread=true para ver solo las
+// no leídas, ?limit= para limitar el número de alertas)
+func (h *NotificationHandler) GetInbox(c *gin.Context) {
+	var unreadOnly bool
+	if unreadParam := c.Query("unread"); unreadParam != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(unreadParam); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "el parámetro unread debe ser booleano"})
+			return
+		}
+	}
+
+	limit := models.DefaultInboxLimit
+	if limitParam := c.Query("limit"); limitParam != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "el parámetro limit debe ser un entero positivo"})
+			return
+		}
+	}
+
+	inbox, err := h.notificationUseCase.GetInbox(c.Request.Context(), c.GetUint("userID"), unreadOnly, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, inbox)
+}
+
+// MarkRead marca como leídas para el usuario las alertas indicadas
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	var req models.MarkAlertsReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	marked, err := h.notificationUseCase.MarkRead(c.Request.Context(), c.GetUint("userID"), req.AlertIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// MarkAllRead marca como leídas para el usuario todas sus alertas
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	marked, err := h.notificationUseCase.MarkAllRead(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// optionalUintQuery lee un parámetro de consulta numérico opcional; devuelve nil si no está presente
+func optionalUintQuery(c *gin.Context, name string) (*uint, error) {
+	param := c.Query(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseUint(param, 10, 32)
+	if err != nil || parsed == 0 {
+		return nil, strconv.ErrSyntax
+	}
+
+	value := uint(parsed)
+	return &value, nil
+}
+
+// optionalTimeQuery lee un parámetro de fecha opcional en formato RFC 3339 o AAAA-MM-DD
+func optionalTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	param := c.Query(name)
+	if param == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, param); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", param, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("el parámetro %s debe tener formato RFC 3339 o AAAA-MM-DD", name)
+	}
+	return &t, nil
+}
+
+// optionalIntQuery lee un parámetro entero opcional; devuelve 0 si no está presente
+func optionalIntQuery(c *gin.Context, name string) (int, error) {
+	param := c.Query(name)
+	if param == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(param)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("el parámetro %s debe ser un entero positivo", name)
+	}
+	return value, nil
+}
+
+// listQuery lee un parámetro con una lista separada por comas, descartando elementos vacíos
+func listQuery(c *gin.Context, name string) []string {
+	var items []string
+	for _, item := range strings.Split(c.Query(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
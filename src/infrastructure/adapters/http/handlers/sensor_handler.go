@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"ApiSmart/src/core/application/use_case"
 	"ApiSmart/src/core/domain/models"
@@ -29,9 +31,18 @@ func (h *SensorHandler) CreateSensorData(c *gin.Context) {
 		return
 	}
 
+	// El dispositivo autenticado determina el origen de la lectura
+	if deviceID, ok := c.Get("deviceID"); ok {
+		if data.DeviceID != 0 && data.DeviceID != deviceID.(uint) {
+			c.JSON(deviceErrorStatus(models.ErrDeviceIDMismatch), gin.H{"error": models.ErrDeviceIDMismatch.Error()})
+			return
+		}
+		data.DeviceID = deviceID.(uint)
+	}
+
 	// Guardar datos del sensor y generar alertas si es necesario
 	if err := h.sensorUseCase.SaveSensorData(c.Request.Context(), &data); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(deviceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -41,20 +52,34 @@ func (h *SensorHandler) CreateSensorData(c *gin.Context) {
 	})
 }
 
-// GetAllSensorData obtiene todos los datos de los sensores
+// GetAllSensorData obtiene el histórico de los sensores, filtrado por
+// ?device_id=&from=&to= y paginado con ?limit=&cursor=
 func (h *SensorHandler) GetAllSensorData(c *gin.Context) {
-	data, err := h.sensorUseCase.GetAllSensorData(c.Request.Context())
+	query, err := sensorDataQuery(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, data)
+	page, err := h.sensorUseCase.GetAllSensorData(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(queryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
 }
 
 // GetLatestSensorData obtiene los datos más recientes del sensor
 func (h *SensorHandler) GetLatestSensorData(c *gin.Context) {
-	data, err := h.sensorUseCase.GetLatestSensorData(c.Request.Context())
+	// Filtrar por dispositivo si se especifica
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	data, err := h.sensorUseCase.GetLatestSensorData(c.Request.Context(), deviceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -63,28 +88,181 @@ func (h *SensorHandler) GetLatestSensorData(c *gin.Context) {
 	c.JSON(http.StatusOK, data)
 }
 
-// GetAlerts obtiene las alertas de sensores
+// AggregateSensorData agrega el histórico en intervalos de tiempo, p. ej.
+// ?bucket=1h&from=&to=&metrics=temperaturaDHT,humedad&fn=avg,min,max&device_id=
+func (h *SensorHandler) AggregateSensorData(c *gin.Context) {
+	query, err := sensorAggregateQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.sensorUseCase.AggregateSensorData(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(queryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportSensorData exporta el histórico como ?format=csv|ndjson, filtrado por
+// ?device_id=&from=&to=, escribiendo las filas a medida que se leen de la base de datos
+func (h *SensorHandler) ExportSensorData(c *gin.Context) {
+	query, err := sensorDataQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, err := newExportWriter(c, "sensor_data", sensorDataExportColumns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w.finish(h.sensorUseCase.ExportSensorData(c.Request.Context(), query, func(data models.SensorData) error {
+		return w.Write(sensorDataRecord(data), data)
+	}))
+}
+
+// GetAlerts obtiene una página de alertas con el estado de lectura del usuario autenticado,
+// filtradas por ?is_read=&device_id=&sensor_type=&severity=&from=&to= y paginadas con ?limit=&cursor=
 func (h *SensorHandler) GetAlerts(c *gin.Context) {
-	// Filtrar alertas por estado (leídas/no leídas)
-	var isRead *bool
-	isReadParam := c.Query("is_read")
-	if isReadParam != "" {
-		isReadBool, err := strconv.ParseBool(isReadParam)
-		if err == nil {
-			isRead = &isReadBool
+	filter, err := alertFilterQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := models.AlertQuery{AlertFilter: filter}
+	if query.Limit, err = optionalIntQuery(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if query.Cursor, err = models.DecodeCursor(cursor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 	}
 
-	alerts, err := h.sensorUseCase.GetAlerts(c.Request.Context(), isRead)
+	page, err := h.sensorUseCase.GetAlerts(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(queryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// CountAlerts cuenta las alertas agrupadas por ?group_by=type,day (por defecto, ambos), con
+// los mismos filtros que GetAlerts
+func (h *SensorHandler) CountAlerts(c *gin.Context) {
+	filter, err := alertFilterQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := models.AlertCountQuery{AlertFilter: filter}
+	groups := listQuery(c, "group_by")
+	if len(groups) == 0 {
+		groups = []string{"type", "day"}
+	}
+	for _, group := range groups {
+		switch group {
+		case "type":
+			query.ByType = true
+		case "day":
+			query.ByDay = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "el parámetro group_by solo admite type y day"})
+			return
+		}
+	}
+
+	counts, err := h.sensorUseCase.CountAlerts(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(queryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, counts)
+}
+
+// UpdateAlerts marca como leídas o no leídas para el usuario autenticado las alertas
+// indicadas por ids o por filter
+func (h *SensorHandler) UpdateAlerts(c *gin.Context) {
+	var req models.AlertBulkUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.sensorUseCase.UpdateAlerts(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// DeleteAlerts elimina las alertas indicadas por ids o por un filter con algún criterio
+func (h *SensorHandler) DeleteAlerts(c *gin.Context) {
+	var req models.AlertBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deleted, err := h.sensorUseCase.DeleteAlerts(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// GetAlertStates obtiene el estado de las condiciones de alerta, opcionalmente de un dispositivo (?device_id=)
+func (h *SensorHandler) GetAlertStates(c *gin.Context) {
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	states, err := h.sensorUseCase.GetAlertStates(c.Request.Context(), deviceID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, alerts)
+	c.JSON(http.StatusOK, states)
+}
+
+// ExportAlerts exporta las alertas como ?format=csv|ndjson, con los mismos filtros que GetAlerts
+func (h *SensorHandler) ExportAlerts(c *gin.Context) {
+	filter, err := alertFilterQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w, err := newExportWriter(c, "alerts", alertExportColumns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	w.finish(h.sensorUseCase.ExportAlerts(c.Request.Context(), filter, func(alert models.Alert) error {
+		return w.Write(alertRecord(alert), alert)
+	}))
 }
 
-// MarkAlertAsRead marca una alerta como leída
+// MarkAlertAsRead marca una alerta como leída para el usuario autenticado
 func (h *SensorHandler) MarkAlertAsRead(c *gin.Context) {
 	alertID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -92,10 +270,172 @@ func (h *SensorHandler) MarkAlertAsRead(c *gin.Context) {
 		return
 	}
 
-	if err := h.sensorUseCase.MarkAlertAsRead(c.Request.Context(), uint(alertID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.sensorUseCase.MarkAlertAsRead(c.Request.Context(), c.GetUint("userID"), uint(alertID)); err != nil {
+		c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Alerta marcada como leída"})
 }
+
+// AcknowledgeAlert marca una alerta abierta como reconocida por el usuario autenticado
+func (h *SensorHandler) AcknowledgeAlert(c *gin.Context) {
+	alertID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de alerta inválido"})
+		return
+	}
+
+	alert, err := h.sensorUseCase.AcknowledgeAlert(c.Request.Context(), uint(alertID), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// ResolveAlert da por resuelta una alerta abierta o reconocida
+func (h *SensorHandler) ResolveAlert(c *gin.Context) {
+	alertID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de alerta inválido"})
+		return
+	}
+
+	alert, err := h.sensorUseCase.ResolveAlert(c.Request.Context(), uint(alertID), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// alertErrorStatus traduce los errores de dominio de alertas a códigos HTTP
+func alertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrAlertNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInvalidAlertTransition):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidAlertSelection),
+		errors.Is(err, models.ErrInvalidAlertFilter),
+		errors.Is(err, models.ErrInvalidTimeRange):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// queryErrorStatus traduce los errores de validación de consultas a códigos HTTP
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidTimeRange),
+		errors.Is(err, models.ErrInvalidCursor),
+		errors.Is(err, models.ErrTimeRangeRequired),
+		errors.Is(err, models.ErrInvalidBucket),
+		errors.Is(err, models.ErrInvalidMetric),
+		errors.Is(err, models.ErrInvalidAggregate),
+		errors.Is(err, models.ErrTooManyBuckets),
+		errors.Is(err, models.ErrSensorDataPurged),
+		errors.Is(err, models.ErrInvalidAlertFilter):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// sensorDataQuery construye las opciones de consulta del histórico a partir de la URL
+func sensorDataQuery(c *gin.Context) (models.SensorDataQuery, error) {
+	var query models.SensorDataQuery
+	var err error
+
+	if query.DeviceID, err = optionalUintQuery(c, "device_id"); err != nil {
+		return query, errors.New("ID de dispositivo inválido")
+	}
+	if query.From, err = optionalTimeQuery(c, "from"); err != nil {
+		return query, err
+	}
+	if query.To, err = optionalTimeQuery(c, "to"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = optionalIntQuery(c, "limit"); err != nil {
+		return query, err
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if query.Cursor, err = models.DecodeCursor(cursor); err != nil {
+			return query, err
+		}
+	}
+
+	return query, nil
+}
+
+// alertFilterQuery construye el filtro de alertas a partir de la URL, con el estado de
+// lectura del usuario autenticado
+func alertFilterQuery(c *gin.Context) (models.AlertFilter, error) {
+	filter := models.AlertFilter{
+		UserID:      c.GetUint("userID"),
+		SensorTypes: listQuery(c, "sensor_type"),
+		Severities:  listQuery(c, "severity"),
+	}
+	var err error
+
+	if isReadParam := c.Query("is_read"); isReadParam != "" {
+		isRead, err := strconv.ParseBool(isReadParam)
+		if err != nil {
+			return filter, errors.New("el parámetro is_read debe ser booleano")
+		}
+		filter.IsRead = &isRead
+	}
+	if filter.DeviceID, err = optionalUintQuery(c, "device_id"); err != nil {
+		return filter, errors.New("ID de dispositivo inválido")
+	}
+	if filter.From, err = optionalTimeQuery(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = optionalTimeQuery(c, "to"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// sensorAggregateQuery construye las opciones de agregación a partir de la URL
+func sensorAggregateQuery(c *gin.Context) (models.SensorAggregateQuery, error) {
+	query := models.SensorAggregateQuery{
+		Metrics:   listQuery(c, "metrics"),
+		Functions: listQuery(c, "fn"),
+	}
+	var err error
+
+	if query.DeviceID, err = optionalUintQuery(c, "device_id"); err != nil {
+		return query, errors.New("ID de dispositivo inválido")
+	}
+	if query.Bucket, err = models.ParseBucket(c.DefaultQuery("bucket", "1h")); err != nil {
+		return query, err
+	}
+
+	// Por defecto, las últimas 24 horas
+	to, err := optionalTimeQuery(c, "to")
+	if err != nil {
+		return query, err
+	}
+	from, err := optionalTimeQuery(c, "from")
+	if err != nil {
+		return query, err
+	}
+
+	query.To = time.Now()
+	if to != nil {
+		query.To = *to
+	}
+	query.From = query.To.Add(-24 * time.Hour)
+	if from != nil {
+		query.From = *from
+	}
+
+	return query, nil
+}
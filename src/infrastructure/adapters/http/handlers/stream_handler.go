@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ApiSmart/src/infrastructure/adapters/realtime"
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeatInterval es cada cuánto se envía un comentario para mantener viva la conexión
+const streamHeartbeatInterval = 15 * time.Second
+
+// StreamHandler maneja la difusión en tiempo real de lecturas y alertas
+type StreamHandler struct {
+	hub *realtime.Hub
+}
+
+// NewStreamHandler crea una nueva instancia de StreamHandler
+func NewStreamHandler(hub *realtime.Hub) *StreamHandler {
+	return &StreamHandler{
+		hub: hub,
+	}
+}
+
+// StreamSensorEvents envía cada nueva lectura y alerta como Server-Sent Events.
+// Admite ?device_id= y ?topics=sensor.data,sensor.alerts para filtrar, y reanuda desde la
+// cabecera Last-Event-ID (o ?last_event_id=) con los mensajes que el hub aún conserva.
+func (h *StreamHandler) StreamSensorEvents(c *gin.Context) {
+	filter, err := newMessageFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var lastID uint64
+	if lastEventID != "" {
+		if lastID, err = strconv.ParseUint(lastEventID, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID inválido"})
+			return
+		}
+	}
+
+	sub, missed := h.hub.Subscribe(lastID)
+	defer h.hub.Unsubscribe(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Indicar al navegador cuánto esperar antes de reconectar
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+
+	for _, msg := range missed {
+		if filter.matches(msg) {
+			writeSSE(c, msg)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case msg, ok := <-sub.C:
+			if !ok {
+				// Desconectado por lentitud: el cliente reconectará con Last-Event-ID
+				return
+			}
+			if filter.matches(msg) {
+				writeSSE(c, msg)
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
+// writeSSE escribe un mensaje en formato Server-Sent Events
+func writeSSE(c *gin.Context, msg realtime.Message) {
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, msg.Data)
+}
+
+// messageFilter selecciona los mensajes por topic y dispositivo
+type messageFilter struct {
+	deviceID *uint
+	topics   map[string]bool
+}
+
+// newMessageFilter construye el filtro a partir de ?device_id= y ?topics=
+func newMessageFilter(c *gin.Context) (*messageFilter, error) {
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil {
+		return nil, errors.New("ID de dispositivo inválido")
+	}
+
+	filter := &messageFilter{deviceID: deviceID}
+	if topics := listQuery(c, "topics"); len(topics) > 0 {
+		filter.topics = make(map[string]bool)
+		for _, topic := range topics {
+			filter.topics[topic] = true
+		}
+	}
+
+	return filter, nil
+}
+
+// matches indica si el mensaje debe enviarse al cliente
+func (f *messageFilter) matches(msg realtime.Message) bool {
+	if f.deviceID != nil && msg.DeviceID != *f.deviceID {
+		return false
+	}
+	if f.topics != nil && !f.topics[msg.Topic] {
+		return false
+	}
+	return true
+}
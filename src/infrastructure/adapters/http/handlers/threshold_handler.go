@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ThresholdHandler maneja las solicitudes HTTP relacionadas con los umbrales de alertas
+type ThresholdHandler struct {
+	thresholdUseCase *use_case.ThresholdUseCase
+}
+
+// NewThresholdHandler crea una nueva instancia de ThresholdHandler
+func NewThresholdHandler(thresholdUseCase *use_case.ThresholdUseCase) *ThresholdHandler {
+	return &ThresholdHandler{
+		thresholdUseCase: thresholdUseCase,
+	}
+}
+
+// GetThresholds obtiene los umbrales globales o, con ?device_id=, los efectivos de un dispositivo
+func (h *ThresholdHandler) GetThresholds(c *gin.Context) {
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	settings, err := h.thresholdUseCase.GetThresholds(c.Request.Context(), deviceID)
+	if err != nil {
+		c.JSON(thresholdErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateThresholds reemplaza los umbrales globales o, con ?device_id=, los de un dispositivo
+func (h *ThresholdHandler) UpdateThresholds(c *gin.Context) {
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	var req models.UpdateThresholdsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.thresholdUseCase.UpdateThresholds(c.Request.Context(), deviceID, req)
+	if err != nil {
+		c.JSON(thresholdErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// DeleteThresholds elimina los umbrales propios de un dispositivo (?device_id= obligatorio)
+func (h *ThresholdHandler) DeleteThresholds(c *gin.Context) {
+	deviceID, err := optionalUintQuery(c, "device_id")
+	if err != nil || deviceID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de dispositivo inválido"})
+		return
+	}
+
+	if err := h.thresholdUseCase.DeleteThresholds(c.Request.Context(), *deviceID); err != nil {
+		c.JSON(thresholdErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Umbrales del dispositivo eliminados"})
+}
+
+// thresholdErrorStatus traduce los errores de dominio de umbrales a códigos HTTP
+func thresholdErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidThresholds):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrThresholdsNotFound):
+		return http.StatusNotFound
+	default:
+		return deviceErrorStatus(err)
+	}
+}
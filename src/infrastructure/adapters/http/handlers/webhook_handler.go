@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/models"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler maneja las solicitudes HTTP relacionadas con los webhooks del usuario autenticado
+type WebhookHandler struct {
+	webhookUseCase *use_case.WebhookUseCase
+}
+
+// NewWebhookHandler crea una nueva instancia de WebhookHandler
+func NewWebhookHandler(webhookUseCase *use_case.WebhookUseCase) *WebhookHandler {
+	return &WebhookHandler{
+		webhookUseCase: webhookUseCase,
+	}
+}
+
+// CreateWebhook registra un webhook; la respuesta incluye la clave de firma, que no se vuelve a mostrar
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	credentials, err := h.webhookUseCase.CreateWebhook(c.Request.Context(), c.GetUint("userID"), req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, credentials)
+}
+
+// GetWebhooks obtiene los webhooks del usuario
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookUseCase.GetWebhooks(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook obtiene un webhook del usuario por su ID
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de webhook inválido"})
+		return
+	}
+
+	webhook, err := h.webhookUseCase.GetWebhook(c.Request.Context(), c.GetUint("userID"), uint(webhookID))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook actualiza un webhook del usuario; "active": true reactiva uno deshabilitado
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de webhook inválido"})
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.webhookUseCase.UpdateWebhook(c.Request.Context(), c.GetUint("userID"), uint(webhookID), req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook elimina un webhook del usuario
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de webhook inválido"})
+		return
+	}
+
+	if err := h.webhookUseCase.DeleteWebhook(c.Request.Context(), c.GetUint("userID"), uint(webhookID)); err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook eliminado correctamente"})
+}
+
+// GetDeliveries obtiene el historial de entregas de un webhook del usuario (?limit=)
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	webhookID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de webhook inválido"})
+		return
+	}
+
+	limit, err := optionalIntQuery(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	deliveries, err := h.webhookUseCase.GetDeliveries(c.Request.Context(), c.GetUint("userID"), uint(webhookID), limit)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// webhookErrorStatus traduce los errores de dominio de webhooks a códigos HTTP
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidWebhookEventType):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrWebhookNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"ApiSmart/src/core/application/use_case"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/infrastructure/adapters/realtime"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Tiempos de la conexión WebSocket
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsMaxMessage = 4096
+)
+
+// Acciones que puede enviar el cliente
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+	wsActionMarkRead    = "mark_read"
+	wsActionPing        = "ping"
+)
+
+// wsTopics son los topics a los que se puede suscribir un cliente
+var wsTopics = map[string]bool{
+	events.TopicSensorData:   true,
+	events.TopicSensorAlerts: true,
+}
+
+// wsClientMessage es un mensaje recibido del cliente
+type wsClientMessage struct {
+	Action   string   `json:"action"`
+	ID       string   `json:"id,omitempty"` // se devuelve en la respuesta para correlacionarla
+	Topics   []string `json:"topics,omitempty"`
+	DeviceID *uint    `json:"device_id,omitempty"`
+	AlertID  uint     `json:"alert_id,omitempty"`
+}
+
+// wsServerMessage es un mensaje enviado al cliente
+type wsServerMessage struct {
+	Type    string            `json:"type"` // event, subscribed, unsubscribed, ack, pong, error
+	ID      string            `json:"id,omitempty"`
+	Topics  []string          `json:"topics,omitempty"`
+	Event   *realtime.Message `json:"event,omitempty"`
+	AlertID uint              `json:"alert_id,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// WebSocketHandler maneja el canal bidireccional en tiempo real
+type WebSocketHandler struct {
+	hub           *realtime.Hub
+	sensorUseCase *use_case.SensorUseCase
+	upgrader      websocket.Upgrader
+}
+
+// NewWebSocketHandler crea una nueva instancia de WebSocketHandler. Solo se aceptan
+// conexiones desde allowedOrigins (o sin cabecera Origin, como las apps móviles).
+func NewWebSocketHandler(hub *realtime.Hub, sensorUseCase *use_case.SensorUseCase, allowedOrigins []string) *WebSocketHandler {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		origins[origin] = true
+	}
+
+	return &WebSocketHandler{
+		hub:           hub,
+		sensorUseCase: sensorUseCase,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || origins["*"] || origins[origin]
+			},
+		},
+	}
+}
+
+// wsSession es el estado de una conexión
+type wsSession struct {
+	conn     *websocket.Conn
+	userID   uint
+	topics   map[string]bool
+	deviceID *uint
+}
+
+// Connect abre el WebSocket. El cliente envía {"action":"subscribe","topics":[...]} para
+// recibir eventos y {"action":"mark_read","alert_id":N} para marcar alertas como leídas.
+func (h *WebSocketHandler) Connect(c *gin.Context) {
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade ya respondió al cliente con el error
+		log.Printf("Error abriendo WebSocket: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	session := &wsSession{
+		conn:   conn,
+		userID: c.GetUint("userID"),
+		topics: make(map[string]bool),
+	}
+
+	sub, _ := h.hub.Subscribe(0)
+	defer h.hub.Unsubscribe(sub)
+
+	// La lectura se hace en otra goroutine; cuando termina se cierra la sesión
+	incoming := make(chan wsClientMessage)
+	go func() {
+		defer cancel()
+		h.readLoop(ctx, session, incoming)
+	}()
+
+	h.writeLoop(ctx, session, sub, incoming)
+	conn.Close()
+}
+
+// readLoop lee los mensajes del cliente hasta que la conexión se cierra
+func (h *WebSocketHandler) readLoop(ctx context.Context, session *wsSession, incoming chan<- wsClientMessage) {
+	defer close(incoming)
+
+	session.conn.SetReadLimit(wsMaxMessage)
+	session.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	session.conn.SetPongHandler(func(string) error {
+		return session.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg wsClientMessage
+		if err := session.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Error leyendo WebSocket: %v", err)
+			}
+			return
+		}
+
+		select {
+		case incoming <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// writeLoop es el único que escribe en la conexión: eventos del hub, respuestas y pings
+func (h *WebSocketHandler) writeLoop(ctx context.Context, session *wsSession, sub *realtime.Subscription, incoming <-chan wsClientMessage) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.write(session, websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case <-ping.C:
+			if err := h.write(session, websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case msg, ok := <-incoming:
+			if !ok {
+				return
+			}
+			if err := h.writeJSON(session, h.handleClientMessage(ctx, session, msg)); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				h.write(session, websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "cliente demasiado lento"))
+				return
+			}
+			if !session.topics[event.Topic] || (session.deviceID != nil && event.DeviceID != *session.deviceID) {
+				continue
+			}
+			if err := h.writeJSON(session, wsServerMessage{Type: "event", Event: &event}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleClientMessage ejecuta una acción del cliente y devuelve la respuesta
+func (h *WebSocketHandler) handleClientMessage(ctx context.Context, session *wsSession, msg wsClientMessage) wsServerMessage {
+	switch msg.Action {
+	case wsActionSubscribe:
+		for _, topic := range msg.Topics {
+			if !wsTopics[topic] {
+				return wsServerMessage{Type: "error", ID: msg.ID, Error: "topic desconocido: " + topic}
+			}
+		}
+		for _, topic := range msg.Topics {
+			session.topics[topic] = true
+		}
+		if msg.DeviceID != nil {
+			session.deviceID = msg.DeviceID
+		}
+		return wsServerMessage{Type: "subscribed", ID: msg.ID, Topics: session.subscribedTopics()}
+
+	case wsActionUnsubscribe:
+		for _, topic := range msg.Topics {
+			delete(session.topics, topic)
+		}
+		return wsServerMessage{Type: "unsubscribed", ID: msg.ID, Topics: session.subscribedTopics()}
+
+	case wsActionMarkRead:
+		if msg.AlertID == 0 {
+			return wsServerMessage{Type: "error", ID: msg.ID, Error: "ID de alerta inválido"}
+		}
+		if err := h.sensorUseCase.MarkAlertAsRead(ctx, session.userID, msg.AlertID); err != nil {
+			return wsServerMessage{Type: "error", ID: msg.ID, AlertID: msg.AlertID, Error: err.Error()}
+		}
+		return wsServerMessage{Type: "ack", ID: msg.ID, AlertID: msg.AlertID}
+
+	case wsActionPing:
+		return wsServerMessage{Type: "pong", ID: msg.ID}
+
+	default:
+		return wsServerMessage{Type: "error", ID: msg.ID, Error: "acción desconocida: " + msg.Action}
+	}
+}
+
+// subscribedTopics devuelve los topics suscritos
+func (s *wsSession) subscribedTopics() []string {
+	topics := []string{}
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+func (h *WebSocketHandler) writeJSON(session *wsSession, msg wsServerMessage) error {
+	session.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return session.conn.WriteJSON(msg)
+}
+
+func (h *WebSocketHandler) write(session *wsSession, messageType int, data []byte) error {
+	session.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return session.conn.WriteMessage(messageType, data)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedQueryParams son los parámetros de consulta que transportan credenciales y no
+// deben llegar nunca a los logs
+var redactedQueryParams = map[string]bool{
+	"access_token": true,
+	"ticket":       true,
+}
+
+// requestLogger registra cada petición con el formato del logger por defecto de Gin, pero
+// ocultando los valores de los parámetros con credenciales
+func requestLogger(out io.Writer) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				redactQuery(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactQuery sustituye en la ruta los valores de los parámetros con credenciales,
+// respetando el orden y la codificación del resto
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, _, _ := strings.Cut(param, "=")
+		if name, err := url.QueryUnescape(key); err == nil && redactedQueryParams[name] {
+			params[i] = key + "=REDACTED"
+		}
+	}
+
+	return base + "?" + strings.Join(params, "&")
+}
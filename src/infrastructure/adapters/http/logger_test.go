@@ -0,0 +1,33 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestLoggerRedactsCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	router := gin.New()
+	router.Use(requestLogger(&logs))
+	router.GET("/api/ws", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ws?since=5&access_token=token-de-sesion&ticket=ticket-de-streaming", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	out := logs.String()
+	for _, secret := range []string{"token-de-sesion", "ticket-de-streaming"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("el log contiene la credencial %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "/api/ws?since=5&access_token=REDACTED&ticket=REDACTED") {
+		t.Errorf("log = %q, se esperaba la ruta con las credenciales ocultas", out)
+	}
+}
@@ -10,42 +10,70 @@ import (
 
 // Router maneja la configuración de las rutas HTTP
 type Router struct {
-	authHandler   *handlers.AuthHandler
-	sensorHandler *handlers.SensorHandler
-	corsConfig    cors.Config
+	authHandler         *handlers.AuthHandler
+	sensorHandler       *handlers.SensorHandler
+	deviceHandler       *handlers.DeviceHandler
+	thresholdHandler    *handlers.ThresholdHandler
+	streamHandler       *handlers.StreamHandler
+	wsHandler           *handlers.WebSocketHandler
+	eventAdminHandler   *handlers.EventAdminHandler
+	webhookHandler      *handlers.WebhookHandler
+	notificationHandler *handlers.NotificationHandler
+	alertRuleHandler    *handlers.AlertRuleHandler
+	corsConfig          cors.Config
+	adminUserIDs        []uint
 }
 
 // RouterConfig contiene la configuración para el router
 type RouterConfig struct {
 	AllowedOrigins []string
+	AdminUserIDs   []uint // Usuarios con acceso a /api/admin
 }
 
 // NewRouter crea un nuevo router HTTP
 func NewRouter(
 	authHandler *handlers.AuthHandler,
 	sensorHandler *handlers.SensorHandler,
+	deviceHandler *handlers.DeviceHandler,
+	thresholdHandler *handlers.ThresholdHandler,
+	streamHandler *handlers.StreamHandler,
+	wsHandler *handlers.WebSocketHandler,
+	eventAdminHandler *handlers.EventAdminHandler,
+	webhookHandler *handlers.WebhookHandler,
+	notificationHandler *handlers.NotificationHandler,
+	alertRuleHandler *handlers.AlertRuleHandler,
 	config RouterConfig,
 ) *Router {
 	// Configurar CORS
 	corsConfig := cors.Config{
 		AllowOrigins:     config.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Device-ID", "X-Device-Key", "X-Timestamp", "X-Signature", "Last-Event-ID"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
 
 	return &Router{
-		authHandler:   authHandler,
-		sensorHandler: sensorHandler,
-		corsConfig:    corsConfig,
+		authHandler:         authHandler,
+		sensorHandler:       sensorHandler,
+		deviceHandler:       deviceHandler,
+		thresholdHandler:    thresholdHandler,
+		streamHandler:       streamHandler,
+		wsHandler:           wsHandler,
+		eventAdminHandler:   eventAdminHandler,
+		webhookHandler:      webhookHandler,
+		notificationHandler: notificationHandler,
+		alertRuleHandler:    alertRuleHandler,
+		corsConfig:          corsConfig,
+		adminUserIDs:        config.AdminUserIDs,
 	}
 }
 
 // Setup configura las rutas en el engine de Gin
 func (r *Router) Setup() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(requestLogger(gin.DefaultWriter), gin.Recovery())
 
 	// Middleware CORS
 	router.Use(cors.New(r.corsConfig))
@@ -54,17 +82,76 @@ func (r *Router) Setup() *gin.Engine {
 	router.POST("/api/register", r.authHandler.Register)
 	router.POST("/api/login", r.authHandler.Login)
 
-	// Ruta para enviar datos de sensores (sin autenticación para dispositivos IoT)
-	router.POST("/sensores", r.sensorHandler.CreateSensorData)
+	// Ruta para enviar datos de sensores (autenticada con las credenciales del dispositivo IoT)
+	router.POST("/sensores", r.deviceHandler.DeviceAuthMiddleware(), r.sensorHandler.CreateSensorData)
+
+	// Rutas de tiempo real (aceptan también un ticket de streaming como ?ticket=)
+	streaming := router.Group("/api")
+	streaming.Use(r.authHandler.StreamAuthMiddleware())
+	{
+		streaming.GET("/sensors/stream", r.streamHandler.StreamSensorEvents)
+		streaming.GET("/ws", r.wsHandler.Connect)
+	}
 
 	// Rutas protegidas por autenticación
 	authorized := router.Group("/api")
 	authorized.Use(r.authHandler.AuthMiddleware())
 	{
+		authorized.POST("/stream-ticket", r.authHandler.IssueStreamTicket)
+
 		authorized.GET("/sensors", r.sensorHandler.GetAllSensorData)
 		authorized.GET("/sensors/latest", r.sensorHandler.GetLatestSensorData)
+		authorized.GET("/sensors/aggregate", r.sensorHandler.AggregateSensorData)
+		authorized.GET("/sensors/export", r.sensorHandler.ExportSensorData)
 		authorized.GET("/sensors/alerts", r.sensorHandler.GetAlerts)
+		authorized.PATCH("/sensors/alerts", r.sensorHandler.UpdateAlerts)
+		authorized.DELETE("/sensors/alerts", r.sensorHandler.DeleteAlerts)
+		authorized.GET("/sensors/alerts/counts", r.sensorHandler.CountAlerts)
+		authorized.GET("/sensors/alerts/export", r.sensorHandler.ExportAlerts)
+		authorized.GET("/sensors/alerts/states", r.sensorHandler.GetAlertStates)
 		authorized.PUT("/sensors/alerts/:id/read", r.sensorHandler.MarkAlertAsRead)
+		authorized.PUT("/sensors/alerts/:id/acknowledge", r.sensorHandler.AcknowledgeAlert)
+		authorized.PUT("/sensors/alerts/:id/resolve", r.sensorHandler.ResolveAlert)
+
+		authorized.GET("/devices", r.deviceHandler.GetAllDevices)
+		authorized.POST("/devices", r.deviceHandler.CreateDevice)
+		authorized.GET("/devices/:id", r.deviceHandler.GetDevice)
+		authorized.PUT("/devices/:id", r.deviceHandler.UpdateDevice)
+		authorized.DELETE("/devices/:id", r.deviceHandler.DeleteDevice)
+		authorized.POST("/devices/:id/key/rotate", r.deviceHandler.RotateDeviceKey)
+		authorized.DELETE("/devices/:id/key", r.deviceHandler.RevokeDeviceKey)
+
+		authorized.GET("/thresholds", r.thresholdHandler.GetThresholds)
+		authorized.PUT("/thresholds", r.thresholdHandler.UpdateThresholds)
+		authorized.DELETE("/thresholds", r.thresholdHandler.DeleteThresholds)
+
+		authorized.GET("/alert-rules", r.alertRuleHandler.GetRules)
+		authorized.POST("/alert-rules", r.alertRuleHandler.CreateRule)
+		authorized.GET("/alert-rules/:id", r.alertRuleHandler.GetRule)
+		authorized.PUT("/alert-rules/:id", r.alertRuleHandler.UpdateRule)
+		authorized.DELETE("/alert-rules/:id", r.alertRuleHandler.DeleteRule)
+
+		authorized.GET("/webhooks", r.webhookHandler.GetWebhooks)
+		authorized.POST("/webhooks", r.webhookHandler.CreateWebhook)
+		authorized.GET("/webhooks/:id", r.webhookHandler.GetWebhook)
+		authorized.PUT("/webhooks/:id", r.webhookHandler.UpdateWebhook)
+		authorized.DELETE("/webhooks/:id", r.webhookHandler.DeleteWebhook)
+		authorized.GET("/webhooks/:id/deliveries", r.webhookHandler.GetDeliveries)
+
+		authorized.GET("/notifications", r.notificationHandler.GetInbox)
+		authorized.POST("/notifications/read", r.notificationHandler.MarkRead)
+		authorized.POST("/notifications/read-all", r.notificationHandler.MarkAllRead)
+		authorized.GET("/notifications/preferences", r.notificationHandler.GetPreferences)
+		authorized.PUT("/notifications/preferences", r.notificationHandler.UpdatePreferences)
+	}
+
+	// Rutas de administración, solo para los usuarios configurados como administradores
+	admin := authorized.Group("/admin")
+	admin.Use(handlers.AdminMiddleware(r.adminUserIDs))
+	{
+		admin.GET("/events/dead-letters", r.eventAdminHandler.ListDeadLetters)
+		admin.POST("/events/dead-letters/requeue", r.eventAdminHandler.RequeueDeadLetters)
+		admin.POST("/events/replay", r.eventAdminHandler.ReplayEvents)
 	}
 
 	return router
@@ -0,0 +1,23 @@
+package notifications
+
+import (
+	"context"
+	"log"
+
+	"ApiSmart/src/core/domain/models"
+)
+
+// LogNotifier implementa application.Notifier escribiendo las notificaciones en el log.
+// Se usa cuando el envío de correos está desactivado.
+type LogNotifier struct{}
+
+// NewLogNotifier crea una nueva instancia de LogNotifier
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify implementa application.Notifier
+func (n *LogNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	log.Printf("Notificación (sin enviar) para %s: %s", notification.To, notification.Subject)
+	return nil
+}
@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+	"ApiSmart/src/core/tipo_de_datos"
+)
+
+// SMTPNotifier implementa application.Notifier enviando correos multipart (texto y HTML)
+// por SMTP. Usa STARTTLS si el servidor lo ofrece y solo se autentica si hay usuario, de
+// modo que funciona tanto con un servidor real como con MailHog o similares.
+type SMTPNotifier struct {
+	host     string
+	addr     string
+	from     string
+	username string
+	password string
+	timeout  time.Duration
+}
+
+// NewSMTPNotifier crea una nueva instancia de SMTPNotifier
+func NewSMTPNotifier(config tipo_de_datos.SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{
+		host:     config.Host,
+		addr:     net.JoinHostPort(config.Host, config.Port),
+		from:     config.From,
+		username: config.Username,
+		password: config.Password,
+		timeout:  time.Duration(config.TimeoutMs) * time.Millisecond,
+	}
+}
+
+// Notify implementa application.Notifier
+func (n *SMTPNotifier) Notify(ctx context.Context, notification models.Notification) error {
+	msg, err := n.buildMessage(notification)
+	if err != nil {
+		return fmt.Errorf("error generando el correo: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: n.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("error conectando al servidor SMTP: %w", err)
+	}
+
+	// Toda la conversación debe terminar dentro del plazo
+	deadline := time.Now().Add(n.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error iniciando la sesión SMTP: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("error iniciando TLS: %w", err)
+		}
+	}
+
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("error de autenticación SMTP: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(notification.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildMessage compone el mensaje MIME multipart/alternative con la versión en texto y en HTML
+func (n *SMTPNotifier) buildMessage(notification models.Notification) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		// El cliente de correo muestra la última alternativa que sepa interpretar
+		{"text/plain; charset=UTF-8", notification.Text},
+		{"text/html; charset=UTF-8", notification.HTML},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+
+		pw, err := mw.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	headers := []string{
+		"From: " + n.from,
+		"To: " + notification.To,
+		"Subject: " + mime.QEncoding.Encode("UTF-8", notification.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", mw.Boundary()),
+	}
+	msg.WriteString(strings.Join(headers, "\r\n"))
+	msg.WriteString("\r\n\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
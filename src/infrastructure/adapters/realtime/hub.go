@@ -0,0 +1,117 @@
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer es la cantidad de mensajes pendientes que admite un suscriptor lento
+// antes de ser desconectado
+const subscriberBuffer = 64
+
+// Message es una notificación en tiempo real difundida a los clientes conectados
+type Message struct {
+	ID       uint64          `json:"id"`
+	Topic    string          `json:"topic"`
+	Type     string          `json:"type"`
+	DeviceID uint            `json:"device_id,omitempty"`
+	Data     json.RawMessage `json:"data"`
+	Time     time.Time       `json:"timestamp"`
+}
+
+// Subscription recibe los mensajes del hub; C se cierra si el suscriptor se queda atrás
+type Subscription struct {
+	C  <-chan Message
+	ch chan Message
+}
+
+// Hub difunde mensajes en proceso a todos los suscriptores y conserva los últimos
+// para que un cliente pueda reanudar desde el último ID recibido.
+// Implementa application.RealtimePublisher.
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Message
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub crea un hub que conserva los últimos historySize mensajes
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish difunde un mensaje a todos los suscriptores
+func (h *Hub) Publish(topic, eventType string, deviceID uint, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error serializando mensaje en tiempo real: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	msg := Message{
+		ID:       h.nextID,
+		Topic:    topic,
+		Type:     eventType,
+		DeviceID: deviceID,
+		Data:     payload,
+		Time:     time.Now(),
+	}
+
+	h.history = append(h.history, msg)
+	if len(h.history) > h.historySize {
+		h.history = h.history[len(h.history)-h.historySize:]
+	}
+
+	for sub := range h.subscribers {
+		select {
+		case sub.ch <- msg:
+		default:
+			// El suscriptor no consume a tiempo: se desconecta para no bloquear al resto
+			delete(h.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe registra un suscriptor y devuelve los mensajes conservados con ID mayor que
+// lastEventID (ninguno si lastEventID es 0)
+func (h *Hub) Subscribe(lastEventID uint64) (*Subscription, []Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var missed []Message
+	if lastEventID > 0 && lastEventID <= h.nextID {
+		for _, msg := range h.history {
+			if msg.ID > lastEventID {
+				missed = append(missed, msg)
+			}
+		}
+	}
+
+	ch := make(chan Message, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch}
+	h.subscribers[sub] = struct{}{}
+
+	return sub, missed
+}
+
+// Unsubscribe elimina un suscriptor
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// AlertReadRepository implementa application.AlertReadRepository
+type AlertReadRepository struct {
+	db *sql.DB
+}
+
+// NewAlertReadRepository crea una nueva instancia de AlertReadRepository
+func NewAlertReadRepository(db *sql.DB) application.AlertReadRepository {
+	return &AlertReadRepository{
+		db: db,
+	}
+}
+
+// MarkRead marca como leídas para el usuario las alertas seleccionadas que aún no había leído
+func (r *AlertReadRepository) MarkRead(ctx context.Context, userID uint, selection models.AlertSelection, readAt time.Time) (int64, error) {
+	clause, args := alertSelectionClause(selection)
+
+	query := `
+		INSERT IGNORE INTO alert_reads (user_id, alert_id, read_at)
+		SELECT ?, alerts.id, ? FROM alerts WHERE 1=1
+	` + clause
+
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{userID, readAt}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// MarkUnread vuelve a marcar como no leídas para el usuario las alertas seleccionadas
+func (r *AlertReadRepository) MarkUnread(ctx context.Context, userID uint, selection models.AlertSelection) (int64, error) {
+	// Solo se borran lecturas, así que el filtro por estado de lectura sobra (y MySQL no
+	// permite consultar alert_reads mientras se borra de ella)
+	if selection.Filter != nil && selection.Filter.IsRead != nil {
+		if !*selection.Filter.IsRead {
+			return 0, nil
+		}
+		filter := *selection.Filter
+		filter.IsRead = nil
+		selection.Filter = &filter
+	}
+
+	clause, args := alertSelectionClause(selection)
+
+	query := `
+		DELETE alert_reads FROM alert_reads
+		JOIN alerts ON alerts.id = alert_reads.alert_id
+		WHERE alert_reads.user_id = ?
+	` + clause
+
+	result, err := r.db.ExecContext(ctx, query, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// CountUnread cuenta las alertas que el usuario no ha leído, por gravedad
+func (r *AlertReadRepository) CountUnread(ctx context.Context, userID uint) (map[string]int, error) {
+	query := `
+		SELECT alerts.severity, COUNT(*)
+		FROM alerts
+		LEFT JOIN alert_reads ON alert_reads.alert_id = alerts.id AND alert_reads.user_id = ?
+		WHERE alert_reads.alert_id IS NULL
+		GROUP BY alerts.severity
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+
+	for rows.Next() {
+		var severity string
+		var count int
+		if err := rows.Scan(&severity, &count); err != nil {
+			return nil, err
+		}
+		counts[severity] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
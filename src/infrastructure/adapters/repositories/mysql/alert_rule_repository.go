@@ -0,0 +1,174 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// AlertRuleRepository implementa application.AlertRuleRepository
+type AlertRuleRepository struct {
+	db *sql.DB
+}
+
+// NewAlertRuleRepository crea una nueva instancia de AlertRuleRepository
+func NewAlertRuleRepository(db *sql.DB) application.AlertRuleRepository {
+	return &AlertRuleRepository{
+		db: db,
+	}
+}
+
+const alertRuleColumns = `id, name, expression, device_id, severity, enabled, created_at, updated_at`
+
+// Create guarda una nueva regla en la base de datos
+func (r *AlertRuleRepository) Create(ctx context.Context, rule *models.AlertRule) error {
+	query := `
+		INSERT INTO alert_rules (name, expression, device_id, severity, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		rule.Name,
+		rule.Expression,
+		rule.DeviceID,
+		rule.Severity,
+		rule.Enabled,
+		rule.CreatedAt,
+		rule.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	rule.ID = uint(id)
+	return nil
+}
+
+// FindByID busca una regla por su ID
+func (r *AlertRuleRepository) FindByID(ctx context.Context, id uint) (*models.AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE id = ?`
+
+	rule, err := scanAlertRule(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrAlertRuleNotFound
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// FindAll obtiene todas las reglas
+func (r *AlertRuleRepository) FindAll(ctx context.Context) ([]models.AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY id`
+	return r.queryAlertRules(ctx, query)
+}
+
+// FindEnabled obtiene las reglas habilitadas
+func (r *AlertRuleRepository) FindEnabled(ctx context.Context) ([]models.AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE enabled = TRUE ORDER BY id`
+	return r.queryAlertRules(ctx, query)
+}
+
+// Update actualiza el nombre, la expresión, el dispositivo, la gravedad y el estado de una regla
+func (r *AlertRuleRepository) Update(ctx context.Context, rule *models.AlertRule) error {
+	query := `
+		UPDATE alert_rules
+		SET name = ?, expression = ?, device_id = ?, severity = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	rule.UpdatedAt = time.Now()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		rule.Name,
+		rule.Expression,
+		rule.DeviceID,
+		rule.Severity,
+		rule.Enabled,
+		rule.UpdatedAt,
+		rule.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrAlertRuleNotFound)
+}
+
+// Delete elimina una regla; sus alertas se conservan sin rule_id
+func (r *AlertRuleRepository) Delete(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrAlertRuleNotFound)
+}
+
+// queryAlertRules ejecuta una consulta que devuelve alertRuleColumns
+func (r *AlertRuleRepository) queryAlertRules(ctx context.Context, query string, args ...interface{}) ([]models.AlertRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.AlertRule{}
+
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, *rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// scanAlertRule lee una regla desde una fila con alertRuleColumns
+func scanAlertRule(row rowScanner) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var deviceID sql.NullInt64
+
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.Expression,
+		&deviceID,
+		&rule.Severity,
+		&rule.Enabled,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.DeviceID = nullUint(deviceID)
+	return &rule, nil
+}
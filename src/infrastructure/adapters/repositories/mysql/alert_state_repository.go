@@ -0,0 +1,125 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// AlertStateRepository implementa application.AlertStateRepository
+type AlertStateRepository struct {
+	db *sql.DB
+}
+
+// NewAlertStateRepository crea una nueva instancia de AlertStateRepository
+func NewAlertStateRepository(db *sql.DB) application.AlertStateRepository {
+	return &AlertStateRepository{
+		db: db,
+	}
+}
+
+const alertStateColumns = `device_id, alert_key, status, since, alert_id, last_value, updated_at`
+
+// FindByDevice obtiene los estados de un dispositivo bloqueándolos hasta el final de la transacción
+func (r *AlertStateRepository) FindByDevice(ctx context.Context, deviceID uint) ([]models.AlertState, error) {
+	query := `SELECT ` + alertStateColumns + ` FROM alert_states WHERE device_id = ? ORDER BY alert_key FOR UPDATE`
+	return r.queryAlertStates(ctx, query, deviceID)
+}
+
+// FindAll obtiene los estados de todos los dispositivos o, si se indica, de uno
+func (r *AlertStateRepository) FindAll(ctx context.Context, deviceID *uint) ([]models.AlertState, error) {
+	var args []interface{}
+
+	query := `SELECT ` + alertStateColumns + ` FROM alert_states WHERE 1=1`
+
+	if deviceID != nil {
+		query += " AND device_id = ?"
+		args = append(args, *deviceID)
+	}
+
+	query += " ORDER BY device_id, alert_key"
+
+	return r.queryAlertStates(ctx, query, args...)
+}
+
+// Save crea o reemplaza el estado de una condición
+func (r *AlertStateRepository) Save(ctx context.Context, state *models.AlertState) error {
+	query := `
+		INSERT INTO alert_states (device_id, alert_key, status, since, alert_id, last_value, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			status = VALUES(status),
+			since = VALUES(since),
+			alert_id = VALUES(alert_id),
+			last_value = VALUES(last_value),
+			updated_at = VALUES(updated_at)
+	`
+
+	state.UpdatedAt = time.Now()
+
+	_, err := executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		state.DeviceID,
+		state.Key,
+		state.Status,
+		state.Since,
+		state.AlertID,
+		state.LastValue,
+		state.UpdatedAt,
+	)
+	return err
+}
+
+// ResolveByAlert da por resuelta la condición que abrió una alerta
+func (r *AlertStateRepository) ResolveByAlert(ctx context.Context, alertID uint, resolvedAt time.Time) error {
+	query := `
+		UPDATE alert_states
+		SET status = ?, since = ?, updated_at = ?
+		WHERE alert_id = ? AND status <> ?
+	`
+
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, models.AlertStateResolved, resolvedAt, time.Now(), alertID, models.AlertStateResolved)
+	return err
+}
+
+// queryAlertStates ejecuta una consulta que devuelve alertStateColumns
+func (r *AlertStateRepository) queryAlertStates(ctx context.Context, query string, args ...interface{}) ([]models.AlertState, error) {
+	rows, err := executor(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []models.AlertState{}
+
+	for rows.Next() {
+		var state models.AlertState
+		var alertID sql.NullInt64
+
+		err := rows.Scan(
+			&state.DeviceID,
+			&state.Key,
+			&state.Status,
+			&state.Since,
+			&alertID,
+			&state.LastValue,
+			&state.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		state.AlertID = nullUint(alertID)
+		states = append(states, state)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
@@ -0,0 +1,240 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// DeviceRepository implementa application.DeviceRepository
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+// NewDeviceRepository crea una nueva instancia de DeviceRepository
+func NewDeviceRepository(db *sql.DB) application.DeviceRepository {
+	return &DeviceRepository{
+		db: db,
+	}
+}
+
+// Create guarda un nuevo dispositivo en la base de datos
+func (r *DeviceRepository) Create(ctx context.Context, device *models.Device) error {
+	query := `
+		INSERT INTO devices (name, location, description, api_key, key_issued_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	device.CreatedAt = now
+	device.UpdatedAt = now
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		device.Name,
+		device.Location,
+		device.Description,
+		nullString(device.APIKey),
+		device.KeyIssuedAt,
+		device.CreatedAt,
+		device.UpdatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	device.ID = uint(id)
+	return nil
+}
+
+// FindByID busca un dispositivo por su ID
+func (r *DeviceRepository) FindByID(ctx context.Context, id uint) (*models.Device, error) {
+	query := `
+		SELECT id, name, location, description, api_key, key_issued_at, created_at, updated_at
+		FROM devices
+		WHERE id = ?
+	`
+
+	device, err := scanDevice(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrDeviceNotFound
+		}
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// FindAll obtiene todos los dispositivos registrados
+func (r *DeviceRepository) FindAll(ctx context.Context) ([]models.Device, error) {
+	query := `
+		SELECT id, name, location, description, api_key, key_issued_at, created_at, updated_at
+		FROM devices
+		ORDER BY id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, *device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// Update actualiza los datos de un dispositivo
+func (r *DeviceRepository) Update(ctx context.Context, device *models.Device) error {
+	query := `
+		UPDATE devices
+		SET name = ?, location = ?, description = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	device.UpdatedAt = time.Now()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		device.Name,
+		device.Location,
+		device.Description,
+		device.UpdatedAt,
+		device.ID,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrDeviceNotFound)
+}
+
+// SetAPIKey reemplaza la clave del dispositivo, invalidando la anterior
+func (r *DeviceRepository) SetAPIKey(ctx context.Context, id uint, apiKey string, issuedAt time.Time) error {
+	query := `UPDATE devices SET api_key = ?, key_issued_at = ?, updated_at = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, apiKey, issuedAt, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrDeviceNotFound)
+}
+
+// RevokeAPIKey elimina la clave del dispositivo, que deja de poder enviar lecturas
+func (r *DeviceRepository) RevokeAPIKey(ctx context.Context, id uint) error {
+	query := `UPDATE devices SET api_key = NULL, key_issued_at = NULL, updated_at = ? WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrDeviceNotFound)
+}
+
+// Delete elimina un dispositivo. Las lecturas asociadas se conservan sin dispositivo.
+func (r *DeviceRepository) Delete(ctx context.Context, id uint) error {
+	query := `DELETE FROM devices WHERE id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrDeviceNotFound)
+}
+
+// requireAffected devuelve notFound si la sentencia no afectó ninguna fila
+func requireAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// rowScanner abstrae *sql.Row y *sql.Rows para reutilizar el escaneo
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDevice lee un dispositivo desde una fila
+func scanDevice(row rowScanner) (*models.Device, error) {
+	var device models.Device
+	var apiKey sql.NullString
+	var keyIssuedAt sql.NullTime
+
+	err := row.Scan(
+		&device.ID,
+		&device.Name,
+		&device.Location,
+		&device.Description,
+		&apiKey,
+		&keyIssuedAt,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	device.APIKey = apiKey.String
+	if keyIssuedAt.Valid {
+		device.KeyIssuedAt = &keyIssuedAt.Time
+	}
+
+	return &device, nil
+}
+
+// nullString convierte una cadena vacía en NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullUint convierte un entero anulable en un puntero, nil si es NULL
+func nullUint(v sql.NullInt64) *uint {
+	if !v.Valid {
+		return nil
+	}
+	u := uint(v.Int64)
+	return &u
+}
+
+// nullTime convierte una fecha anulable en un puntero, nil si es NULL
+func nullTime(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// EventLogRepository implementa application.EventStore
+type EventLogRepository struct {
+	db *sql.DB
+}
+
+// NewEventLogRepository crea una nueva instancia de EventLogRepository
+func NewEventLogRepository(db *sql.DB) application.EventStore {
+	return &EventLogRepository{
+		db: db,
+	}
+}
+
+// Append registra un evento publicado; si ya estaba registrado no hace nada
+func (r *EventLogRepository) Append(ctx context.Context, topic string, event events.Event) error {
+	payload, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("error serializando evento: %w", err)
+	}
+
+	query := `
+		INSERT IGNORE INTO event_log (event_id, topic, event_type, schema_version, payload, occurred_at, recorded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(
+		ctx,
+		query,
+		event.ID,
+		topic,
+		event.Type,
+		event.SchemaVersion,
+		payload,
+		event.Timestamp,
+		time.Now(),
+	)
+	return err
+}
+
+// Stream recorre los eventos registrados que cumplen el filtro, en el orden en que se publicaron
+func (r *EventLogRepository) Stream(ctx context.Context, filter models.EventLogFilter, fn func(events.LoggedEvent) error) error {
+	query := `
+		SELECT id, event_id, topic, event_type, schema_version, payload, occurred_at, recorded_at
+		FROM event_log
+		WHERE 1=1
+	`
+	var args []interface{}
+
+	if filter.From != nil {
+		query += " AND occurred_at >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		query += " AND occurred_at < ?"
+		args = append(args, *filter.To)
+	}
+	if len(filter.EventTypes) > 0 {
+		query += " AND event_type IN (?" + strings.Repeat(", ?", len(filter.EventTypes)-1) + ")"
+		for _, eventType := range filter.EventTypes {
+			args = append(args, eventType)
+		}
+	}
+	query += " ORDER BY occurred_at, id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var logged events.LoggedEvent
+		var payload []byte
+
+		err := rows.Scan(
+			&logged.ID,
+			&logged.Event.ID,
+			&logged.Topic,
+			&logged.Event.Type,
+			&logged.Event.SchemaVersion,
+			&payload,
+			&logged.Event.Timestamp,
+			&logged.RecordedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Se reenvía tal cual; los consumidores lo actualizan a la versión vigente
+		logged.Event.Data = events.RawPayload{Type: logged.Event.Type, Data: payload}
+
+		if err := fn(logged); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// NotificationPreferenceRepository implementa application.NotificationPreferenceRepository
+type NotificationPreferenceRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationPreferenceRepository crea una nueva instancia de NotificationPreferenceRepository
+func NewNotificationPreferenceRepository(db *sql.DB) application.NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{
+		db: db,
+	}
+}
+
+// Get obtiene las preferencias del usuario o las de por defecto si no las ha guardado
+func (r *NotificationPreferenceRepository) Get(ctx context.Context, userID uint) (*models.NotificationPreferences, error) {
+	query := `SELECT email_alerts, updated_at FROM notification_preferences WHERE user_id = ?`
+
+	prefs := models.NotificationPreferences{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&prefs.EmailAlerts, &prefs.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// Save crea o reemplaza las preferencias del usuario
+func (r *NotificationPreferenceRepository) Save(ctx context.Context, prefs *models.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, email_alerts, updated_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			email_alerts = VALUES(email_alerts),
+			updated_at = VALUES(updated_at)
+	`
+
+	prefs.UpdatedAt = time.Now()
+
+	_, err := r.db.ExecContext(ctx, query, prefs.UserID, prefs.EmailAlerts, prefs.UpdatedAt)
+	return err
+}
+
+// FindAlertRecipients obtiene los usuarios que quieren recibir las alertas por correo
+func (r *NotificationPreferenceRepository) FindAlertRecipients(ctx context.Context) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email
+		FROM users u
+		JOIN notification_preferences p ON p.user_id = u.id
+		WHERE p.email_alerts = TRUE
+		ORDER BY u.id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Email); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
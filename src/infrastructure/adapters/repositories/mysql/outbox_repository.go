@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"github.com/google/uuid"
+)
+
+// OutboxRepository implementa application.OutboxRepository
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+// NewOutboxRepository crea una nueva instancia de OutboxRepository
+func NewOutboxRepository(db *sql.DB) application.OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+// Enqueue guarda un evento pendiente dentro de la transacción del contexto, si la hay.
+// El ID del evento se fija aquí para que las reentregas conserven el mismo.
+func (r *OutboxRepository) Enqueue(ctx context.Context, topic string, payload events.Payload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error serializando evento: %w", err)
+	}
+
+	query := `
+		INSERT INTO outbox (event_id, topic, event_type, schema_version, payload, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	_, err = executor(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		uuid.New().String(),
+		topic,
+		payload.EventType(),
+		events.CurrentSchemaVersion(payload.EventType()),
+		data,
+		now,
+		now,
+	)
+	return err
+}
+
+// ClaimPending obtiene hasta limit eventos pendientes cuyo turno llegó en now, por turno y
+// después en el orden en que se guardaron, y los aplaza lease. Las filas que otra instancia
+// está reclamando en ese momento se saltan en lugar de esperarlas.
+func (r *OutboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]events.OutboxMessage, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, event_id, topic, event_type, schema_version, payload, attempts, last_error, next_attempt_at, created_at
+		FROM outbox
+		WHERE sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, id
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`
+
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []events.OutboxMessage
+	var ids []interface{}
+	for rows.Next() {
+		var msg events.OutboxMessage
+		var payload []byte
+		var lastError sql.NullString
+
+		err := rows.Scan(
+			&msg.ID,
+			&msg.Event.ID,
+			&msg.Topic,
+			&msg.Event.Type,
+			&msg.Event.SchemaVersion,
+			&payload,
+			&msg.Attempts,
+			&lastError,
+			&msg.NextAttemptAt,
+			&msg.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// El relay solo reenvía el contenido: no hace falta decodificarlo
+		msg.Event.Data = events.RawPayload{Type: msg.Event.Type, Data: payload}
+		msg.Event.Timestamp = msg.CreatedAt
+		msg.LastError = lastError.String
+
+		messages = append(messages, msg)
+		ids = append(ids, msg.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	claim := `UPDATE outbox SET next_attempt_at = ? WHERE id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+	if _, err := tx.ExecContext(ctx, claim, append([]interface{}{now.Add(lease)}, ids...)...); err != nil {
+		return nil, err
+	}
+
+	return messages, tx.Commit()
+}
+
+// Release devuelve un evento reclamado y no publicado a su turno original
+func (r *OutboxRepository) Release(ctx context.Context, id uint64, nextAttemptAt time.Time) error {
+	query := "UPDATE outbox SET next_attempt_at = ? WHERE id = ? AND sent_at IS NULL"
+
+	_, err := r.db.ExecContext(ctx, query, nextAttemptAt, id)
+	return err
+}
+
+// MarkSent marca un evento como publicado
+func (r *OutboxRepository) MarkSent(ctx context.Context, id uint64) error {
+	query := "UPDATE outbox SET sent_at = ?, attempts = attempts + 1, last_error = NULL WHERE id = ?"
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	return err
+}
+
+// MarkFailed registra un intento de publicación fallido y programa el siguiente
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint64, reason string, nextAttemptAt time.Time) error {
+	query := "UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?"
+
+	_, err := r.db.ExecContext(ctx, query, reason, nextAttemptAt, id)
+	return err
+}
+
+// MarkParked registra el último intento fallido y aparta el evento
+func (r *OutboxRepository) MarkParked(ctx context.Context, id uint64, reason string) error {
+	query := "UPDATE outbox SET attempts = attempts + 1, last_error = ?, failed_at = ? WHERE id = ?"
+
+	_, err := r.db.ExecContext(ctx, query, reason, time.Now(), id)
+	return err
+}
+
+// PurgeSent elimina hasta limit eventos publicados antes de before
+func (r *OutboxRepository) PurgeSent(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := "DELETE FROM outbox WHERE sent_at IS NOT NULL AND sent_at < ? ORDER BY id LIMIT ?"
+
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,223 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// rollupChunkBuckets limita cuántos intervalos se recalculan en cada sentencia
+const rollupChunkBuckets = 1440
+
+// rollupTables relaciona cada resolución con su tabla de resumen
+var rollupTables = map[time.Duration]string{
+	models.RollupMinute: "sensor_data_1m",
+	models.RollupHour:   "sensor_data_1h",
+	models.RollupDay:    "sensor_data_1d",
+}
+
+// sensorMetricColumnList son las columnas de métricas en un orden estable
+var sensorMetricColumnList = []string{"temperatura_dht", "luz", "humedad", "humo"}
+
+// RollupRepository implementa application.RollupRepository
+type RollupRepository struct {
+	db *sql.DB
+}
+
+// NewRollupRepository crea una nueva instancia de RollupRepository
+func NewRollupRepository(db *sql.DB) application.RollupRepository {
+	return &RollupRepository{
+		db: db,
+	}
+}
+
+// Rollup recalcula los intervalos de la resolución indicada hasta until. Se retoma desde el
+// último intervalo ya calculado (que puede estar incompleto), de modo que es idempotente.
+func (r *RollupRepository) Rollup(ctx context.Context, resolution time.Duration, until time.Time) error {
+	target, ok := rollupTables[resolution]
+	if !ok {
+		return fmt.Errorf("resolución de resumen no soportada: %s", resolution)
+	}
+
+	from, ok, err := r.rollupStart(ctx, resolution)
+	if err != nil || !ok {
+		return err
+	}
+
+	chunk := time.Duration(rollupChunkBuckets) * resolution
+	for start := from; start.Before(until); start = start.Add(chunk) {
+		if err := r.rollupRange(ctx, resolution, target, start, start.Add(chunk)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollupStart calcula desde dónde hay que recalcular: el último intervalo del resumen o,
+// si está vacío, el primer dato de la fuente alineado al intervalo
+func (r *RollupRepository) rollupStart(ctx context.Context, resolution time.Duration) (time.Time, bool, error) {
+	var last sql.NullTime
+	query := fmt.Sprintf("SELECT MAX(bucket_start) FROM %s", rollupTables[resolution])
+	if err := r.db.QueryRowContext(ctx, query).Scan(&last); err != nil {
+		return time.Time{}, false, err
+	}
+	if last.Valid {
+		return last.Time, true, nil
+	}
+
+	sourceTable, timeColumn := rollupSource(resolution)
+	var first sql.NullTime
+	query = fmt.Sprintf("SELECT MIN(%s) FROM %s", timeColumn, sourceTable)
+	if err := r.db.QueryRowContext(ctx, query).Scan(&first); err != nil {
+		return time.Time{}, false, err
+	}
+	if !first.Valid {
+		return time.Time{}, false, nil
+	}
+
+	return alignToBucket(first.Time, resolution), true, nil
+}
+
+// rollupRange recalcula y guarda los intervalos de [from, to)
+func (r *RollupRepository) rollupRange(ctx context.Context, resolution time.Duration, target string, from, to time.Time) error {
+	sourceTable, timeColumn := rollupSource(resolution)
+	fromRaw := sourceTable == "sensor_data"
+
+	insertColumns := []string{"device_id", "bucket_start", "sample_count"}
+	selects := []string{
+		"COALESCE(device_id, 0)",
+		fmt.Sprintf("DATE_ADD('1970-01-01 00:00:00', INTERVAL (TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', %s) DIV ?) * ? SECOND) AS bucket", timeColumn),
+	}
+	updates := []string{"sample_count = VALUES(sample_count)"}
+
+	if fromRaw {
+		selects = append(selects, "COUNT(*)")
+	} else {
+		selects = append(selects, "SUM(sample_count)")
+	}
+
+	for _, column := range sensorMetricColumnList {
+		insertColumns = append(insertColumns, column+"_sum", column+"_min", column+"_max")
+		if fromRaw {
+			selects = append(selects, fmt.Sprintf("SUM(%s), MIN(%s), MAX(%s)", column, column, column))
+		} else {
+			selects = append(selects, fmt.Sprintf("SUM(%s_sum), MIN(%s_min), MAX(%s_max)", column, column, column))
+		}
+		for _, suffix := range []string{"_sum", "_min", "_max"} {
+			updates = append(updates, fmt.Sprintf("%s%s = VALUES(%s%s)", column, suffix, column, suffix))
+		}
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT %s
+		FROM %s
+		WHERE %s >= ? AND %s < ?
+		GROUP BY COALESCE(device_id, 0), bucket
+		ON DUPLICATE KEY UPDATE %s
+	`,
+		target, strings.Join(insertColumns, ", "),
+		strings.Join(selects, ", "),
+		sourceTable,
+		timeColumn, timeColumn,
+		strings.Join(updates, ", "),
+	)
+
+	seconds := int64(resolution / time.Second)
+	_, err := r.db.ExecContext(ctx, query, seconds, seconds, from, to)
+	return err
+}
+
+// Purge elimina hasta batchSize filas anteriores a before. Nunca elimina datos que el
+// resumen siguiente aún no ha consumido, ni lecturas referenciadas por alertas.
+func (r *RollupRepository) Purge(ctx context.Context, resolution time.Duration, before time.Time, batchSize int) (int64, error) {
+	// Limitar el corte al progreso del resumen que se alimenta de esta tabla
+	if consumer, ok := nextResolution(resolution); ok {
+		var watermark sql.NullTime
+		query := fmt.Sprintf("SELECT MAX(bucket_start) FROM %s", rollupTables[consumer])
+		if err := r.db.QueryRowContext(ctx, query).Scan(&watermark); err != nil {
+			return 0, err
+		}
+		if !watermark.Valid {
+			return 0, nil
+		}
+		if watermark.Time.Before(before) {
+			before = watermark.Time
+		}
+	}
+
+	var query string
+	if resolution == 0 {
+		query = `
+			DELETE FROM sensor_data
+			WHERE created_at < ?
+				AND NOT EXISTS (SELECT 1 FROM alerts WHERE alerts.sensor_id = sensor_data.id)
+			ORDER BY created_at
+			LIMIT ?
+		`
+	} else {
+		table, ok := rollupTables[resolution]
+		if !ok {
+			return 0, fmt.Errorf("resolución de resumen no soportada: %s", resolution)
+		}
+		query = fmt.Sprintf("DELETE FROM %s WHERE bucket_start < ? ORDER BY bucket_start LIMIT ?", table)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// rollupSource devuelve la tabla y la columna de tiempo a partir de la que se calcula una resolución
+func rollupSource(resolution time.Duration) (string, string) {
+	for i, res := range models.RollupResolutions {
+		if res == resolution && i > 0 {
+			return rollupTables[models.RollupResolutions[i-1]], "bucket_start"
+		}
+	}
+	return "sensor_data", "created_at"
+}
+
+// nextResolution devuelve la resolución que se calcula a partir de la indicada
+func nextResolution(resolution time.Duration) (time.Duration, bool) {
+	if resolution == 0 {
+		return models.RollupResolutions[0], true
+	}
+	for i, res := range models.RollupResolutions {
+		if res == resolution && i+1 < len(models.RollupResolutions) {
+			return models.RollupResolutions[i+1], true
+		}
+	}
+	return 0, false
+}
+
+// alignToBucket redondea hacia abajo al inicio del intervalo, con la misma alineación
+// (hora local) que usan las consultas de agregación
+func alignToBucket(t time.Time, bucket time.Duration) time.Time {
+	seconds := int64(bucket / time.Second)
+	return wallClockToLocal(wallClockSeconds(t) / seconds * seconds)
+}
+
+// alignUpToBucket devuelve el primer inicio de intervalo posterior o igual a t
+func alignUpToBucket(t time.Time, bucket time.Duration) time.Time {
+	aligned := alignToBucket(t, bucket)
+	if aligned.Before(t) {
+		seconds := int64(bucket / time.Second)
+		return wallClockToLocal(wallClockSeconds(aligned) + seconds)
+	}
+	return aligned
+}
+
+// wallClockSeconds es la operación inversa de wallClockToLocal
+func wallClockSeconds(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC).Unix()
+}
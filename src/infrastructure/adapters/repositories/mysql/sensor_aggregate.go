@@ -0,0 +1,241 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+)
+
+// weekEpochSeconds desplaza el origen de los intervalos semanales del jueves 1970-01-01 al
+// lunes 1970-01-05, para que las semanas empiecen en lunes
+const weekEpochSeconds = 4 * 24 * 60 * 60
+
+// sensorMetricColumns relaciona cada métrica con su columna en sensor_data
+var sensorMetricColumns = map[string]string{
+	models.MetricTemperaturaDHT: "temperatura_dht",
+	models.MetricLuz:            "luz",
+	models.MetricHumedad:        "humedad",
+	models.MetricHumo:           "humo",
+}
+
+// AggregateSensorData agrupa las lecturas en intervalos de tiempo dentro de MySQL y
+// devuelve una serie por métrica. Los intervalos se alinean a la hora local, de modo
+// que los intervalos diarios comienzan a medianoche y los semanales, el lunes a
+// medianoche. Si hay una tabla de resumen adecuada, la parte del rango alineada a su
+// resolución se consulta en la más gruesa y solo los extremos en las lecturas.
+func (r *SensorRepository) AggregateSensorData(ctx context.Context, opts models.SensorAggregateQuery) ([]models.MetricSeries, error) {
+	bucketSeconds := int64(opts.Bucket / time.Second)
+
+	var epoch int64
+	if opts.Bucket%(7*24*time.Hour) == 0 {
+		epoch = weekEpochSeconds
+	}
+
+	segments, err := r.aggregateSegments(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cada tramo se agrega por separado con sumas, mínimos y máximos parciales, que la
+	// consulta exterior combina por intervalo. Las columnas proceden de una lista cerrada,
+	// nunca de la entrada del usuario.
+	var parts []string
+	var args []interface{}
+	for _, segment := range segments {
+		timeColumn := "bucket_start"
+		selects := []string{"SUM(sample_count) AS n"}
+		if segment.table == "sensor_data" {
+			timeColumn = "created_at"
+			selects = []string{"COUNT(*) AS n"}
+		}
+		for _, metric := range opts.Metrics {
+			column := sensorMetricColumns[metric]
+			if segment.table == "sensor_data" {
+				selects = append(selects, fmt.Sprintf("SUM(%s) AS %s_sum, MIN(%s) AS %s_min, MAX(%s) AS %s_max", column, column, column, column, column, column))
+			} else {
+				selects = append(selects, fmt.Sprintf("SUM(%s_sum) AS %s_sum, MIN(%s_min) AS %s_min, MAX(%s_max) AS %s_max", column, column, column, column, column, column))
+			}
+		}
+
+		part := fmt.Sprintf(
+			"SELECT (TIMESTAMPDIFF(SECOND, '1970-01-01 00:00:00', %s) - ?) DIV ? AS bucket, %s FROM %s WHERE %s >= ? AND %s < ?",
+			timeColumn, strings.Join(selects, ", "), segment.table, timeColumn, timeColumn,
+		)
+		args = append(args, epoch, bucketSeconds, segment.from, segment.to)
+
+		if opts.DeviceID != nil {
+			part += " AND device_id = ?"
+			args = append(args, *opts.DeviceID)
+		}
+
+		parts = append(parts, part+" GROUP BY bucket")
+	}
+
+	selects := []string{"SUM(n)"}
+	for _, metric := range opts.Metrics {
+		column := sensorMetricColumns[metric]
+		selects = append(selects, fmt.Sprintf("SUM(%s_sum) / SUM(n), MIN(%s_min), MAX(%s_max)", column, column, column))
+	}
+	query := fmt.Sprintf(
+		"SELECT bucket, %s FROM (%s) AS segments GROUP BY bucket ORDER BY bucket",
+		strings.Join(selects, ", "), strings.Join(parts, " UNION ALL "),
+	)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := make([]models.MetricSeries, len(opts.Metrics))
+	for i, metric := range opts.Metrics {
+		series[i] = models.MetricSeries{Metric: metric, Points: []models.AggregatePoint{}}
+	}
+
+	for rows.Next() {
+		var bucket, count int64
+		values := make([]sql.NullFloat64, 3*len(opts.Metrics))
+
+		dest := []interface{}{&bucket, &count}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		bucketStart := wallClockToLocal(epoch + bucket*bucketSeconds)
+		for i := range opts.Metrics {
+			series[i].Points = append(series[i].Points, aggregatePoint(opts, bucketStart, count, values[3*i:3*i+3]))
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// aggregateSegment es un tramo [from, to) del rango consultado y la tabla que lo responde
+type aggregateSegment struct {
+	table    string
+	from, to time.Time
+}
+
+// dataSpan es el primer y el último intervalo con datos de una tabla de resumen
+type dataSpan struct {
+	first, last sql.NullTime
+}
+
+// aggregateSegments consulta qué datos hay en las lecturas y en cada resumen y reparte el
+// rango entre ellos (ver planAggregate)
+func (r *SensorRepository) aggregateSegments(ctx context.Context, opts models.SensorAggregateQuery) ([]aggregateSegment, error) {
+	var oldestRaw sql.NullTime
+	if err := r.db.QueryRowContext(ctx, "SELECT MIN(created_at) FROM sensor_data").Scan(&oldestRaw); err != nil {
+		return nil, err
+	}
+
+	rollups := make(map[time.Duration]dataSpan, len(rollupTables))
+	for resolution, table := range rollupTables {
+		var span dataSpan
+		query := fmt.Sprintf("SELECT MIN(bucket_start), MAX(bucket_start) FROM %s", table)
+		if err := r.db.QueryRowContext(ctx, query).Scan(&span.first, &span.last); err != nil {
+			return nil, err
+		}
+		rollups[resolution] = span
+	}
+
+	return planAggregate(opts, oldestRaw, rollups)
+}
+
+// planAggregate reparte el rango consultado entre las lecturas y la tabla de resumen más
+// gruesa cuya resolución divide el intervalo y que tiene datos desde el inicio de la parte
+// del rango alineada a esa resolución. Esa parte, hasta donde llega el trabajador de
+// resúmenes, se responde con el resumen y el resto, con las lecturas; si ningún resumen
+// sirve, todo el rango se responde con las lecturas.
+//
+// Si algún tramo de lecturas empieza antes de la lectura más antigua y hay resúmenes de
+// antes de esta, esas lecturas se eliminaron por la retención: devuelve
+// models.ErrSensorDataPurged en lugar de un resultado incompleto.
+func planAggregate(opts models.SensorAggregateQuery, oldestRaw sql.NullTime, rollups map[time.Duration]dataSpan) ([]aggregateSegment, error) {
+	segments := []aggregateSegment{{table: "sensor_data", from: opts.From, to: opts.To}}
+	for i := len(models.RollupResolutions) - 1; i >= 0; i-- {
+		resolution := models.RollupResolutions[i]
+		if opts.Bucket%resolution != 0 {
+			continue
+		}
+
+		span := rollups[resolution]
+		if !span.first.Valid {
+			continue
+		}
+
+		// Lo posterior al último intervalo resumido aún no ha pasado por el trabajador de
+		// resúmenes, pero sus lecturas no se eliminan hasta entonces
+		start, stop := alignUpToBucket(opts.From, resolution), alignToBucket(opts.To, resolution)
+		if watermark := span.last.Time.Add(resolution); watermark.Before(stop) {
+			stop = watermark
+		}
+		if span.first.Time.After(start) || !start.Before(stop) {
+			continue
+		}
+
+		segments = []aggregateSegment{{table: rollupTables[resolution], from: start, to: stop}}
+		if opts.From.Before(start) {
+			segments = append(segments, aggregateSegment{table: "sensor_data", from: opts.From, to: start})
+		}
+		if stop.Before(opts.To) {
+			segments = append(segments, aggregateSegment{table: "sensor_data", from: stop, to: opts.To})
+		}
+		break
+	}
+
+	// Los resúmenes se calculan a partir de las lecturas: si alguno termina antes de la
+	// lectura más antigua, las lecturas anteriores a esta se eliminaron
+	purged := false
+	for resolution, span := range rollups {
+		if span.first.Valid && (!oldestRaw.Valid || !span.first.Time.Add(resolution).After(oldestRaw.Time)) {
+			purged = true
+		}
+	}
+	for _, segment := range segments {
+		if purged && segment.table == "sensor_data" && (!oldestRaw.Valid || segment.from.Before(oldestRaw.Time)) {
+			return nil, models.ErrSensorDataPurged
+		}
+	}
+
+	return segments, nil
+}
+
+// aggregatePoint construye el punto de una métrica con las funciones solicitadas
+func aggregatePoint(opts models.SensorAggregateQuery, bucketStart time.Time, count int64, values []sql.NullFloat64) models.AggregatePoint {
+	point := models.AggregatePoint{Time: bucketStart}
+
+	if opts.HasFunction(models.AggregateAvg) {
+		point.Avg = &values[0].Float64
+	}
+	if opts.HasFunction(models.AggregateMin) {
+		point.Min = &values[1].Float64
+	}
+	if opts.HasFunction(models.AggregateMax) {
+		point.Max = &values[2].Float64
+	}
+	if opts.HasFunction(models.AggregateCount) {
+		point.Count = &count
+	}
+
+	return point
+}
+
+// wallClockToLocal convierte segundos desde 1970-01-01 00:00:00 (sin zona horaria, como
+// los guarda la columna DATETIME) en un instante de la zona horaria local
+func wallClockToLocal(seconds int64) time.Time {
+	wall := time.Unix(seconds, 0).UTC()
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, time.Local)
+}
@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/domain/models"
+)
+
+func at(month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(2024, month, day, hour, minute, second, 0, time.Local)
+}
+
+func validTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// purgedRollups son resúmenes desde enero con las lecturas conservadas solo desde el 10 de marzo
+func purgedRollups() (sql.NullTime, map[time.Duration]dataSpan) {
+	return validTime(at(time.March, 10, 0, 0, 0)), map[time.Duration]dataSpan{
+		models.RollupMinute: {first: validTime(at(time.January, 1, 0, 0, 0)), last: validTime(at(time.March, 20, 12, 0, 0))},
+		models.RollupHour:   {first: validTime(at(time.January, 1, 0, 0, 0)), last: validTime(at(time.March, 20, 11, 0, 0))},
+		models.RollupDay:    {first: validTime(at(time.January, 1, 0, 0, 0)), last: validTime(at(time.March, 19, 0, 0, 0))},
+	}
+}
+
+func TestPlanAggregateRejectsUnalignedRangeOlderThanRawRetention(t *testing.T) {
+	oldestRaw, rollups := purgedRollups()
+	opts := models.SensorAggregateQuery{Bucket: time.Hour, From: at(time.February, 1, 10, 30, 0), To: at(time.February, 2, 10, 30, 0)}
+
+	_, err := planAggregate(opts, oldestRaw, rollups)
+	if !errors.Is(err, models.ErrSensorDataPurged) {
+		t.Fatalf("err = %v, se esperaba ErrSensorDataPurged", err)
+	}
+}
+
+func TestPlanAggregateUsesRollupForAlignedRangeOlderThanRawRetention(t *testing.T) {
+	oldestRaw, rollups := purgedRollups()
+	opts := models.SensorAggregateQuery{Bucket: time.Hour, From: at(time.February, 1, 10, 0, 0), To: at(time.February, 2, 10, 0, 0)}
+
+	segments, err := planAggregate(opts, oldestRaw, rollups)
+	if err != nil {
+		t.Fatalf("planAggregate: %v", err)
+	}
+	want := []aggregateSegment{{table: "sensor_data_1h", from: opts.From, to: opts.To}}
+	assertSegments(t, segments, want)
+}
+
+func TestPlanAggregateSplitsUnalignedRange(t *testing.T) {
+	oldestRaw, rollups := purgedRollups()
+	opts := models.SensorAggregateQuery{Bucket: time.Hour, From: at(time.March, 15, 10, 30, 0), To: at(time.March, 16, 10, 30, 0)}
+
+	segments, err := planAggregate(opts, oldestRaw, rollups)
+	if err != nil {
+		t.Fatalf("planAggregate: %v", err)
+	}
+	want := []aggregateSegment{
+		{table: "sensor_data_1h", from: at(time.March, 15, 11, 0, 0), to: at(time.March, 16, 10, 0, 0)},
+		{table: "sensor_data", from: opts.From, to: at(time.March, 15, 11, 0, 0)},
+		{table: "sensor_data", from: at(time.March, 16, 10, 0, 0), to: opts.To},
+	}
+	assertSegments(t, segments, want)
+}
+
+func TestPlanAggregateReadsRawPastTheRollupWatermark(t *testing.T) {
+	oldestRaw, rollups := purgedRollups()
+	opts := models.SensorAggregateQuery{Bucket: time.Hour, From: at(time.March, 20, 0, 0, 0), To: at(time.March, 21, 0, 0, 0)}
+
+	segments, err := planAggregate(opts, oldestRaw, rollups)
+	if err != nil {
+		t.Fatalf("planAggregate: %v", err)
+	}
+	want := []aggregateSegment{
+		{table: "sensor_data_1h", from: opts.From, to: at(time.March, 20, 12, 0, 0)},
+		{table: "sensor_data", from: at(time.March, 20, 12, 0, 0), to: opts.To},
+	}
+	assertSegments(t, segments, want)
+}
+
+func TestPlanAggregateDoesNotReportPurgeBeforeFirstReading(t *testing.T) {
+	// Instalación reciente: el primer resumen es el del minuto de la primera lectura
+	oldestRaw := validTime(at(time.March, 10, 10, 0, 30))
+	rollups := map[time.Duration]dataSpan{
+		models.RollupMinute: {first: validTime(at(time.March, 10, 10, 0, 0)), last: validTime(at(time.March, 10, 10, 30, 0))},
+	}
+	opts := models.SensorAggregateQuery{Bucket: time.Minute, From: at(time.March, 10, 9, 0, 0), To: at(time.March, 10, 11, 0, 0)}
+
+	segments, err := planAggregate(opts, oldestRaw, rollups)
+	if err != nil {
+		t.Fatalf("planAggregate: %v", err)
+	}
+	want := []aggregateSegment{{table: "sensor_data", from: opts.From, to: opts.To}}
+	assertSegments(t, segments, want)
+}
+
+func assertSegments(t *testing.T, got, want []aggregateSegment) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("tramos = %v, se esperaba %v", got, want)
+	}
+	for i := range want {
+		if got[i].table != want[i].table || !got[i].from.Equal(want[i].from) || !got[i].to.Equal(want[i].to) {
+			t.Errorf("tramo %d = %v, se esperaba %v", i, got[i], want[i])
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"ApiSmart/src/core/application"
@@ -25,16 +26,17 @@ func NewSensorRepository(db *sql.DB) application.SensorRepository {
 // SaveSensorData guarda datos de un sensor en la base de datos
 func (r *SensorRepository) SaveSensorData(ctx context.Context, data *models.SensorData) error {
 	query := `
-		INSERT INTO sensor_data (temperatura_dht, luz, humedad, humo, created_at) 
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO sensor_data (device_id, temperatura_dht, luz, humedad, humo, created_at) 
+		VALUES (?, ?, ?, ?, ?, ?)
 	`
 
 	now := time.Now()
 	data.CreatedAt = now
 
-	result, err := r.db.ExecContext(
+	result, err := executor(ctx, r.db).ExecContext(
 		ctx,
 		query,
+		data.DeviceID,
 		data.TemperaturaDHT,
 		data.Luz,
 		data.Humedad,
@@ -55,74 +57,108 @@ func (r *SensorRepository) SaveSensorData(ctx context.Context, data *models.Sens
 	return nil
 }
 
-// GetAllSensorData obtiene todos los datos de sensores
-func (r *SensorRepository) GetAllSensorData(ctx context.Context) ([]models.SensorData, error) {
+// GetAllSensorData obtiene una página del histórico de lecturas usando paginación por
+// clave sobre (created_at, id), del más reciente al más antiguo
+func (r *SensorRepository) GetAllSensorData(ctx context.Context, opts models.SensorDataQuery) (*models.SensorDataPage, error) {
+	filter, args := sensorDataFilter(opts)
+
 	query := `
-		SELECT id, temperatura_dht, luz, humedad, humo, created_at 
+		SELECT id, device_id, temperatura_dht, luz, humedad, humo, created_at 
 		FROM sensor_data 
-		ORDER BY created_at DESC 
-		LIMIT 1000
-	`
+		WHERE 1=1
+	` + filter
 
-	rows, err := r.db.QueryContext(ctx, query)
+	// Continuar después de la última fila de la página anterior
+	if opts.Cursor != nil {
+		query += " AND (created_at < ? OR (created_at = ? AND id < ?))"
+		args = append(args, opts.Cursor.CreatedAt, opts.Cursor.CreatedAt, opts.Cursor.ID)
+	}
+
+	// Pedir una fila extra para saber si hay más páginas
+	limit := models.NormalizeLimit(opts.Limit)
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var sensorDataList []models.SensorData
+	page := &models.SensorDataPage{Data: []models.SensorData{}}
 
 	for rows.Next() {
-		var data models.SensorData
-		var createdAtStr string
-
-		err := rows.Scan(
-			&data.ID,
-			&data.TemperaturaDHT,
-			&data.Luz,
-			&data.Humedad,
-			&data.Humo,
-			&createdAtStr,
-		)
-
+		data, err := scanSensorData(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		data.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		sensorDataList = append(sensorDataList, data)
+		page.Data = append(page.Data, *data)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return sensorDataList, nil
+	if len(page.Data) > limit {
+		page.Data = page.Data[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return page, nil
 }
 
-// GetLatestSensorData obtiene los datos más recientes del sensor
-func (r *SensorRepository) GetLatestSensorData(ctx context.Context) (*models.SensorData, error) {
+// StreamSensorData recorre las lecturas del rango, de la más antigua a la más reciente,
+// llamando a fn por cada fila sin acumularlas en memoria. Ignora Limit y Cursor.
+func (r *SensorRepository) StreamSensorData(ctx context.Context, opts models.SensorDataQuery, fn func(models.SensorData) error) error {
+	filter, args := sensorDataFilter(opts)
+
 	query := `
-		SELECT id, temperatura_dht, luz, humedad, humo, created_at 
+		SELECT id, device_id, temperatura_dht, luz, humedad, humo, created_at 
 		FROM sensor_data 
-		ORDER BY created_at DESC 
-		LIMIT 1
-	`
+		WHERE 1=1
+	` + filter + " ORDER BY created_at, id"
 
-	row := r.db.QueryRowContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-	var data models.SensorData
-	var createdAtStr string
+	for rows.Next() {
+		data, err := scanSensorData(rows)
+		if err != nil {
+			return err
+		}
 
-	err := row.Scan(
-		&data.ID,
-		&data.TemperaturaDHT,
-		&data.Luz,
-		&data.Humedad,
-		&data.Humo,
-		&createdAtStr,
-	)
+		if err := fn(*data); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetLatestSensorData obtiene los datos más recientes del sensor, opcionalmente de un dispositivo
+func (r *SensorRepository) GetLatestSensorData(ctx context.Context, deviceID *uint) (*models.SensorData, error) {
+	var args []interface{}
+
+	query := `
+		SELECT id, device_id, temperatura_dht, luz, humedad, humo, created_at 
+		FROM sensor_data 
+		WHERE 1=1
+	`
+
+	// Filtrar por dispositivo si se especifica
+	if deviceID != nil {
+		query += " AND device_id = ?"
+		args = append(args, *deviceID)
+	}
 
+	query += " ORDER BY created_at DESC, id DESC LIMIT 1"
+
+	data, err := scanSensorData(r.db.QueryRowContext(ctx, query, args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, errors.New("no hay datos de sensores disponibles")
@@ -130,29 +166,83 @@ func (r *SensorRepository) GetLatestSensorData(ctx context.Context) (*models.Sen
 		return nil, err
 	}
 
-	data.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	return data, nil
+}
+
+// GetSensorDataWindow obtiene las lecturas de un dispositivo desde from hasta untilID, de la
+// más antigua a la más reciente, precedidas de la última lectura anterior o igual a from.
+// Participa en la transacción del contexto, de modo que incluye la lectura recién guardada.
+func (r *SensorRepository) GetSensorDataWindow(ctx context.Context, deviceID uint, from time.Time, untilID uint) ([]models.SensorData, error) {
+	exec := executor(ctx, r.db)
 
-	return &data, nil
+	window := []models.SensorData{}
+
+	anchor, err := scanSensorData(exec.QueryRowContext(ctx, `
+		SELECT id, device_id, temperatura_dht, luz, humedad, humo, created_at
+		FROM sensor_data
+		WHERE device_id = ? AND id <= ? AND created_at <= ?
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, deviceID, untilID, from))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if anchor != nil {
+		window = append(window, *anchor)
+	}
+
+	rows, err := exec.QueryContext(ctx, `
+		SELECT id, device_id, temperatura_dht, luz, humedad, humo, created_at
+		FROM sensor_data
+		WHERE device_id = ? AND id <= ? AND created_at > ?
+		ORDER BY created_at, id
+	`, deviceID, untilID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		data, err := scanSensorData(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		window = append(window, *data)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return window, nil
 }
 
 // SaveAlert guarda una alerta en la base de datos
 func (r *SensorRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
 	query := `
-		INSERT INTO alerts (sensor_id, sensor_type, value, message, is_read, created_at) 
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO alerts (device_id, sensor_id, sensor_type, value, message, rule_id, severity, status, occurrences, last_value, last_seen_at, created_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	now := time.Now()
 	alert.CreatedAt = now
+	alert.LastSeenAt = now
 
-	result, err := r.db.ExecContext(
+	result, err := executor(ctx, r.db).ExecContext(
 		ctx,
 		query,
+		alert.DeviceID,
 		alert.SensorID,
 		alert.SensorType,
 		alert.Value,
 		alert.Message,
-		alert.IsRead,
+		alert.RuleID,
+		alert.Severity,
+		alert.Status,
+		alert.Occurrences,
+		alert.LastValue,
+		alert.LastSeenAt,
 		now,
 	)
 
@@ -169,55 +259,91 @@ func (r *SensorRepository) SaveAlert(ctx context.Context, alert *models.Alert) e
 	return nil
 }
 
-// GetAlerts obtiene las alertas filtradas por estado
-func (r *SensorRepository) GetAlerts(ctx context.Context, isRead *bool) ([]models.Alert, error) {
-	var query string
-	var args []interface{}
+// RecordAlertOccurrence suma una ocurrencia a una alerta abierta y guarda el último valor
+func (r *SensorRepository) RecordAlertOccurrence(ctx context.Context, alertID uint, value float64, seenAt time.Time) error {
+	query := `
+		UPDATE alerts
+		SET occurrences = occurrences + 1, last_value = ?, last_seen_at = ?
+		WHERE id = ?
+	`
 
-	// Base query
-	query = `
-		SELECT id, sensor_id, sensor_type, value, message, is_read, created_at 
-		FROM alerts 
-		WHERE 1=1
+	_, err := executor(ctx, r.db).ExecContext(ctx, query, value, seenAt, alertID)
+	return err
+}
+
+// GetAlert busca una alerta por su ID, con el estado de lectura del usuario
+func (r *SensorRepository) GetAlert(ctx context.Context, alertID, userID uint) (*models.Alert, error) {
+	query := `SELECT ` + alertColumns + `, ` + alertReadColumn + ` FROM alerts WHERE id = ?`
+
+	var isRead bool
+	alert, err := scanAlert(executor(ctx, r.db).QueryRowContext(ctx, query, userID, alertID), &isRead)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrAlertNotFound
+		}
+		return nil, err
+	}
+
+	alert.IsRead = isRead
+	return alert, nil
+}
+
+// AcknowledgeAlert marca una alerta abierta como reconocida por el usuario. Devuelve
+// models.ErrInvalidAlertTransition si la alerta ya no estaba abierta.
+func (r *SensorRepository) AcknowledgeAlert(ctx context.Context, alertID, userID uint, acknowledgedAt time.Time) error {
+	query := `
+		UPDATE alerts
+		SET status = ?, acknowledged_by = ?, acknowledged_at = ?
+		WHERE id = ? AND status = ?
 	`
 
-	// Filtrar por estado de lectura si se especifica
-	if isRead != nil {
-		query += " AND is_read = ?"
-		args = append(args, *isRead)
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, models.AlertStatusAcknowledged, userID, acknowledgedAt, alertID, models.AlertStatusOpen)
+	if err != nil {
+		return err
 	}
 
-	// Ordenar por fecha de creación, más recientes primero
-	query += " ORDER BY created_at DESC"
+	return requireAffected(result, models.ErrInvalidAlertTransition)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+// ResolveAlert marca una alerta como resuelta por resolvedBy (nil si se resolvió sola).
+// Devuelve models.ErrInvalidAlertTransition si la alerta ya estaba resuelta.
+func (r *SensorRepository) ResolveAlert(ctx context.Context, alertID uint, resolvedBy *uint, resolvedAt time.Time) error {
+	query := `UPDATE alerts SET status = ?, resolved_by = ?, resolved_at = ? WHERE id = ? AND status <> ?`
+
+	result, err := executor(ctx, r.db).ExecContext(ctx, query, models.AlertStatusResolved, resolvedBy, resolvedAt, alertID, models.AlertStatusResolved)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrInvalidAlertTransition)
+}
+
+// FindAlertsToEscalate obtiene las alertas críticas abiertas desde antes de openedBefore
+// que aún no se han escalado, de la más antigua a la más reciente
+func (r *SensorRepository) FindAlertsToEscalate(ctx context.Context, openedBefore time.Time, limit int) ([]models.Alert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM alerts
+		WHERE status = ? AND severity = ? AND escalated_at IS NULL AND created_at <= ?
+		ORDER BY created_at, id
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, models.AlertStatusOpen, models.SeverityCritical, openedBefore, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var alerts []models.Alert
+	alerts := []models.Alert{}
 
 	for rows.Next() {
-		var alert models.Alert
-		var createdAtStr string
-
-		err := rows.Scan(
-			&alert.ID,
-			&alert.SensorID,
-			&alert.SensorType,
-			&alert.Value,
-			&alert.Message,
-			&alert.IsRead,
-			&createdAtStr,
-		)
-
+		alert, err := scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
 
-		alert.CreatedAt, _ = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		alerts = append(alerts, alert)
+		alerts = append(alerts, *alert)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -227,10 +353,333 @@ func (r *SensorRepository) GetAlerts(ctx context.Context, isRead *bool) ([]model
 	return alerts, nil
 }
 
-// MarkAlertAsRead marca una alerta como leída
-func (r *SensorRepository) MarkAlertAsRead(ctx context.Context, alertID uint) error {
-	query := `UPDATE alerts SET is_read = true WHERE id = ?`
+// SetAlertEscalated marca una alerta abierta como escalada en escalatedAt, o la desmarca
+// con nil. Al marcarla devuelve false si otra instancia ya lo hizo o dejó de estar abierta.
+func (r *SensorRepository) SetAlertEscalated(ctx context.Context, alertID uint, escalatedAt *time.Time) (bool, error) {
+	query := `UPDATE alerts SET escalated_at = NULL WHERE id = ?`
+	args := []interface{}{alertID}
+	if escalatedAt != nil {
+		query = `UPDATE alerts SET escalated_at = ? WHERE id = ? AND escalated_at IS NULL AND status = ?`
+		args = []interface{}{*escalatedAt, alertID, models.AlertStatusOpen}
+	}
 
-	_, err := r.db.ExecContext(ctx, query, alertID)
-	return err
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// GetAlerts obtiene una página de las alertas del filtro usando paginación por clave sobre
+// (created_at, id), de la más reciente a la más antigua, con el estado de lectura del usuario
+func (r *SensorRepository) GetAlerts(ctx context.Context, query models.AlertQuery) (*models.AlertPage, error) {
+	filter, filterArgs := alertFilterClause(query.AlertFilter)
+	args := append([]interface{}{query.UserID}, filterArgs...)
+
+	sqlQuery := `
+		SELECT ` + alertColumns + `, ` + alertReadColumn + ` 
+		FROM alerts 
+		WHERE 1=1
+	` + filter
+
+	// Continuar después de la última fila de la página anterior
+	if query.Cursor != nil {
+		sqlQuery += " AND (created_at < ? OR (created_at = ? AND id < ?))"
+		args = append(args, query.Cursor.CreatedAt, query.Cursor.CreatedAt, query.Cursor.ID)
+	}
+
+	// Pedir una fila extra para saber si hay más páginas
+	limit := models.NormalizeLimit(query.Limit)
+	sqlQuery += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.AlertPage{Data: []models.Alert{}}
+
+	for rows.Next() {
+		var isRead bool
+		alert, err := scanAlert(rows, &isRead)
+		if err != nil {
+			return nil, err
+		}
+
+		alert.IsRead = isRead
+		page.Data = append(page.Data, *alert)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Data) > limit {
+		page.Data = page.Data[:limit]
+		last := page.Data[limit-1]
+		page.NextCursor = models.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return page, nil
+}
+
+// StreamAlerts recorre las alertas que cumplen el filtro, de la más antigua a la más
+// reciente, llamando a fn por cada fila sin acumularlas en memoria
+func (r *SensorRepository) StreamAlerts(ctx context.Context, filter models.AlertFilter, fn func(models.Alert) error) error {
+	clause, filterArgs := alertFilterClause(filter)
+	args := append([]interface{}{filter.UserID}, filterArgs...)
+
+	query := `
+		SELECT ` + alertColumns + `, ` + alertReadColumn + ` 
+		FROM alerts 
+		WHERE 1=1
+	` + clause + " ORDER BY created_at, id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var isRead bool
+		alert, err := scanAlert(rows, &isRead)
+		if err != nil {
+			return err
+		}
+
+		alert.IsRead = isRead
+		if err := fn(*alert); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountAlerts cuenta las alertas del filtro agrupadas por tipo de sensor, por día o por ambos
+func (r *SensorRepository) CountAlerts(ctx context.Context, query models.AlertCountQuery) ([]models.AlertCount, error) {
+	filter, args := alertFilterClause(query.AlertFilter)
+
+	// Las columnas por las que no se agrupa se devuelven vacías
+	typeColumn, dayColumn := "''", "''"
+	var groups []string
+	if query.ByType {
+		typeColumn = "sensor_type"
+		groups = append(groups, "sensor_type")
+	}
+	if query.ByDay {
+		dayColumn = "DATE_FORMAT(created_at, '%Y-%m-%d')"
+		groups = append(groups, "DATE_FORMAT(created_at, '%Y-%m-%d')")
+	}
+
+	sqlQuery := `
+		SELECT ` + typeColumn + `, ` + dayColumn + `, COUNT(*)
+		FROM alerts
+		WHERE 1=1
+	` + filter
+
+	if len(groups) > 0 {
+		sqlQuery += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.AlertCount{}
+
+	for rows.Next() {
+		var count models.AlertCount
+		if err := rows.Scan(&count.SensorType, &count.Day, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// DeleteAlerts elimina las alertas seleccionadas y devuelve cuántas se eliminaron
+func (r *SensorRepository) DeleteAlerts(ctx context.Context, selection models.AlertSelection) (int64, error) {
+	clause, args := alertSelectionClause(selection)
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM alerts WHERE 1=1`+clause, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// alertFilterClause construye las condiciones SQL comunes de un filtro de alertas
+func alertFilterClause(filter models.AlertFilter) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+
+	if filter.IsRead != nil {
+		clause += " AND " + alertReadColumn + " = ?"
+		args = append(args, filter.UserID, *filter.IsRead)
+	}
+
+	if filter.DeviceID != nil {
+		clause += " AND device_id = ?"
+		args = append(args, *filter.DeviceID)
+	}
+
+	if len(filter.SensorTypes) > 0 {
+		clause += " AND sensor_type IN (?" + strings.Repeat(", ?", len(filter.SensorTypes)-1) + ")"
+		for _, sensorType := range filter.SensorTypes {
+			args = append(args, sensorType)
+		}
+	}
+
+	if len(filter.Severities) > 0 {
+		clause += " AND severity IN (?" + strings.Repeat(", ?", len(filter.Severities)-1) + ")"
+		for _, severity := range filter.Severities {
+			args = append(args, severity)
+		}
+	}
+
+	if filter.From != nil {
+		clause += " AND created_at >= ?"
+		args = append(args, *filter.From)
+	}
+
+	if filter.To != nil {
+		clause += " AND created_at < ?"
+		args = append(args, *filter.To)
+	}
+
+	return clause, args
+}
+
+// alertSelectionClause construye las condiciones SQL de una selección de alertas por IDs o por filtro
+func alertSelectionClause(selection models.AlertSelection) (string, []interface{}) {
+	if selection.Filter != nil {
+		return alertFilterClause(*selection.Filter)
+	}
+
+	// Una lista vacía no selecciona ninguna alerta
+	if len(selection.IDs) == 0 {
+		return " AND 1=0", nil
+	}
+
+	args := make([]interface{}, len(selection.IDs))
+	for i, id := range selection.IDs {
+		args[i] = id
+	}
+
+	return " AND alerts.id IN (?" + strings.Repeat(", ?", len(selection.IDs)-1) + ")", args
+}
+
+// alertColumns son las columnas que lee scanAlert. Las alertas anteriores al seguimiento de
+// ocurrencias no tienen último valor ni última lectura, y toman los de su creación.
+const alertColumns = `id, device_id, sensor_id, sensor_type, value, message, rule_id, severity, status, occurrences,
+	COALESCE(last_value, value), COALESCE(last_seen_at, created_at), acknowledged_at, acknowledged_by,
+	resolved_at, resolved_by, escalated_at, created_at`
+
+// alertReadColumn indica si el usuario indicado en su parámetro ha leído la alerta
+const alertReadColumn = `EXISTS (SELECT 1 FROM alert_reads WHERE alert_reads.alert_id = alerts.id AND alert_reads.user_id = ?)`
+
+// scanAlert lee una alerta desde una fila con alertColumns, seguidas de las columnas de extra
+func scanAlert(row rowScanner, extra ...interface{}) (*models.Alert, error) {
+	var alert models.Alert
+	var deviceID, ruleID, acknowledgedBy, resolvedBy sql.NullInt64
+	var acknowledgedAt, resolvedAt, escalatedAt sql.NullTime
+
+	dest := []interface{}{
+		&alert.ID,
+		&deviceID,
+		&alert.SensorID,
+		&alert.SensorType,
+		&alert.Value,
+		&alert.Message,
+		&ruleID,
+		&alert.Severity,
+		&alert.Status,
+		&alert.Occurrences,
+		&alert.LastValue,
+		&alert.LastSeenAt,
+		&acknowledgedAt,
+		&acknowledgedBy,
+		&resolvedAt,
+		&resolvedBy,
+		&escalatedAt,
+		&alert.CreatedAt,
+	}
+
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	alert.DeviceID = uint(deviceID.Int64)
+	alert.RuleID = nullUint(ruleID)
+	alert.AcknowledgedBy = nullUint(acknowledgedBy)
+	alert.AcknowledgedAt = nullTime(acknowledgedAt)
+	alert.ResolvedBy = nullUint(resolvedBy)
+	alert.ResolvedAt = nullTime(resolvedAt)
+	alert.EscalatedAt = nullTime(escalatedAt)
+
+	return &alert, nil
+}
+
+// scanSensorData lee una lectura desde una fila
+func scanSensorData(row rowScanner) (*models.SensorData, error) {
+	var data models.SensorData
+	var deviceID sql.NullInt64
+
+	err := row.Scan(
+		&data.ID,
+		&deviceID,
+		&data.TemperaturaDHT,
+		&data.Luz,
+		&data.Humedad,
+		&data.Humo,
+		&data.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	data.DeviceID = uint(deviceID.Int64)
+	return &data, nil
+}
+
+// sensorDataFilter construye las condiciones de dispositivo y rango de fechas de una consulta
+func sensorDataFilter(opts models.SensorDataQuery) (string, []interface{}) {
+	var filter string
+	var args []interface{}
+
+	if opts.DeviceID != nil {
+		filter += " AND device_id = ?"
+		args = append(args, *opts.DeviceID)
+	}
+
+	if opts.From != nil {
+		filter += " AND created_at >= ?"
+		args = append(args, *opts.From)
+	}
+
+	if opts.To != nil {
+		filter += " AND created_at < ?"
+		args = append(args, *opts.To)
+	}
+
+	return filter, args
 }
@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/models"
+)
+
+// globalThresholdsScope es el valor de device_id con el que se guardan los umbrales globales
+const globalThresholdsScope = 0
+
+// ThresholdRepository implementa application.ThresholdRepository
+type ThresholdRepository struct {
+	db *sql.DB
+}
+
+// NewThresholdRepository crea una nueva instancia de ThresholdRepository
+func NewThresholdRepository(db *sql.DB) application.ThresholdRepository {
+	return &ThresholdRepository{
+		db: db,
+	}
+}
+
+// Get obtiene los umbrales de un dispositivo o los globales si deviceID es nil
+func (r *ThresholdRepository) Get(ctx context.Context, deviceID *uint) (*models.ThresholdSettings, error) {
+	query := `
+		SELECT temperatura_max, temperatura_min, luz_max, luz_min, humedad_max, humedad_min, humo_max, updated_at
+		FROM alert_thresholds
+		WHERE device_id = ?
+	`
+
+	row := r.db.QueryRowContext(ctx, query, thresholdScope(deviceID))
+
+	settings := models.ThresholdSettings{DeviceID: deviceID}
+	err := row.Scan(
+		&settings.TemperaturaMax,
+		&settings.TemperaturaMin,
+		&settings.LuzMax,
+		&settings.LuzMin,
+		&settings.HumedadMax,
+		&settings.HumedadMin,
+		&settings.HumoMax,
+		&settings.UpdatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrThresholdsNotFound
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// Save crea o reemplaza los umbrales del ámbito
+func (r *ThresholdRepository) Save(ctx context.Context, settings *models.ThresholdSettings) error {
+	query := `
+		INSERT INTO alert_thresholds
+			(device_id, temperatura_max, temperatura_min, luz_max, luz_min, humedad_max, humedad_min, humo_max, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			temperatura_max = VALUES(temperatura_max),
+			temperatura_min = VALUES(temperatura_min),
+			luz_max = VALUES(luz_max),
+			luz_min = VALUES(luz_min),
+			humedad_max = VALUES(humedad_max),
+			humedad_min = VALUES(humedad_min),
+			humo_max = VALUES(humo_max),
+			updated_at = VALUES(updated_at)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		thresholdScope(settings.DeviceID),
+		settings.TemperaturaMax,
+		settings.TemperaturaMin,
+		settings.LuzMax,
+		settings.LuzMin,
+		settings.HumedadMax,
+		settings.HumedadMin,
+		settings.HumoMax,
+		settings.UpdatedAt,
+	)
+
+	return err
+}
+
+// Delete elimina los umbrales propios de un dispositivo
+func (r *ThresholdRepository) Delete(ctx context.Context, deviceID uint) error {
+	query := `DELETE FROM alert_thresholds WHERE device_id = ?`
+
+	result, err := r.db.ExecContext(ctx, query, deviceID)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrThresholdsNotFound)
+}
+
+// thresholdScope traduce el ámbito al valor almacenado en device_id
+func thresholdScope(deviceID *uint) uint {
+	if deviceID == nil {
+		return globalThresholdsScope
+	}
+	return *deviceID
+}
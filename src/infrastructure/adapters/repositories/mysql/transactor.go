@@ -0,0 +1,58 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"ApiSmart/src/core/application"
+)
+
+// txKey es la clave con la que se guarda la transacción en curso en el contexto
+type txKey struct{}
+
+// dbExecutor es la parte común de *sql.DB y *sql.Tx que usan los repositorios
+type dbExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Transactor implementa application.Transactor
+type Transactor struct {
+	db *sql.DB
+}
+
+// NewTransactor crea una nueva instancia de Transactor
+func NewTransactor(db *sql.DB) application.Transactor {
+	return &Transactor{
+		db: db,
+	}
+}
+
+// WithinTransaction ejecuta fn en una transacción y la confirma si no hay error. Si el
+// contexto ya tiene una transacción, fn se ejecuta dentro de ella.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// executor devuelve la transacción del contexto o, si no hay ninguna, la conexión
+func executor(ctx context.Context, db *sql.DB) dbExecutor {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,449 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ApiSmart/src/core/application"
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// WebhookRepository implementa application.WebhookRepository
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+// NewWebhookRepository crea una nueva instancia de WebhookRepository
+func NewWebhookRepository(db *sql.DB) application.WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+const webhookColumns = `id, user_id, url, event_types, secret, active, consecutive_failures, disabled_at, created_at, updated_at`
+
+// Create guarda un nuevo webhook en la base de datos
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, url, event_types, secret, active, consecutive_failures, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?)
+	`
+
+	eventTypes, err := json.Marshal(webhook.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		webhook.UserID,
+		webhook.URL,
+		eventTypes,
+		webhook.Secret,
+		webhook.Active,
+		webhook.CreatedAt,
+		webhook.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	webhook.ID = uint(id)
+	return nil
+}
+
+// FindByID busca un webhook por su ID
+func (r *WebhookRepository) FindByID(ctx context.Context, id uint) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?`
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// FindByUser obtiene los webhooks de un usuario
+func (r *WebhookRepository) FindByUser(ctx context.Context, userID uint) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = ? ORDER BY id`
+	return r.queryWebhooks(ctx, query, userID)
+}
+
+// FindActiveByEventType obtiene los webhooks activos suscritos a un tipo de evento
+func (r *WebhookRepository) FindActiveByEventType(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE active = TRUE AND JSON_CONTAINS(event_types, JSON_QUOTE(?))
+		ORDER BY id
+	`
+	return r.queryWebhooks(ctx, query, eventType)
+}
+
+// Update actualiza la URL, los tipos de evento y el estado de un webhook
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = ?, event_types = ?, active = ?, consecutive_failures = ?, disabled_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	eventTypes, err := json.Marshal(webhook.EventTypes)
+	if err != nil {
+		return err
+	}
+
+	webhook.UpdatedAt = time.Now()
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		webhook.URL,
+		eventTypes,
+		webhook.Active,
+		webhook.ConsecutiveFailures,
+		webhook.DisabledAt,
+		webhook.UpdatedAt,
+		webhook.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrWebhookNotFound)
+}
+
+// Delete elimina un webhook; su historial de entregas se elimina en cascada
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, models.ErrWebhookNotFound)
+}
+
+// RecordDelivery guarda un intento de entrega en el historial
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, attempt, status_code, success, error, duration_ms, delivered_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		delivery.WebhookID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Attempt,
+		delivery.StatusCode,
+		delivery.Success,
+		nullString(delivery.Error),
+		delivery.DurationMs,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	delivery.ID = uint64(id)
+	return nil
+}
+
+// ListDeliveries obtiene los últimos intentos de entrega de un webhook, del más reciente al más antiguo
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT id, webhook_id, event_id, event_type, attempt, status_code, success, error, duration_ms, delivered_at
+		FROM webhook_deliveries
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		var statusCode sql.NullInt64
+		var deliveryError sql.NullString
+
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Attempt,
+			&statusCode,
+			&delivery.Success,
+			&deliveryError,
+			&delivery.DurationMs,
+			&delivery.DeliveredAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			delivery.StatusCode = &code
+		}
+		delivery.Error = deliveryError.String
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// RecordSuccess pone a cero los fallos consecutivos del webhook
+func (r *WebhookRepository) RecordSuccess(ctx context.Context, id uint) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE webhooks SET consecutive_failures = 0 WHERE id = ?`, id)
+	return err
+}
+
+// RecordFailure suma un fallo consecutivo y deshabilita el webhook al llegar a maxFailures
+func (r *WebhookRepository) RecordFailure(ctx context.Context, id uint, maxFailures int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Bloquear la fila: varias entregas del mismo webhook pueden fallar a la vez
+	var active bool
+	var failures int
+	err = tx.QueryRowContext(ctx, `SELECT active, consecutive_failures FROM webhooks WHERE id = ? FOR UPDATE`, id).
+		Scan(&active, &failures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, models.ErrWebhookNotFound
+		}
+		return false, err
+	}
+
+	failures++
+	disable := active && failures >= maxFailures
+
+	query := `UPDATE webhooks SET consecutive_failures = ? WHERE id = ?`
+	args := []interface{}{failures, id}
+	if disable {
+		query = `UPDATE webhooks SET consecutive_failures = ?, active = FALSE, disabled_at = ? WHERE id = ?`
+		args = []interface{}{failures, time.Now(), id}
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return false, err
+	}
+
+	return disable, tx.Commit()
+}
+
+// ScheduleRetry guarda una entrega pendiente de reintentar. Si el evento ya tenía un
+// reintento pendiente para el webhook (por ejemplo, porque el broker lo reentregó) no hace nada.
+func (r *WebhookRepository) ScheduleRetry(ctx context.Context, retry *models.WebhookRetry) error {
+	event, err := json.Marshal(retry.Event)
+	if err != nil {
+		return fmt.Errorf("error serializando evento: %w", err)
+	}
+
+	query := `
+		INSERT IGNORE INTO webhook_retries (webhook_id, event_id, event, attempt, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, retry.WebhookID, retry.Event.ID, event, retry.Attempt, retry.NextAttemptAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	retry.ID = uint64(id)
+	return nil
+}
+
+// ClaimDueRetries obtiene hasta limit reintentos vencidos en now y los aplaza lease. Cada
+// reintento se reclama solo si nadie lo ha aplazado desde que se leyó.
+func (r *WebhookRepository) ClaimDueRetries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookRetry, error) {
+	query := `
+		SELECT id, webhook_id, event, attempt, next_attempt_at
+		FROM webhook_retries
+		WHERE next_attempt_at <= ?
+		ORDER BY next_attempt_at
+		LIMIT ?
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	due := []models.WebhookRetry{}
+	payloads := [][]byte{}
+
+	for rows.Next() {
+		var retry models.WebhookRetry
+		var payload []byte
+
+		if err := rows.Scan(&retry.ID, &retry.WebhookID, &payload, &retry.Attempt, &retry.NextAttemptAt); err != nil {
+			return nil, err
+		}
+
+		due = append(due, retry)
+		payloads = append(payloads, payload)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	claimed := []models.WebhookRetry{}
+	leaseUntil := now.Add(lease)
+
+	for i := range due {
+		result, err := r.db.ExecContext(
+			ctx,
+			`UPDATE webhook_retries SET next_attempt_at = ? WHERE id = ? AND next_attempt_at = ?`,
+			leaseUntil,
+			due[i].ID,
+			due[i].NextAttemptAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			continue
+		}
+
+		var event events.Event
+		if err := json.Unmarshal(payloads[i], &event); err != nil {
+			return nil, fmt.Errorf("error decodificando el reintento %d: %w", due[i].ID, err)
+		}
+
+		due[i].Event = event
+		due[i].NextAttemptAt = leaseUntil
+		claimed = append(claimed, due[i])
+	}
+
+	return claimed, nil
+}
+
+// RescheduleRetry fija el número y la fecha del siguiente intento de un reintento
+func (r *WebhookRepository) RescheduleRetry(ctx context.Context, id uint64, attempt int, nextAttemptAt time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE webhook_retries SET attempt = ?, next_attempt_at = ? WHERE id = ?`,
+		attempt,
+		nextAttemptAt,
+		id,
+	)
+	return err
+}
+
+// DeleteRetry elimina un reintento entregado o agotado
+func (r *WebhookRepository) DeleteRetry(ctx context.Context, id uint64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM webhook_retries WHERE id = ?`, id)
+	return err
+}
+
+// queryWebhooks ejecuta una consulta que devuelve webhookColumns
+func (r *WebhookRepository) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, *webhook)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// scanWebhook lee un webhook desde una fila con webhookColumns
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var webhook models.Webhook
+	var eventTypes []byte
+	var disabledAt sql.NullTime
+
+	err := row.Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&eventTypes,
+		&webhook.Secret,
+		&webhook.Active,
+		&webhook.ConsecutiveFailures,
+		&disabledAt,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(eventTypes, &webhook.EventTypes); err != nil {
+		return nil, err
+	}
+	if disabledAt.Valid {
+		webhook.DisabledAt = &disabledAt.Time
+	}
+
+	return &webhook, nil
+}
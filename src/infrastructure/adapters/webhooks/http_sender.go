@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+// maxResponseBytes limita lo que se lee de la respuesta del receptor, que solo se descarta
+const maxResponseBytes = 64 << 10
+
+// HTTPSender implementa application.WebhookSender con un POST JSON firmado.
+// La cabecera X-Webhook-Signature lleva "sha256=" seguido del HMAC-SHA256 hexadecimal de
+// "<X-Webhook-Timestamp>.<cuerpo>" con la clave del webhook, el mismo esquema con el que
+// firman los dispositivos. Cualquier respuesta 2xx se considera aceptada.
+//
+// Las URLs las registran los usuarios, así que salvo con allowPrivateTargets no se conecta
+// a direcciones de loopback, privadas, de enlace local ni del resto de rangos de propósito
+// especial (ver forbiddenPrefixes). La comprobación se hace al abrir cada conexión, con la
+// IP ya resuelta, y cubre también las redirecciones.
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender crea una nueva instancia de HTTPSender
+func NewHTTPSender(timeout time.Duration, allowPrivateTargets bool) *HTTPSender {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateTargets {
+		dialer.Control = rejectPrivateTargets
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Un proxy abriría la conexión final por su cuenta, saltándose la comprobación
+	transport.Proxy = nil
+
+	return &HTTPSender{
+		client: &http.Client{Timeout: timeout, Transport: transport},
+	}
+}
+
+// Send implementa application.WebhookSender
+func (s *HTTPSender) Send(ctx context.Context, webhook *models.Webhook, event events.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("error serializando evento: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ApiSmart-Webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", strconv.FormatUint(uint64(webhook.ID), 10))
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Leer la respuesta permite reutilizar la conexión
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w: %d", models.ErrWebhookRejected, resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign calcula la firma hexadecimal de una entrega; los receptores la recalculan para verificarla
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// forbiddenPrefixes son los rangos de propósito especial del registro de IANA a los que no
+// se entregan webhooks: no son direcciones públicas de Internet o llevan a redes internas
+var forbiddenPrefixes = []netip.Prefix{
+	// IPv4
+	netip.MustParsePrefix("0.0.0.0/8"),       // "esta" red
+	netip.MustParsePrefix("10.0.0.0/8"),      // privada
+	netip.MustParsePrefix("100.64.0.0/10"),   // NAT de operador (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // enlace local
+	netip.MustParsePrefix("172.16.0.0/12"),   // privada
+	netip.MustParsePrefix("192.0.0.0/24"),    // asignaciones de protocolo de IETF
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentación
+	netip.MustParsePrefix("192.88.99.0/24"),  // relay 6to4
+	netip.MustParsePrefix("192.168.0.0/16"),  // privada
+	netip.MustParsePrefix("198.18.0.0/15"),   // pruebas de rendimiento
+	netip.MustParsePrefix("198.51.100.0/24"), // documentación
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentación
+	netip.MustParsePrefix("224.0.0.0/4"),     // multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // reservada y difusión
+	// IPv6
+	netip.MustParsePrefix("::/128"),         // no especificada
+	netip.MustParsePrefix("::1/128"),        // loopback
+	netip.MustParsePrefix("64:ff9b::/96"),   // traducción IPv4/IPv6 (NAT64)
+	netip.MustParsePrefix("64:ff9b:1::/48"), // traducción IPv4/IPv6 local
+	netip.MustParsePrefix("100::/64"),       // descarte
+	netip.MustParsePrefix("2001::/23"),      // asignaciones de protocolo de IETF (Teredo, etc.)
+	netip.MustParsePrefix("2001:db8::/32"),  // documentación
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("fc00::/7"),       // únicas locales
+	netip.MustParsePrefix("fe80::/10"),      // enlace local
+	netip.MustParsePrefix("fec0::/10"),      // de sitio (obsoletas)
+	netip.MustParsePrefix("ff00::/8"),       // multicast
+}
+
+// rejectPrivateTargets impide conectar a direcciones que no son públicas
+func rejectPrivateTargets(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || forbiddenAddr(addr) {
+		return fmt.Errorf("%w: %s", models.ErrWebhookTargetForbidden, host)
+	}
+
+	return nil
+}
+
+// forbiddenAddr indica si la dirección pertenece a algún rango de forbiddenPrefixes. Las
+// direcciones IPv4 mapeadas en IPv6 se comprueban como IPv4.
+func forbiddenAddr(addr netip.Addr) bool {
+	addr = addr.WithZone("").Unmap()
+	for _, prefix := range forbiddenPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
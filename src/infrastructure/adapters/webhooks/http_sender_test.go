@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ApiSmart/src/core/domain/events"
+	"ApiSmart/src/core/domain/models"
+)
+
+func testEvent() events.Event {
+	return events.NewEvent("evt-1", events.SensorDataCreated{ID: 7, DeviceID: 3, Luz: 120}, time.Unix(1700000000, 0))
+}
+
+func TestHTTPSenderSignsDelivery(t *testing.T) {
+	var header http.Header
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := &models.Webhook{ID: 5, URL: server.URL, Secret: "clave-de-prueba"}
+	status, err := NewHTTPSender(time.Second, true).Send(context.Background(), webhook, testEvent())
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if status != http.StatusNoContent {
+		t.Errorf("status = %d, se esperaba %d", status, http.StatusNoContent)
+	}
+
+	want := "sha256=" + Sign(webhook.Secret, header.Get("X-Webhook-Timestamp"), body)
+	if got := header.Get("X-Webhook-Signature"); got != want {
+		t.Errorf("X-Webhook-Signature = %q, se esperaba %q", got, want)
+	}
+	if got := header.Get("X-Event-ID"); got != "evt-1" {
+		t.Errorf("X-Event-ID = %q", got)
+	}
+	if got := header.Get("X-Event-Type"); got != events.EventTypeSensorDataCreated {
+		t.Errorf("X-Event-Type = %q", got)
+	}
+	if got := header.Get("X-Webhook-ID"); got != "5" {
+		t.Errorf("X-Webhook-ID = %q", got)
+	}
+}
+
+func TestHTTPSenderRejectsNon2xx(t *testing.T) {
+	for _, code := range []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		}))
+
+		status, err := NewHTTPSender(time.Second, true).Send(context.Background(), &models.Webhook{URL: server.URL}, testEvent())
+		server.Close()
+
+		if !errors.Is(err, models.ErrWebhookRejected) {
+			t.Errorf("código %d: err = %v, se esperaba ErrWebhookRejected", code, err)
+		}
+		if status != code {
+			t.Errorf("código %d: status = %d", code, status)
+		}
+	}
+}
+
+func TestHTTPSenderRefusesPrivateTargets(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	status, err := NewHTTPSender(time.Second, false).Send(context.Background(), &models.Webhook{URL: server.URL}, testEvent())
+	if !errors.Is(err, models.ErrWebhookTargetForbidden) {
+		t.Fatalf("err = %v, se esperaba ErrWebhookTargetForbidden", err)
+	}
+	if status != 0 {
+		t.Errorf("status = %d, se esperaba 0", status)
+	}
+	if called {
+		t.Error("el receptor en loopback no debería recibir la entrega")
+	}
+}
+
+func TestRejectPrivateTargets(t *testing.T) {
+	tests := []struct {
+		address   string
+		forbidden bool
+	}{
+		{"93.184.216.34:443", false},
+		{"8.8.8.8:80", false},
+		{"[2606:4700:4700::1111]:443", false},
+		{"127.0.0.1:80", true},
+		{"10.1.2.3:80", true},
+		{"172.16.0.1:80", true},
+		{"192.168.1.1:80", true},
+		{"169.254.169.254:80", true},
+		{"100.64.0.1:80", true},
+		{"100.127.255.255:80", true},
+		{"0.0.0.0:80", true},
+		{"0.1.2.3:80", true},
+		{"198.18.0.1:80", true},
+		{"198.19.255.255:80", true},
+		{"192.0.0.8:80", true},
+		{"192.0.2.1:80", true},
+		{"224.0.0.1:80", true},
+		{"255.255.255.255:80", true},
+		{"[::]:80", true},
+		{"[::1]:80", true},
+		{"[::ffff:127.0.0.1]:80", true},
+		{"[::ffff:100.64.0.1]:80", true},
+		{"[64:ff9b::a00:1]:80", true},
+		{"[fc00::1]:80", true},
+		{"[fe80::1%eth0]:80", true},
+		{"[ff02::1]:80", true},
+		{"ejemplo.com:80", true},
+	}
+
+	for _, tt := range tests {
+		err := rejectPrivateTargets("tcp", tt.address, nil)
+		if forbidden := errors.Is(err, models.ErrWebhookTargetForbidden); forbidden != tt.forbidden {
+			t.Errorf("%s: err = %v, se esperaba prohibida = %v", tt.address, err, tt.forbidden)
+		}
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 )
 
+// streamTicketAudience identifica los tickets de streaming frente a los tokens de sesión
+const streamTicketAudience = "stream"
+
 // JWTService implementa la autenticación JWT
 type JWTService struct {
 	secretKey   string
@@ -20,6 +24,12 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
+// StreamTicketClaims contiene los claims de un ticket de streaming
+type StreamTicketClaims struct {
+	UserID uint `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
 // NewJWTService crea una nueva instancia de JWTService
 func NewJWTService(secretKey string, expiryHours int) *JWTService {
 	return &JWTService{
@@ -82,3 +92,60 @@ func (s *JWTService) ValidateToken(tokenString string) (uint, error) {
 
 	return claims.UserID, nil
 }
+
+// GenerateStreamTicket genera un ticket de streaming con un identificador único que caduca
+// tras ttl. Se firma con una clave derivada, de modo que un ticket no sirve como token de
+// sesión ni a la inversa.
+func (s *JWTService) GenerateStreamTicket(userID uint, ttl time.Duration) (string, string, time.Time, error) {
+	now := time.Now()
+	ticketID := uuid.NewString()
+	expiresAt := now.Add(ttl)
+
+	claims := StreamTicketClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        ticketID,
+			Audience:  jwt.ClaimStrings{streamTicketAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	signedTicket, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.streamTicketKey())
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return signedTicket, ticketID, expiresAt, nil
+}
+
+// ValidateStreamTicket valida un ticket de streaming y devuelve el ID del usuario, el
+// identificador del ticket y su expiración
+func (s *JWTService) ValidateStreamTicket(ticket string) (uint, string, time.Time, error) {
+	token, err := jwt.ParseWithClaims(ticket, &StreamTicketClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("método de firma inesperado")
+		}
+
+		return s.streamTicketKey(), nil
+	})
+	if err != nil {
+		return 0, "", time.Time{}, err
+	}
+
+	claims, ok := token.Claims.(*StreamTicketClaims)
+	if !ok || !token.Valid {
+		return 0, "", time.Time{}, errors.New("ticket inválido")
+	}
+	if !claims.VerifyAudience(streamTicketAudience, true) || claims.ID == "" || claims.ExpiresAt == nil {
+		return 0, "", time.Time{}, errors.New("ticket inválido")
+	}
+
+	return claims.UserID, claims.ID, claims.ExpiresAt.Time, nil
+}
+
+// streamTicketKey deriva la clave de firma de los tickets de streaming
+func (s *JWTService) streamTicketKey() []byte {
+	return []byte(s.secretKey + ":" + streamTicketAudience)
+}
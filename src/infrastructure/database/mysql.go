@@ -16,7 +16,7 @@ type MySQLConnection struct {
 
 // NewMySQLConnection crea una nueva conexión a MySQL
 func NewMySQLConnection(config tipo_de_datos.DatabaseConfig) (*MySQLConnection, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=Local",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=Local&clientFoundRows=true",
 		config.User, config.Password, config.Host, config.Port, config.DBName)
 
 	// Intentar abrir la conexión
@@ -71,22 +71,58 @@ func initTables(db *sql.DB) error {
 		return err
 	}
 
+	// Tabla de dispositivos
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS devices (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			location VARCHAR(100) NOT NULL DEFAULT '',
+			description TEXT NOT NULL,
+			api_key VARCHAR(64) NULL,
+			key_issued_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	if err := addColumnIfNotExists(db, "devices", "api_key", `
+		ADD COLUMN api_key VARCHAR(64) NULL AFTER description,
+		ADD COLUMN key_issued_at DATETIME NULL AFTER api_key
+	`); err != nil {
+		return err
+	}
+
 	// Tabla de datos de sensores
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS sensor_data (
 			id INT AUTO_INCREMENT PRIMARY KEY,
+			device_id INT NULL,
 			temperatura_dht FLOAT NOT NULL,
 			luz FLOAT NOT NULL,
 			humedad FLOAT NOT NULL,
 			humo FLOAT NOT NULL,
 			created_at DATETIME NOT NULL,
-			INDEX (created_at)
+			INDEX (created_at),
+			INDEX idx_sensor_data_device_created (device_id, created_at),
+			CONSTRAINT fk_sensor_data_device FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE SET NULL
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 	`)
 	if err != nil {
 		return err
 	}
 
+	// Migrar instalaciones anteriores que no tenían dispositivos
+	if err := addColumnIfNotExists(db, "sensor_data", "device_id", `
+		ADD COLUMN device_id INT NULL AFTER id,
+		ADD INDEX idx_sensor_data_device_created (device_id, created_at),
+		ADD CONSTRAINT fk_sensor_data_device FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE SET NULL
+	`); err != nil {
+		return err
+	}
+
 	// Tabla de alertas
 	_, err = db.Exec(`
 		CREATE TABLE IF NOT EXISTS alerts (
@@ -108,5 +144,394 @@ func initTables(db *sql.DB) error {
 		return err
 	}
 
+	// Tabla de umbrales de alertas (device_id = 0 para los umbrales globales)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_thresholds (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			device_id INT NOT NULL DEFAULT 0,
+			temperatura_max FLOAT NOT NULL,
+			temperatura_min FLOAT NOT NULL,
+			luz_max FLOAT NOT NULL,
+			luz_min FLOAT NOT NULL,
+			humedad_max FLOAT NOT NULL,
+			humedad_min FLOAT NOT NULL,
+			humo_max FLOAT NOT NULL,
+			updated_at DATETIME NOT NULL,
+			UNIQUE KEY uq_alert_thresholds_device (device_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Tablas de resumen de lecturas (device_id = 0 para lecturas sin dispositivo)
+	for _, table := range []string{"sensor_data_1m", "sensor_data_1h", "sensor_data_1d"} {
+		_, err = db.Exec(fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				device_id INT NOT NULL,
+				bucket_start DATETIME NOT NULL,
+				sample_count INT NOT NULL,
+				temperatura_dht_sum DOUBLE NOT NULL,
+				temperatura_dht_min FLOAT NOT NULL,
+				temperatura_dht_max FLOAT NOT NULL,
+				luz_sum DOUBLE NOT NULL,
+				luz_min FLOAT NOT NULL,
+				luz_max FLOAT NOT NULL,
+				humedad_sum DOUBLE NOT NULL,
+				humedad_min FLOAT NOT NULL,
+				humedad_max FLOAT NOT NULL,
+				humo_sum DOUBLE NOT NULL,
+				humo_min FLOAT NOT NULL,
+				humo_max FLOAT NOT NULL,
+				PRIMARY KEY (device_id, bucket_start),
+				INDEX (bucket_start)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+		`, table))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Bandeja de salida de eventos, escrita en la misma transacción que los datos
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL,
+			topic VARCHAR(100) NOT NULL,
+			event_type VARCHAR(100) NOT NULL,
+			schema_version INT NOT NULL DEFAULT 1,
+			payload JSON NOT NULL,
+			attempts INT NOT NULL DEFAULT 0,
+			last_error TEXT NULL,
+			next_attempt_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			created_at DATETIME(6) NOT NULL,
+			sent_at DATETIME NULL,
+			failed_at DATETIME NULL,
+			INDEX idx_outbox_pending (sent_at, id),
+			INDEX idx_outbox_due (sent_at, failed_at, next_attempt_at, id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Migración: versión de esquema del contenido de los eventos
+	err = addColumnIfNotExists(db, "outbox", "schema_version",
+		"ADD COLUMN schema_version INT NOT NULL DEFAULT 1 AFTER event_type")
+	if err != nil {
+		return err
+	}
+
+	// Migración: reintentos con espera de la bandeja de salida y eventos apartados tras
+	// agotar los intentos (failed_at)
+	err = addColumnIfNotExists(db, "outbox", "next_attempt_at", `
+		ADD COLUMN next_attempt_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) AFTER last_error,
+		ADD COLUMN failed_at DATETIME NULL AFTER sent_at,
+		ADD INDEX idx_outbox_due (sent_at, failed_at, next_attempt_at, id)
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Registro persistente de todos los eventos publicados, para poder reproducirlos
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS event_log (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			event_id VARCHAR(36) NOT NULL,
+			topic VARCHAR(100) NOT NULL,
+			event_type VARCHAR(100) NOT NULL,
+			schema_version INT NOT NULL,
+			payload JSON NOT NULL,
+			occurred_at DATETIME(6) NOT NULL,
+			recorded_at DATETIME NOT NULL,
+			UNIQUE KEY uq_event_log_event (event_id),
+			INDEX idx_event_log_occurred (occurred_at),
+			INDEX idx_event_log_type_occurred (event_type, occurred_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Webhooks de los usuarios (event_types es un array JSON de tipos de evento)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			user_id INT NOT NULL,
+			url VARCHAR(500) NOT NULL,
+			event_types JSON NOT NULL,
+			secret VARCHAR(64) NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			consecutive_failures INT NOT NULL DEFAULT 0,
+			disabled_at DATETIME NULL,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			INDEX (user_id),
+			INDEX (active),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Historial de intentos de entrega de los webhooks
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			webhook_id INT NOT NULL,
+			event_id VARCHAR(36) NOT NULL,
+			event_type VARCHAR(100) NOT NULL,
+			attempt INT NOT NULL,
+			status_code INT NULL,
+			success BOOLEAN NOT NULL,
+			error TEXT NULL,
+			duration_ms BIGINT NOT NULL,
+			delivered_at DATETIME(6) NOT NULL,
+			INDEX idx_webhook_deliveries_webhook (webhook_id, id),
+			FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Entregas a webhooks pendientes de reintentar, una por webhook y evento
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_retries (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			webhook_id INT NOT NULL,
+			event_id VARCHAR(36) NOT NULL,
+			event JSON NOT NULL,
+			attempt INT NOT NULL,
+			next_attempt_at DATETIME(6) NOT NULL,
+			UNIQUE KEY uq_webhook_retries_event (webhook_id, event_id),
+			INDEX idx_webhook_retries_due (next_attempt_at),
+			FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Preferencias de notificación de los usuarios
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS notification_preferences (
+			user_id INT PRIMARY KEY,
+			email_alerts BOOLEAN NOT NULL DEFAULT FALSE,
+			updated_at DATETIME NOT NULL,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Reglas de alerta (device_id NULL para las que se aplican a todos los dispositivos)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_rules (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			expression VARCHAR(500) NOT NULL,
+			device_id INT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL,
+			INDEX (enabled),
+			FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Migración: regla que generó cada alerta
+	if err := addColumnIfNotExists(db, "alerts", "rule_id", `
+		ADD COLUMN rule_id INT NULL AFTER is_read,
+		ADD INDEX idx_alerts_rule (rule_id),
+		ADD CONSTRAINT fk_alerts_rule FOREIGN KEY (rule_id) REFERENCES alert_rules(id) ON DELETE SET NULL
+	`); err != nil {
+		return err
+	}
+
+	// Migración: dispositivo, estado y ocurrencias de cada alerta. Las alertas anteriores
+	// se dan por resueltas y toman el dispositivo de su lectura.
+	if err := addColumnIfNotExists(db, "alerts", "status", `
+		ADD COLUMN device_id INT NULL AFTER id,
+		ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'resolved' AFTER rule_id,
+		ADD COLUMN occurrences INT NOT NULL DEFAULT 1 AFTER status,
+		ADD COLUMN last_value FLOAT NULL AFTER occurrences,
+		ADD COLUMN last_seen_at DATETIME NULL AFTER last_value,
+		ADD COLUMN resolved_at DATETIME NULL AFTER last_seen_at,
+		ADD INDEX idx_alerts_device_status (device_id, status)
+	`); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		UPDATE alerts a
+		JOIN sensor_data s ON s.id = a.sensor_id
+		SET a.device_id = s.device_id
+		WHERE a.device_id IS NULL AND s.device_id IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Estado de cada condición de alerta (tipo de sensor o regla) por dispositivo
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_states (
+			device_id INT NOT NULL,
+			alert_key VARCHAR(50) NOT NULL,
+			status VARCHAR(20) NOT NULL,
+			since DATETIME NOT NULL,
+			alert_id INT NULL,
+			last_value FLOAT NOT NULL,
+			updated_at DATETIME NOT NULL,
+			PRIMARY KEY (device_id, alert_key),
+			FOREIGN KEY (device_id) REFERENCES devices(id) ON DELETE CASCADE,
+			FOREIGN KEY (alert_id) REFERENCES alerts(id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Migración única: las condiciones de umbral incluyen el sentido en la clave ("temperatura_alta")
+	if err := resetLegacyAlertStates(db); err != nil {
+		return err
+	}
+
+	// Migración: gravedad y ciclo de vida (reconocimiento, resolución y escalado) de las alertas
+	if err := addColumnIfNotExists(db, "alerts", "severity", `
+		ADD COLUMN severity VARCHAR(10) NOT NULL DEFAULT 'warning' AFTER sensor_type,
+		ADD COLUMN acknowledged_at DATETIME NULL AFTER last_seen_at,
+		ADD COLUMN acknowledged_by INT NULL AFTER acknowledged_at,
+		ADD COLUMN resolved_by INT NULL AFTER resolved_at,
+		ADD COLUMN escalated_at DATETIME NULL AFTER resolved_by,
+		ADD INDEX idx_alerts_status_severity (status, severity, created_at),
+		ADD CONSTRAINT fk_alerts_acknowledged_by FOREIGN KEY (acknowledged_by) REFERENCES users(id) ON DELETE SET NULL,
+		ADD CONSTRAINT fk_alerts_resolved_by FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+	`); err != nil {
+		return err
+	}
+
+	// Migración: gravedad de las alertas que genera cada regla
+	if err := addColumnIfNotExists(db, "alert_rules", "severity", `
+		ADD COLUMN severity VARCHAR(10) NOT NULL DEFAULT 'warning' AFTER device_id
+	`); err != nil {
+		return err
+	}
+
+	// Estado de lectura de las alertas por usuario; sustituye a la columna global is_read
+	readsExist, err := tableExists(db, "alert_reads")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_reads (
+			user_id INT NOT NULL,
+			alert_id INT NOT NULL,
+			read_at DATETIME NOT NULL,
+			PRIMARY KEY (user_id, alert_id),
+			INDEX idx_alert_reads_alert (alert_id),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (alert_id) REFERENCES alerts(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+	`)
+	if err != nil {
+		return err
+	}
+
+	// Migración: las alertas que ya estaban leídas lo quedan para todos los usuarios existentes
+	if !readsExist {
+		_, err = db.Exec(`
+			INSERT IGNORE INTO alert_reads (user_id, alert_id, read_at)
+			SELECT u.id, a.id, NOW()
+			FROM users u
+			CROSS JOIN alerts a
+			WHERE a.is_read = TRUE
+		`)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// tableExists indica si la tabla ya existe en la base de datos
+func tableExists(db *sql.DB, table string) (bool, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+	`, table).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// legacyAlertStateKeys son las claves de las condiciones de umbral anteriores a incluir el sentido
+const legacyAlertStateKeys = `'temperatura', 'luz', 'humedad', 'humo'`
+
+// resetLegacyAlertStates reinicia como no activas las condiciones de umbral con clave sin
+// sentido: la clave antigua no indica si el umbral superado era el máximo o el mínimo. Sus
+// alertas abiertas se resuelven y sus estados se descartan; la siguiente lectura las vuelve
+// a abrir con la clave nueva si la condición se sigue cumpliendo. Solo actúa si quedan claves antiguas.
+func resetLegacyAlertStates(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM alert_states WHERE alert_key IN (` + legacyAlertStateKeys + `)`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE alerts a
+		JOIN alert_states s ON s.alert_id = a.id
+		SET a.status = 'resolved', a.resolved_at = NOW()
+		WHERE s.alert_key IN (` + legacyAlertStateKeys + `) AND a.status <> 'resolved'
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM alert_states WHERE alert_key IN (` + legacyAlertStateKeys + `)`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// addColumnIfNotExists aplica la alteración indicada solo si la columna aún no existe
+func addColumnIfNotExists(db *sql.DB, table, column, alteration string) error {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?
+	`, table, column).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return nil
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s %s", table, alteration))
+	return err
+}